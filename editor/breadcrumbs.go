@@ -0,0 +1,175 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/therecipe/qt/widgets"
+)
+
+// Breadcrumbs is the symbol path at the cursor shown at the top of the
+// focused window. Plugins push the path, outermost symbol first, with
+//
+//	call rpcnotify(0, 'Gui', 'gonvim_breadcrumbs', [{'name': 'Server', 'kind': 'class'}, ...])
+//
+// or by defining GonvimBreadcrumbs() returning that list, which is called on
+// CursorMoved. From neovim 0.8 on it is drawn over the window's winbar, set
+// to a blank one unless 'winbar' is already set, so it covers no text.
+// Before that there is no row to spare and it is drawn over the window's
+// top row, hidden while the cursor is on it.
+type Breadcrumbs struct {
+	ws      *Workspace
+	widget  *widgets.QWidget
+	layout  *widgets.QHBoxLayout
+	items   []*BreadcrumbItem
+	enabled bool
+	shown   int
+	style   string
+}
+
+// BreadcrumbItem is a symbol in the breadcrumbs
+type BreadcrumbItem struct {
+	widget    *widgets.QWidget
+	separator *widgets.QLabel
+	kind      *widgets.QLabel
+	name      *widgets.QLabel
+}
+
+func initBreadcrumbs() *Breadcrumbs {
+	layout := widgets.NewQHBoxLayout()
+	layout.SetContentsMargins(4, 0, 4, 0)
+	layout.SetSpacing(0)
+	layout.AddStretch(1)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetLayout(layout)
+	widget.Hide()
+	return &Breadcrumbs{
+		widget: widget,
+		layout: layout,
+	}
+}
+
+func (b *Breadcrumbs) newItem() *BreadcrumbItem {
+	font := b.ws.font.fontNew
+	separator := widgets.NewQLabel(nil, 0)
+	separator.SetFont(font)
+	separator.SetText(" › ")
+	kind := widgets.NewQLabel(nil, 0)
+	kind.SetFont(font)
+	kind.SetContentsMargins(2, 0, 2, 0)
+	name := widgets.NewQLabel(nil, 0)
+	name.SetFont(font)
+	name.SetContentsMargins(4, 0, 0, 0)
+	layout := widgets.NewQHBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(0)
+	layout.AddWidget(separator, 0, 0)
+	layout.AddWidget(kind, 0, 0)
+	layout.AddWidget(name, 0, 0)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetLayout(layout)
+	b.layout.InsertWidget(len(b.items), widget, 0, 0)
+	return &BreadcrumbItem{
+		widget:    widget,
+		separator: separator,
+		kind:      kind,
+		name:      name,
+	}
+}
+
+func (b *Breadcrumbs) update(args []interface{}) {
+	if !b.enabled || len(args) == 0 {
+		return
+	}
+	symbols, _ := args[0].([]interface{})
+	b.shown = 0
+	for _, symbol := range symbols {
+		name, kind := breadcrumbSymbol(symbol)
+		if name == "" {
+			continue
+		}
+		if b.shown >= len(b.items) {
+			b.items = append(b.items, b.newItem())
+		}
+		item := b.items[b.shown]
+		item.separator.SetVisible(b.shown > 0)
+		kindText, color, bg := kindStyle(symbolKind(strings.ToLower(kind)))
+		item.kind.SetStyleSheet(fmt.Sprintf("background-color: %s; color: %s;", bg.String(), color.String()))
+		item.kind.SetText(kindText)
+		item.name.SetText(name)
+		item.widget.Show()
+		b.shown++
+	}
+	for i := b.shown; i < len(b.items); i++ {
+		b.items[i].widget.Hide()
+	}
+	b.move()
+}
+
+// breadcrumbSymbol accepts a symbol as {'name': ..., 'kind': ...} or as
+// [name, kind]
+func breadcrumbSymbol(symbol interface{}) (string, string) {
+	name := ""
+	kind := ""
+	switch s := symbol.(type) {
+	case map[string]interface{}:
+		name, _ = s["name"].(string)
+		kind, _ = s["kind"].(string)
+	case []interface{}:
+		if len(s) > 0 {
+			name, _ = s[0].(string)
+		}
+		if len(s) > 1 {
+			kind, _ = s[1].(string)
+		}
+	}
+	return name, kind
+}
+
+// symbolKind maps the symbol kinds of the language servers the completion
+// kinds have no name for
+func symbolKind(kind string) string {
+	switch kind {
+	case "method", "constructor":
+		return "function"
+	case "variable", "field", "property":
+		return "var"
+	case "constant":
+		return "const"
+	case "struct", "interface":
+		return "class"
+	}
+	return kind
+}
+
+// move puts the breadcrumbs over the winbar, or the top row, of the focused
+// window
+func (b *Breadcrumbs) move() {
+	if !b.enabled || b.shown == 0 {
+		b.widget.Hide()
+		return
+	}
+	screen := b.ws.screen
+	win := screen.windowAt(screen.cursor[0], screen.cursor[1])
+	if win == nil || (win.winbar == 0 && screen.cursor[0] == win.pos[0]) {
+		b.widget.Hide()
+		return
+	}
+	bg := b.ws.background
+	if win.bg != nil {
+		bg = win.bg
+	}
+	fg := b.ws.foreground
+	if bg != nil && fg != nil {
+		style := fmt.Sprintf(".QWidget {background-color: %s;} QLabel {color: %s;}", bg.String(), fg.String())
+		if style != b.style {
+			b.style = style
+			b.widget.SetStyleSheet(style)
+		}
+	}
+	font := b.ws.font
+	b.widget.Move2(int(float64(win.pos[1])*font.truewidth), (win.pos[0]-win.winbar)*font.lineHeight)
+	b.widget.SetFixedSize2(int(float64(win.width)*font.truewidth), font.lineHeight)
+	b.widget.Show()
+	b.widget.Raise()
+}
@@ -0,0 +1,102 @@
+package editor
+
+import (
+	"fmt"
+)
+
+// subscribeCells defines GonvimGetCell(row, col), which returns what is
+// drawn in the cell counting from 0, for tests and tools that need to check
+// the screen:
+//
+//	{'char': 'a', 'width': 1, 'fg': '#ffffff', 'bg': '#000000',
+//	 'sp': '#ff0000', 'bold': 0, 'italic': 0, 'undercurl': 0,
+//	 'underline': 0, 'underdouble': 0, 'strikethrough': 0, 'reverse': 0}
+//
+// width is 2 for a double width character and 0 for the cell after one.
+// Colors the cell doesn't set are the default ones, and empty only before
+// neovim sent those. fg and bg are as drawn, already swapped for reverse. A
+// position off the screen is an error. The command of the same name echoes
+// the result.
+//
+// The request is answered on the RPC goroutine, handleRedraw holds
+// redrawMutex while it changes the grid, so the GUI thread is never waited
+// on.
+func (s *Screen) subscribeCells() {
+	s.ws.nvim.RegisterHandler("GonvimGetCell", func(row, col int) (map[string]interface{}, error) {
+		s.redrawMutex.Lock()
+		cell := s.cellAt(row, col)
+		s.redrawMutex.Unlock()
+		if cell == nil {
+			return nil, fmt.Errorf("GonvimGetCell: %d, %d is off the screen", row, col)
+		}
+		return cell, nil
+	})
+	s.ws.nvim.Command(`execute "function! GonvimGetCell(row, col) abort\n return rpcrequest(g:gonvim_channel_id, 'GonvimGetCell', a:row, a:col)\nendfunction"`)
+	s.ws.nvim.Command(`command! -nargs=+ GonvimGetCell echo call('GonvimGetCell', map([<f-args>], 'str2nr(v:val)'))`)
+}
+
+func colorHex(color *RGBA) string {
+	if color == nil {
+		return ""
+	}
+	return color.Hex()
+}
+
+// cellAt returns the cell as GonvimGetCell sends it, or nil when the
+// position is off the screen
+func (s *Screen) cellAt(row, col int) map[string]interface{} {
+	if row < 0 || row >= s.ws.rows || col < 0 || col >= s.ws.cols {
+		return nil
+	}
+	cell := map[string]interface{}{
+		"char":          " ",
+		"width":         1,
+		"fg":            colorHex(s.ws.foreground),
+		"bg":            colorHex(s.ws.background),
+		"sp":            colorHex(s.ws.special),
+		"bold":          0,
+		"italic":        0,
+		"undercurl":     0,
+		"underline":     0,
+		"underdouble":   0,
+		"strikethrough": 0,
+		"reverse":       0,
+	}
+	if row >= len(s.content) || col >= len(s.content[row]) {
+		return cell
+	}
+	char := s.content[row][col]
+	if char == nil {
+		return cell
+	}
+	cell["char"] = char.char
+	if char.char == "" {
+		cell["width"] = 0
+	} else if !char.normalWidth {
+		cell["width"] = 2
+	}
+	hl := char.highlight
+	if hl.foreground != nil {
+		cell["fg"] = colorHex(hl.foreground)
+	}
+	if hl.background != nil {
+		cell["bg"] = colorHex(hl.background)
+	}
+	if hl.special != nil {
+		cell["sp"] = colorHex(hl.special)
+	}
+	for name, set := range map[string]bool{
+		"bold":          hl.bold,
+		"italic":        hl.italic,
+		"undercurl":     hl.undercurl,
+		"underline":     hl.underline,
+		"underdouble":   hl.underdouble,
+		"strikethrough": hl.strike,
+		"reverse":       hl.reverse,
+	} {
+		if set {
+			cell[name] = 1
+		}
+	}
+	return cell
+}
@@ -0,0 +1,39 @@
+package editor
+
+import "testing"
+
+func TestCellAt(t *testing.T) {
+	s := newGridScreen(2, 10)
+	s.ws.foreground = calcColor(0xabb2bf)
+	s.ws.background = calcColor(0x282c34)
+	s.ws.special = calcColor(0xff0000)
+	gridPut(s, 0, 0, "ab")
+	gridSetHighlight(s, map[string]interface{}{"foreground": int64(0x61afef), "special": int64(0x98c379), "undercurl": true, "reverse": true})
+	gridPut(s, 0, 2, "c")
+
+	cases := []struct {
+		row, col   int
+		char       string
+		fg, bg, sp string
+		bold       int
+		undercurl  int
+		reverse    int
+	}{
+		{0, 0, "a", "#abb2bf", "#282c34", "#ff0000", 0, 0, 0},
+		{0, 2, "c", "#282c34", "#61afef", "#98c379", 0, 1, 1},
+		{1, 5, " ", "#abb2bf", "#282c34", "#ff0000", 0, 0, 0},
+	}
+	for _, c := range cases {
+		cell := s.cellAt(c.row, c.col)
+		if cell == nil {
+			t.Errorf("cellAt(%d, %d) = nil", c.row, c.col)
+			continue
+		}
+		if cell["char"] != c.char || cell["fg"] != c.fg || cell["bg"] != c.bg || cell["sp"] != c.sp || cell["bold"] != c.bold || cell["undercurl"] != c.undercurl || cell["reverse"] != c.reverse {
+			t.Errorf("cellAt(%d, %d) = %v", c.row, c.col, cell)
+		}
+	}
+	if cell := s.cellAt(2, 0); cell != nil {
+		t.Errorf("cellAt off the screen = %v, want nil", cell)
+	}
+}
@@ -0,0 +1,206 @@
+package editor
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// copyHighlighted puts the given buffer lines on the clipboard as plain text
+// and as HTML colored like the screen. Only lines visible in the current
+// window can be colored, others are copied as plain text. The args are the
+// first line number, the window's top line, its text offset, 'tabstop' and
+// the lines themselves.
+func (s *Screen) copyHighlighted(args []interface{}) {
+	if len(args) < 5 {
+		return
+	}
+	first := reflectToInt(args[0])
+	top := reflectToInt(args[1])
+	textoff := reflectToInt(args[2])
+	tabstop := reflectToInt(args[3])
+	rawLines, ok := args[4].([]interface{})
+	if !ok {
+		return
+	}
+
+	bg := s.ws.background
+	fg := s.ws.foreground
+	win := s.cursorWin()
+	plain := []string{}
+	rich := []string{}
+	for i, rawLine := range rawLines {
+		text, _ := rawLine.(string)
+		plain = append(plain, text)
+		row := -1
+		if win != nil {
+			row = win.pos[0] + first + i - top
+			if row < win.pos[0] || row >= win.pos[0]+win.height || row >= len(s.content) {
+				row = -1
+			}
+		}
+		if row == -1 {
+			rich = append(rich, html.EscapeString(text))
+			continue
+		}
+		start := win.pos[1] + textoff
+		end := win.pos[1] + win.width
+		if end > len(s.content[row]) {
+			end = len(s.content[row])
+		}
+		if start >= end {
+			rich = append(rich, html.EscapeString(text))
+			continue
+		}
+		line := s.content[row][start:end]
+		expanded := strings.TrimRight(expandTabs(text, tabstop), " ")
+		if strings.TrimRight(cellsText(line), " ") != expanded {
+			rich = append(rich, html.EscapeString(text))
+			continue
+		}
+		rich = append(rich, s.cellsHTML(line))
+	}
+
+	style := "font-family: monospace;"
+	if bg != nil {
+		style += fmt.Sprintf(" background-color: %s;", bg.Hex())
+	}
+	if fg != nil {
+		style += fmt.Sprintf(" color: %s;", fg.Hex())
+	}
+	mime := core.NewQMimeData()
+	mime.SetText(strings.Join(plain, "\n"))
+	mime.SetHtml(fmt.Sprintf("<pre style=\"%s\">%s</pre>", style, strings.Join(rich, "\n")))
+	widgets.QApplication_Clipboard().SetMimeData(mime, gui.QClipboard__Clipboard)
+}
+
+// cellsHTML renders the cells up to the last non blank one as HTML spans, one
+// span per run of cells sharing the same highlight
+func (s *Screen) cellsHTML(line []*Char) string {
+	n := len(line)
+	for n > 0 && (line[n-1] == nil || line[n-1].char == " " || line[n-1].char == "") {
+		n--
+	}
+	result := ""
+	text := ""
+	var last *Highlight
+	flush := func() {
+		if text == "" {
+			return
+		}
+		if last == nil {
+			result += html.EscapeString(text)
+		} else {
+			result += fmt.Sprintf("<span style=\"%s\">%s</span>", s.highlightStyle(last), html.EscapeString(text))
+		}
+		text = ""
+	}
+	for x := 0; x < n; x++ {
+		char := line[x]
+		var hl *Highlight
+		c := " "
+		if char != nil {
+			hl = &char.highlight
+			c = char.char
+		}
+		if !sameStyle(hl, last) {
+			flush()
+			last = hl
+		}
+		text += c
+	}
+	flush()
+	return result
+}
+
+func (s *Screen) highlightStyle(hl *Highlight) string {
+	style := ""
+	if hl.foreground != nil {
+		style += fmt.Sprintf("color: %s;", hl.foreground.Hex())
+	}
+	if hl.background != nil && (s.ws.background == nil || !hl.background.equals(s.ws.background)) {
+		style += fmt.Sprintf("background-color: %s;", hl.background.Hex())
+	}
+	if hl.bold {
+		style += "font-weight: bold;"
+	}
+	if hl.italic {
+		style += "font-style: italic;"
+	}
+	lines := []string{}
+	if hl.undercurl || hl.underline || hl.underdouble {
+		lines = append(lines, "underline")
+	}
+	if hl.strike {
+		lines = append(lines, "line-through")
+	}
+	if len(lines) > 0 {
+		style += fmt.Sprintf("text-decoration: %s;", strings.Join(lines, " "))
+	}
+	if hl.undercurl {
+		style += "text-decoration-style: wavy;"
+	} else if hl.underdouble {
+		style += "text-decoration-style: double;"
+	}
+	if len(lines) > 0 && hl.special != nil && !sameColor(hl.special, s.ws.special) {
+		style += fmt.Sprintf("text-decoration-color: %s;", hl.special.Hex())
+	}
+	return style
+}
+
+func sameStyle(a, b *Highlight) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return sameColor(a.foreground, b.foreground) &&
+		sameColor(a.background, b.background) &&
+		sameColor(a.special, b.special) &&
+		a.bold == b.bold &&
+		a.italic == b.italic &&
+		a.undercurl == b.undercurl &&
+		a.underline == b.underline &&
+		a.underdouble == b.underdouble &&
+		a.strike == b.strike
+}
+
+func sameColor(a, b *RGBA) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.equals(b)
+}
+
+func cellsText(line []*Char) string {
+	text := ""
+	for _, char := range line {
+		if char == nil {
+			text += " "
+			continue
+		}
+		text += char.char
+	}
+	return text
+}
+
+func expandTabs(text string, tabstop int) string {
+	if tabstop <= 0 {
+		tabstop = 8
+	}
+	result := ""
+	col := 0
+	for _, r := range text {
+		if r == '\t' {
+			n := tabstop - col%tabstop
+			result += strings.Repeat(" ", n)
+			col += n
+			continue
+		}
+		result += string(r)
+		col++
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+package editor
+
+import "testing"
+
+func TestHighlightStyleDecorations(t *testing.T) {
+	s := &Screen{ws: &Workspace{special: calcColor(0xffffff)}}
+	red := calcColor(0xff0000)
+	cases := []struct {
+		hl    Highlight
+		style string
+	}{
+		{Highlight{}, ""},
+		{Highlight{underline: true}, "text-decoration: underline;"},
+		{Highlight{undercurl: true}, "text-decoration: underline;text-decoration-style: wavy;"},
+		{Highlight{underdouble: true}, "text-decoration: underline;text-decoration-style: double;"},
+		{Highlight{strike: true}, "text-decoration: line-through;"},
+		{Highlight{underline: true, strike: true}, "text-decoration: underline line-through;"},
+		{Highlight{undercurl: true, special: red}, "text-decoration: underline;text-decoration-style: wavy;text-decoration-color: #ff0000;"},
+		{Highlight{underline: true, special: calcColor(0xffffff)}, "text-decoration: underline;"},
+		{Highlight{special: red}, ""},
+	}
+	for _, c := range cases {
+		style := s.highlightStyle(&c.hl)
+		if style != c.style {
+			t.Errorf("highlightStyle(%+v) = %q, want %q", c.hl, style, c.style)
+		}
+	}
+}
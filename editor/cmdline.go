@@ -2,7 +2,11 @@ package editor
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
 )
 
 // CmdContent is the content of the cmdline
@@ -21,9 +25,16 @@ type Cmdline struct {
 	preContent    *CmdContent
 	function      []*CmdContent
 	inFunction    bool
+	blockEvents   bool
 	rawItems      []interface{}
 	wildmenuShown bool
 	top           int
+	ghostEnabled  bool
+	wildmenuIcons bool
+	wildmenuTypes []string
+	wildmenuGen   int
+	ghost         string
+	preGhostText  string
 }
 
 func initCmdline() *Cmdline {
@@ -51,7 +62,7 @@ func (c *Cmdline) getText(ch string) string {
 
 func (c *Cmdline) show(args []interface{}) {
 	arg := args[0].([]interface{})
-	content := arg[0].([]interface{})[0].([]interface{})[1].(string)
+	content := cmdlineText(arg[0])
 	pos := reflectToInt(arg[1])
 	firstc := arg[2].(string)
 	prompt := arg[3].(string)
@@ -67,6 +78,7 @@ func (c *Cmdline) show(args []interface{}) {
 	text := c.getText("")
 	palette := c.ws.palette
 	palette.setPattern(text)
+	c.updateGhost()
 	c.cursorMove()
 	if !c.wildmenuShown {
 		c.showAddition()
@@ -121,10 +133,68 @@ func (c *Cmdline) cursorMove() {
 	c.ws.palette.cursorMove(c.pos + len(c.content.firstc) + c.content.indent)
 }
 
+// updateGhost shows the rest of the first completion of the command line
+// after the cursor in a muted color, when g:gonvim_cmdline_ghost is set and
+// the cursor is at the end of an ex command. Until neovim answers, the
+// previous ghost is kept if the typed text still matches it.
+func (c *Cmdline) updateGhost() {
+	content := c.content.content
+	if !c.ghostEnabled || c.wildmenuShown || c.content.firstc != ":" || c.pos != len(content) || content == "" {
+		c.setGhost("")
+		return
+	}
+	ghost := ""
+	if c.ghost != "" && c.preGhostText != "" && strings.HasPrefix(content, c.preGhostText) {
+		typed := content[len(c.preGhostText):]
+		if strings.HasPrefix(c.ghost, typed) {
+			ghost = c.ghost[len(typed):]
+		}
+	}
+	c.setGhost(ghost)
+	c.preGhostText = content
+	go func() {
+		var items []string
+		err := c.ws.nvim.Call("getcompletion", &items, content, "cmdline")
+		if err != nil || len(items) == 0 {
+			items = []string{""}
+		}
+		last := content[strings.LastIndex(content, " ")+1:]
+		ghost := ""
+		if strings.HasPrefix(items[0], last) {
+			ghost = items[0][len(last):]
+		}
+		c.ws.guiUpdates <- []interface{}{"gonvim_cmdline_ghost", content, ghost}
+		c.ws.signal.GuiSignal()
+	}()
+}
+
+// ghostResult shows the ghost found for text if the command line still
+// shows text
+func (c *Cmdline) ghostResult(args []interface{}) {
+	if len(args) < 2 {
+		return
+	}
+	text, _ := args[0].(string)
+	ghost, _ := args[1].(string)
+	if c.content.content != text || c.pos != len(text) || c.wildmenuShown {
+		return
+	}
+	c.setGhost(ghost)
+}
+
+func (c *Cmdline) setGhost(ghost string) {
+	if ghost == c.ghost {
+		return
+	}
+	c.ghost = ghost
+	c.ws.palette.setPatternGhost(ghost)
+}
+
 func (c *Cmdline) hide(args []interface{}) {
+	c.setGhost("")
 	palette := c.ws.palette
 	palette.hide()
-	if c.inFunction {
+	if c.inFunction && !c.blockEvents {
 		c.function = append(c.function, c.content)
 	}
 	c.preContent = c.content
@@ -133,9 +203,55 @@ func (c *Cmdline) hide(args []interface{}) {
 
 func (c *Cmdline) functionShow() {
 	c.inFunction = true
+	c.blockEvents = false
 	c.function = []*CmdContent{c.preContent}
 }
 
+// blockShow starts a block typed on the cmdline, like a :function, with the
+// lines already entered. cmdline_block_show and cmdline_block_append are
+// what neovim sends for it, the function events are an older form.
+func (c *Cmdline) blockShow(args []interface{}) {
+	c.inFunction = true
+	c.blockEvents = true
+	c.function = []*CmdContent{}
+	if len(args) == 0 {
+		return
+	}
+	arg, _ := args[0].([]interface{})
+	if len(arg) == 0 {
+		return
+	}
+	lines, _ := arg[0].([]interface{})
+	for _, line := range lines {
+		c.function = append(c.function, &CmdContent{content: cmdlineText(line)})
+	}
+}
+
+func (c *Cmdline) blockAppend(args []interface{}) {
+	for _, arg := range args {
+		line, ok := arg.([]interface{})
+		if !ok || len(line) == 0 {
+			continue
+		}
+		c.function = append(c.function, &CmdContent{content: cmdlineText(line[0])})
+	}
+}
+
+// cmdlineText joins the text of the [attr, text] chunks of a cmdline line
+func cmdlineText(line interface{}) string {
+	chunks, _ := line.([]interface{})
+	text := ""
+	for _, chunk := range chunks {
+		parts, ok := chunk.([]interface{})
+		if !ok || len(parts) < 2 {
+			continue
+		}
+		s, _ := parts[1].(string)
+		text += s
+	}
+	return text
+}
+
 func (c *Cmdline) functionHide() {
 	c.inFunction = false
 }
@@ -146,6 +262,9 @@ func (c *Cmdline) changePos(args []interface{}) {
 	// level := reflectToInt(args[1])
 	// fmt.Println("change pos", pos, level)
 	c.pos = pos
+	if pos != len(c.content.content) {
+		c.setGhost("")
+	}
 	c.cursorMove()
 }
 
@@ -157,13 +276,16 @@ func (c *Cmdline) putChar(args []interface{}) {
 	// fmt.Println("putChar", ch, shift, level)
 	text := c.getText(ch)
 	palette := c.ws.palette
+	c.ghost = ""
 	palette.setPattern(text)
 }
 
 func (c *Cmdline) wildmenuShow(args []interface{}) {
 	c.wildmenuShown = true
+	c.setGhost("")
 	args = args[0].([]interface{})
 	c.rawItems = args[0].([]interface{})
+	c.lookupWildmenuTypes()
 	palette := c.ws.palette
 	c.top = 0
 	for i := 0; i < palette.showTotal; i++ {
@@ -173,7 +295,7 @@ func (c *Cmdline) wildmenuShow(args []interface{}) {
 			continue
 		}
 		text := c.rawItems[i].(string)
-		resultItem.setItem(text, "", []int{})
+		resultItem.setItem(text, c.wildmenuType(i), []int{})
 		resultItem.show()
 		resultItem.setSelected(false)
 	}
@@ -223,7 +345,7 @@ func (c *Cmdline) wildmenuScroll(n int) {
 			continue
 		}
 		text := c.rawItems[i+c.top].(string)
-		resultItem.setItem(text, "", []int{})
+		resultItem.setItem(text, c.wildmenuType(i+c.top), []int{})
 		resultItem.show()
 		resultItem.setSelected(false)
 	}
@@ -231,6 +353,75 @@ func (c *Cmdline) wildmenuScroll(n int) {
 	palette.scrollBar.Move2(0, palette.scrollBarPos)
 }
 
+// lookupWildmenuTypes gives the wildmenu items that are files or
+// directories their icons with g:gonvim_wildmenu_icons set. An item is taken
+// for a path when it is an argument on the : cmdline and names something
+// that exists, so commands and options completed get no icon. The items
+// are looked up on the disk in a goroutine and get their icons when
+// wildmenuTypesResult has them, if the wildmenu still shows those items.
+func (c *Cmdline) lookupWildmenuTypes() {
+	c.wildmenuTypes = nil
+	c.wildmenuGen++
+	if !c.wildmenuIcons || c.content.firstc != ":" || !strings.Contains(c.content.content, " ") {
+		return
+	}
+	gen := c.wildmenuGen
+	cwd := c.ws.cwd
+	items := make([]string, len(c.rawItems))
+	for i, item := range c.rawItems {
+		items[i], _ = item.(string)
+	}
+	go func() {
+		types := make([]string, len(items))
+		for i, item := range items {
+			types[i] = wildmenuItemType(item, cwd)
+		}
+		c.ws.guiUpdates <- []interface{}{"gonvim_wildmenu_types", gen, types}
+		c.ws.signal.GuiSignal()
+	}()
+}
+
+func (c *Cmdline) wildmenuTypesResult(args []interface{}) {
+	if len(args) < 2 {
+		return
+	}
+	gen, _ := args[0].(int)
+	types, _ := args[1].([]string)
+	if gen != c.wildmenuGen || !c.wildmenuShown || len(types) != len(c.rawItems) {
+		return
+	}
+	c.wildmenuTypes = types
+	c.wildmenuScroll(0)
+}
+
+// wildmenuType is the type of the item, "" until it has been looked up
+func (c *Cmdline) wildmenuType(i int) string {
+	if i >= len(c.wildmenuTypes) {
+		return ""
+	}
+	return c.wildmenuTypes[i]
+}
+
+// wildmenuItemType returns "dir" or "file" for a completed item naming a
+// directory or a file, relative ones taken from cwd, and "" otherwise
+func wildmenuItemType(text string, cwd string) string {
+	path, err := homedir.Expand(text)
+	if err != nil {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return "dir"
+	}
+	return "file"
+}
+
 func (c *Cmdline) wildmenuHide() {
 	c.wildmenuShown = false
 }
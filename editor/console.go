@@ -0,0 +1,191 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+const (
+	consoleLines   = 500
+	consoleArgsMax = 100
+)
+
+// Console is the overlay showing the redraw events and Gui notifications as
+// they are dispatched, for debugging. Events go into a ring buffer and the
+// view is refreshed at most every 100ms.
+type Console struct {
+	ws     *Workspace
+	widget *widgets.QPlainTextEdit
+	timer  *core.QTimer
+	shown  bool
+	paused bool
+	dirty  bool
+	filter string
+	lines  []consoleLine
+	next   int
+	full   bool
+}
+
+type consoleLine struct {
+	event string
+	text  string
+}
+
+func initConsole() *Console {
+	widget := widgets.NewQPlainTextEdit(nil)
+	widget.SetReadOnly(true)
+	widget.SetFocusPolicy(core.Qt__NoFocus)
+	widget.SetLineWrapMode(widgets.QPlainTextEdit__NoWrap)
+	widget.SetFont(gui.NewQFont2("Monospace", 9, int(gui.QFont__Normal), false))
+	widget.SetStyleSheet(`
+	QPlainTextEdit {
+		border: none;
+		border-top: 1px solid #000;
+		color: rgba(205, 211, 222, 1);
+		background-color: rgba(24, 29, 34, 0.9);
+	}
+	`)
+	widget.Hide()
+	c := &Console{
+		widget: widget,
+		timer:  core.NewQTimer(nil),
+		lines:  make([]consoleLine, consoleLines),
+	}
+	c.timer.ConnectTimeout(c.refresh)
+	return c
+}
+
+// command handles GonvimConsole with no args or on/off to toggle, pause,
+// clear, and filter {name} to only show the events whose name contains name
+func (c *Console) command(args []interface{}) {
+	sub := ""
+	if len(args) > 0 {
+		sub, _ = args[0].(string)
+	}
+	switch sub {
+	case "pause":
+		c.paused = !c.paused
+		c.dirty = true
+	case "clear":
+		c.next = 0
+		c.full = false
+		c.dirty = true
+	case "filter":
+		c.filter = ""
+		if len(args) > 1 {
+			c.filter, _ = args[1].(string)
+		}
+		c.dirty = true
+	default:
+		c.toggle(toggleArg(c.shown, args))
+	}
+}
+
+func (c *Console) toggle(shown bool) {
+	if shown == c.shown {
+		return
+	}
+	c.shown = shown
+	if shown {
+		c.resize()
+		c.widget.Show()
+		c.widget.Raise()
+		c.dirty = true
+		c.timer.Start(100)
+	} else {
+		c.widget.Hide()
+		c.timer.Stop()
+	}
+}
+
+func (c *Console) resize() {
+	if !c.shown {
+		return
+	}
+	width := c.ws.screen.widget.Width()
+	height := c.ws.screen.widget.Height() / 3
+	c.widget.Resize2(width, height)
+	c.widget.Move2(0, c.ws.screen.widget.Height()-height)
+}
+
+func (c *Console) redraw(updates [][]interface{}) {
+	if !c.shown || c.paused {
+		return
+	}
+	for _, update := range updates {
+		if len(update) == 0 {
+			continue
+		}
+		event, _ := update[0].(string)
+		c.add("redraw", event, update[1:])
+	}
+}
+
+func (c *Console) gui(updates []interface{}) {
+	if !c.shown || c.paused || len(updates) == 0 {
+		return
+	}
+	event, _ := updates[0].(string)
+	c.add("Gui", event, updates[1:])
+}
+
+// shortenArgs cuts the text to consoleArgsMax bytes, on the start of a
+// character so the line doesn't end in half of one
+func shortenArgs(text string) string {
+	if len(text) <= consoleArgsMax {
+		return text
+	}
+	end := consoleArgsMax
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end] + "…"
+}
+
+func (c *Console) add(method, event string, args []interface{}) {
+	text := shortenArgs(fmt.Sprintf("%v", args))
+	c.lines[c.next] = consoleLine{
+		event: event,
+		text:  fmt.Sprintf("%-6s %-20s %s", method, event, text),
+	}
+	c.next++
+	if c.next == consoleLines {
+		c.next = 0
+		c.full = true
+	}
+	c.dirty = true
+}
+
+func (c *Console) refresh() {
+	if !c.dirty {
+		return
+	}
+	c.dirty = false
+	entries := []consoleLine{}
+	if c.full {
+		entries = append(entries, c.lines[c.next:]...)
+	}
+	entries = append(entries, c.lines[:c.next]...)
+	lines := []string{}
+	for _, entry := range entries {
+		if c.filter != "" && !strings.Contains(entry.event, c.filter) {
+			continue
+		}
+		lines = append(lines, entry.text)
+	}
+	status := fmt.Sprintf("-- console: %d events", len(lines))
+	if c.filter != "" {
+		status += fmt.Sprintf(", filter %q", c.filter)
+	}
+	if c.paused {
+		status += ", paused"
+	}
+	lines = append(lines, status)
+	c.widget.SetPlainText(strings.Join(lines, "\n"))
+	c.widget.VerticalScrollBar().SetValue(c.widget.VerticalScrollBar().Maximum())
+}
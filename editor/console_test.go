@@ -0,0 +1,29 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestShortenArgs(t *testing.T) {
+	short := "[1 2 3]"
+	if text := shortenArgs(short); text != short {
+		t.Errorf("shortenArgs(%q) = %q", short, text)
+	}
+	exact := strings.Repeat("a", consoleArgsMax)
+	if text := shortenArgs(exact); text != exact {
+		t.Errorf("shortenArgs of %d bytes = %q", consoleArgsMax, text)
+	}
+	for offset := 0; offset < 3; offset++ {
+		// the cut falls at each byte of a three byte character
+		long := strings.Repeat("a", consoleArgsMax-offset) + strings.Repeat("漢", 10)
+		text := shortenArgs(long)
+		if !utf8.ValidString(text) {
+			t.Errorf("offset %d: shortenArgs cut a character: %q", offset, text)
+		}
+		if !strings.HasSuffix(text, "…") || len(text)-len("…") > consoleArgsMax {
+			t.Errorf("offset %d: shortenArgs = %q", offset, text)
+		}
+	}
+}
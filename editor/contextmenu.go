@@ -0,0 +1,125 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// With 'mousemodel' popup or popup_setpos a right click shows the entries of
+// neovim's PopUp menu for the current mode in a native menu instead of
+// sending <RightMouse>, and picking one runs it with :emenu. popup_setpos
+// moves the cursor to the click first, unless in visual mode.
+type contextMenu struct {
+	mousemodel string
+	pos        *core.QPoint
+	menu       *widgets.QMenu
+}
+
+func (c *contextMenu) enabled() bool {
+	return c.mousemodel == "popup" || c.mousemodel == "popup_setpos"
+}
+
+// contextClick handles a right click when the context menu is on, and
+// swallows the release that follows it
+func (s *Screen) contextClick(event *gui.QMouseEvent) bool {
+	c := &s.context
+	if !c.enabled() || event.Button() != core.Qt__RightButton {
+		return false
+	}
+	if event.Type() != core.QEvent__MouseButtonPress {
+		return true
+	}
+	c.pos = event.GlobalPos()
+	col, row := s.pointCell(event.X(), event.Y())
+	setpos := c.mousemodel == "popup_setpos" && !strings.HasPrefix(s.ws.mode, "visual")
+	go func() {
+		if setpos {
+			s.ws.input(fmt.Sprintf("<LeftMouse><%d,%d>", col, row))
+		}
+		s.ws.nvim.Command(`call rpcnotify(0, 'Gui', 'gonvim_context_menu', menu_get('PopUp'), mode())`)
+	}()
+	return true
+}
+
+func (c *contextMenu) setMousemodel(args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	c.mousemodel, _ = args[0].(string)
+}
+
+// showContextMenu takes what menu_get('PopUp') returned and the mode
+func (s *Screen) showContextMenu(args []interface{}) {
+	c := &s.context
+	if len(args) < 2 || c.pos == nil {
+		return
+	}
+	menus, _ := args[0].([]interface{})
+	mode, _ := args[1].(string)
+	modeKey := "n"
+	prefix := ""
+	switch mode {
+	case "v", "V", "\x16", "s", "S", "\x13":
+		modeKey = "v"
+	case "i":
+		modeKey = "i"
+		prefix = "<C-o>"
+	}
+	if len(menus) == 0 {
+		return
+	}
+	popup, _ := menus[0].(map[string]interface{})
+	submenus, _ := popup["submenus"].([]interface{})
+
+	if c.menu != nil {
+		c.menu.DeleteLater()
+	}
+	c.menu = widgets.NewQMenu(s.widget)
+	added := 0
+	for _, submenu := range submenus {
+		entry, ok := submenu.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := entry["name"].(string)
+		if name == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "-") && strings.HasSuffix(name, "-") {
+			if added > 0 {
+				c.menu.AddSeparator()
+			}
+			continue
+		}
+		mappings, _ := entry["mappings"].(map[string]interface{})
+		mapping, ok := mappings[modeKey].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		action := c.menu.AddAction(name)
+		if enabled, ok := mapping["enabled"]; ok && reflectToInt(enabled) == 0 {
+			action.SetEnabled(false)
+		}
+		input := fmt.Sprintf("%s:emenu PopUp.%s<CR>", prefix, escapeMenuName(name))
+		action.ConnectTriggered(func(checked bool) {
+			s.ws.input(input)
+		})
+		added++
+	}
+	if added == 0 {
+		return
+	}
+	c.menu.Popup(c.pos, nil)
+}
+
+// escapeMenuName escapes a menu name for :emenu typed with nvim_input
+func escapeMenuName(name string) string {
+	name = strings.Replace(name, `\`, `\\`, -1)
+	name = strings.Replace(name, ".", `\.`, -1)
+	name = strings.Replace(name, " ", `\ `, -1)
+	return strings.Replace(name, "<", "<lt>", -1)
+}
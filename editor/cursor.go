@@ -1,56 +1,289 @@
 package editor
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/therecipe/qt/core"
 	"github.com/therecipe/qt/widgets"
 )
 
 // Cursor is
 type Cursor struct {
-	ws     *Workspace
-	widget *widgets.QWidget
-	mode   string
-	x      int
-	y      int
-	row    int
-	col    int
+	ws            *Workspace
+	widget        *widgets.QWidget
+	mode          string
+	terminal      bool
+	terminalShape string
+	terminalBlink string
+	blinkTimer    *core.QTimer
+	blinkOn       int
+	blinkOff      int
+	blinkWait     int
+	blinkShown    bool
+	blinkDisabled bool
+	presentSteady bool
+	steadyModes   map[string]bool
+	busy          bool
+	busyStyle     string
+	effect        *widgets.QGraphicsOpacityEffect
+	x             int
+	y             int
+	width         int
+	height        int
+	offsetY       int
+	row           int
+	col           int
 }
 
 func initCursorNew() *Cursor {
 	widget := widgets.NewQWidget(nil, 0)
+	effect := widgets.NewQGraphicsOpacityEffect(nil)
+	effect.SetOpacity(1)
+	widget.SetGraphicsEffect(effect)
 	cursor := &Cursor{
-		widget: widget,
+		widget:     widget,
+		effect:     effect,
+		blinkTimer: core.NewQTimer(nil),
+		blinkShown: true,
 	}
+	cursor.blinkTimer.SetSingleShot(true)
+	cursor.blinkTimer.ConnectTimeout(cursor.blink)
 	return cursor
 }
 
 func (c *Cursor) move() {
-	c.widget.Move2(c.x, c.y)
+	// a cursor in the zoomed window is scaled with it
+	x, y, factor := c.ws.screen.zoomPoint(c.x, c.y+c.offsetY)
+	c.widget.Resize2(
+		int(math.Ceil(float64(c.width)*factor)),
+		int(math.Ceil(float64(c.height)*factor)),
+	)
+	c.widget.Move2(x, y)
 	c.ws.loc.widget.Move2(c.x, c.y+c.ws.font.lineHeight)
 }
 
+func (c *Cursor) resize(width, height int) {
+	c.width = width
+	c.height = height
+	c.widget.Resize2(width, height)
+}
+
 func (c *Cursor) updateShape() {
-	mode := c.ws.mode
-	if mode == "normal" {
-		c.widget.Resize2(c.ws.font.width, c.ws.font.lineHeight)
-		c.widget.SetStyleSheet("background-color: rgba(255, 255, 255, 0.5)")
-	} else if mode == "insert" {
-		c.widget.Resize2(1, c.ws.font.lineHeight)
-		c.widget.SetStyleSheet("background-color: rgba(255, 255, 255, 0.9)")
+	if c.terminal {
+		c.updateTerminalShape()
+		return
+	}
+	switch c.ws.mode {
+	case "insert":
+		c.updateModeShape("insert", "vertical", 25, "rgba(255, 255, 255, 0.9)")
+	case "operator":
+		c.updateModeShape("operator", "horizontal", 50, "rgba(81, 154, 186, 0.8)")
+	default:
+		c.updateModeShape(c.ws.mode, "block", 100, "")
+	}
+}
+
+// updateModeShape sizes the cursor after the cursor_shape and
+// cell_percentage of the mode in mode_info_set, or the given shape when the
+// mode has none. A block fills the cell, a vertical bar takes the percentage
+// of the cell width and a horizontal one of the line height at the bottom.
+// color is the color of the cursor, by default translucent white for a
+// block and less so for a bar.
+func (c *Cursor) updateModeShape(mode, shape string, percentage int, color string) {
+	info, ok := c.ws.modeInfo[mode]
+	if ok {
+		if cursorShape, ok := info["cursor_shape"].(string); ok {
+			shape = cursorShape
+		}
+		if cellPercentage, ok := info["cell_percentage"]; ok && reflectToInt(cellPercentage) > 0 {
+			percentage = reflectToInt(cellPercentage)
+		}
+	}
+	width := int(math.Ceil(c.ws.font.truewidth))
+	height := c.ws.font.lineHeight
+	switch shape {
+	case "horizontal":
+		height = height * percentage / 100
+	case "vertical":
+		width = int(c.ws.font.truewidth * float64(percentage) / 100)
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	if color == "" {
+		color = "rgba(255, 255, 255, 0.5)"
+		if shape != "block" {
+			color = "rgba(255, 255, 255, 0.9)"
+		}
 	}
+	c.offsetY = c.ws.font.lineHeight - height
+	c.resize(width, height)
+	c.widget.SetStyleSheet("background-color: " + color)
+}
+
+// updateTerminalShape applies g:gonvim_terminal_cursor in terminal buffers,
+// which is one of block, bottom, underline or bar
+func (c *Cursor) updateTerminalShape() {
+	width := c.ws.font.width
+	height := c.ws.font.lineHeight
+	alpha := 0.5
+	switch c.terminalShape {
+	case "bottom":
+		height = c.ws.font.lineHeight / 2
+	case "underline":
+		height = 2
+		alpha = 0.9
+	case "bar":
+		width = 1
+		alpha = 0.9
+	}
+	c.offsetY = c.ws.font.lineHeight - height
+	c.resize(width, height)
+	c.widget.SetStyleSheet(fmt.Sprintf("background-color: rgba(255, 255, 255, %v)", alpha))
+}
+
+// updateBlink sets the blinking of the cursor for the mode. In editor buffers
+// it is the blinkwait, blinkon and blinkoff of 'guicursor' as sent in
+// mode_info_set. In terminal buffers g:gonvim_terminal_cursor_blink decides,
+// "insert" (the default) blinks only in terminal mode so a TUI running in
+// the terminal isn't left with a blinking cursor, "always" and "never" do as
+// they say. The modes in g:gonvim_cursor_steady, e.g. ['insert'], never
+// blink whatever the rest says.
+func (c *Cursor) updateBlink() {
+	c.blinkOn, c.blinkOff, c.blinkWait = c.blinkTimes()
+	c.resetBlink()
+}
+
+// blinkTimes returns the blinkon, blinkoff and blinkwait for the mode and
+// buffer, all 0 for a steady cursor
+func (c *Cursor) blinkTimes() (int, int, int) {
+	if c.blinkDisabled || c.presentSteady || c.steadyModes[c.mode] {
+		return 0, 0, 0
+	}
+	if c.terminal {
+		blink := false
+		switch c.terminalBlink {
+		case "always":
+			blink = true
+		case "never":
+			blink = false
+		default:
+			blink = c.mode == "insert" || c.mode == "terminal"
+		}
+		if blink {
+			return 500, 500, 500
+		}
+		return 0, 0, 0
+	}
+	info, ok := c.ws.modeInfo[c.mode]
+	if !ok {
+		return 0, 0, 0
+	}
+	return reflectToInt(info["blinkon"]), reflectToInt(info["blinkoff"]), reflectToInt(info["blinkwait"])
+}
+
+// resetBlink shows the cursor and starts blinking again after blinkwait
+func (c *Cursor) resetBlink() {
+	c.blinkTimer.Stop()
+	c.blinkShown = true
+	switch c.busyState() {
+	case "hide":
+		c.widget.Hide()
+		return
+	case "dim":
+		c.widget.Show()
+		return
+	}
+	c.widget.Show()
+	if c.blinkOn <= 0 || c.blinkOff <= 0 {
+		return
+	}
+	c.blinkTimer.Start(c.blinkWait + c.blinkOn)
+}
+
+func (c *Cursor) blink() {
+	if c.steadyModes[c.ws.mode] {
+		c.blinkShown = true
+		c.widget.Show()
+		return
+	}
+	c.blinkShown = !c.blinkShown
+	c.widget.SetVisible(c.blinkShown)
+	if c.blinkShown {
+		c.blinkTimer.Start(c.blinkOn)
+	} else {
+		c.blinkTimer.Start(c.blinkOff)
+	}
+}
+
+// toggleBlink turns the blinking on or off with GonvimCursorBlink, off keeps
+// the cursor steady in every mode like g:gonvim_cursor_blink 0 does
+func (c *Cursor) toggleBlink(args []interface{}) {
+	blink := toggleArg(!c.blinkDisabled, args)
+	if blink == !c.blinkDisabled {
+		return
+	}
+	c.blinkDisabled = !blink
+	c.updateBlink()
+}
+
+// setBusy follows busy_start and busy_stop. While neovim is busy the cursor
+// is hidden, or with g:gonvim_busy_cursor "dim" shown faded and steady, or
+// with "show" left alone.
+func (c *Cursor) setBusy(busy bool) {
+	if c.busy == busy {
+		return
+	}
+	c.busy = busy
+	if c.busyState() == "dim" {
+		c.effect.SetOpacity(0.3)
+	} else {
+		c.effect.SetOpacity(1)
+	}
+	c.resetBlink()
+}
+
+// busyState is how the cursor is shown for being busy, "" when it isn't
+func (c *Cursor) busyState() string {
+	if !c.busy {
+		return ""
+	}
+	switch c.busyStyle {
+	case "show":
+		return ""
+	case "dim":
+		return "dim"
+	}
+	return "hide"
+}
+
+func (c *Cursor) isTerminal() bool {
+	win := c.ws.screen.cursorWin()
+	return win != nil && win.bufType == "terminal"
 }
 
 func (c *Cursor) update() {
-	if c.mode != c.ws.mode {
+	terminal := c.isTerminal()
+	c.ws.termCursorline.update(c.ws, terminal)
+	if c.mode != c.ws.mode || c.terminal != terminal {
 		c.mode = c.ws.mode
+		c.terminal = terminal
 		c.updateShape()
+		c.updateBlink()
+		c.move()
 	}
-	row := c.ws.screen.cursor[0]
-	col := c.ws.screen.cursor[1]
+	row, col := c.ws.screen.clampCursor(c.ws.screen.cursor[0], c.ws.screen.cursor[1])
 	if c.row != row || c.col != col {
-		c.x = int(float64(col) * c.ws.font.truewidth)
+		c.x = c.ws.font.cellX(col)
 		c.y = row * c.ws.font.lineHeight
+		if c.ws.screen.tooltip.IsVisible() {
+			c.x, c.y = c.ws.screen.placeToolTip(row, col)
+		}
 		c.move()
+		c.resetBlink()
 	}
-	c.ws.screen.tooltip.Move(core.NewQPoint2(c.x, c.y))
 }
@@ -0,0 +1,36 @@
+package editor
+
+import "testing"
+
+func TestCursorBlinkTimes(t *testing.T) {
+	ws := &Workspace{
+		modeInfo: map[string]map[string]interface{}{
+			"normal": {"blinkon": int64(400), "blinkoff": int64(250), "blinkwait": int64(700)},
+			"insert": {"blinkon": int64(0), "blinkoff": int64(0), "blinkwait": int64(0)},
+		},
+	}
+	cases := []struct {
+		name          string
+		cursor        Cursor
+		on, off, wait int
+	}{
+		{"editor normal", Cursor{mode: "normal"}, 400, 250, 700},
+		{"editor insert", Cursor{mode: "insert"}, 0, 0, 0},
+		{"editor unknown mode", Cursor{mode: "visual"}, 0, 0, 0},
+		{"terminal normal", Cursor{mode: "normal", terminal: true}, 0, 0, 0},
+		{"terminal mode", Cursor{mode: "terminal", terminal: true}, 500, 500, 500},
+		{"terminal always", Cursor{mode: "normal", terminal: true, terminalBlink: "always"}, 500, 500, 500},
+		{"terminal never", Cursor{mode: "terminal", terminal: true, terminalBlink: "never"}, 0, 0, 0},
+		{"steady mode", Cursor{mode: "normal", steadyModes: map[string]bool{"normal": true}}, 0, 0, 0},
+		{"disabled", Cursor{mode: "terminal", terminal: true, blinkDisabled: true}, 0, 0, 0},
+		{"present mode", Cursor{mode: "normal", presentSteady: true}, 0, 0, 0},
+	}
+	for _, c := range cases {
+		cursor := c.cursor
+		cursor.ws = ws
+		on, off, wait := cursor.blinkTimes()
+		if on != c.on || off != c.off || wait != c.wait {
+			t.Errorf("%s: blinkTimes() = %d, %d, %d, want %d, %d, %d", c.name, on, off, wait, c.on, c.off, c.wait)
+		}
+	}
+}
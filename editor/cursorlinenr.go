@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// cursorLineNr shows the number of the cursor's line in the focused window
+// in CursorLineNr with g:gonvim_cursor_line_nr set, whether 'cursorline' is
+// on or not, and leaves the rest of the line alone. The number cells are
+// only recolored, so with 'relativenumber' it is the 0 or the absolute
+// number neovim puts there.
+type cursorLineNr struct {
+	enabled bool
+	fg      *RGBA
+	bg      *RGBA
+	bold    bool
+	row     int
+	start   int
+	end     int
+}
+
+// moveCursorNr is called after every redraw and repaints the number cells
+// the highlight was on and goes to
+func (s *Screen) moveCursorNr() {
+	nr := &s.cursorNr
+	row, start, end := -1, 0, 0
+	if nr.enabled {
+		win := s.cursorWin()
+		if win != nil && win.numcol >= 0 && win.textstart > win.numcol && win.textstart <= win.width {
+			start = win.pos[1] + win.numcol
+			end = win.pos[1] + win.textstart
+			row = s.numberRow(s.cursor[0], win.pos[0], start, end)
+		}
+	}
+	if row == nr.row && start == nr.start && end == nr.end {
+		return
+	}
+	if nr.row >= 0 && nr.end > nr.start {
+		s.queueRedraw(nr.start, nr.row, nr.end-nr.start, 1)
+	}
+	if row >= 0 {
+		s.queueRedraw(start, row, end-start, 1)
+	}
+	nr.row = row
+	nr.start = start
+	nr.end = end
+}
+
+// numberRow returns the first screen row of the line on the row, the one
+// with the line number. The rows a long line wraps onto, and the filler
+// lines above it, have blank number cells, so it goes up to the first row
+// with a number, or returns -1 when there is none down from the window's top.
+func (s *Screen) numberRow(row, top, start, end int) int {
+	for ; row >= top && row < len(s.content); row-- {
+		line := s.content[row]
+		for x := start; x < end && x < len(line); x++ {
+			char := line[x]
+			if char != nil && char.char != "" && char.char != " " {
+				return row
+			}
+		}
+	}
+	return -1
+}
+
+func (s *Screen) drawCursorNr(p *gui.QPainter) {
+	nr := &s.cursorNr
+	if !nr.enabled || nr.row < 0 || nr.row >= len(s.content) || nr.end <= nr.start {
+		return
+	}
+	fg := nr.fg
+	if fg == nil {
+		fg = s.ws.foreground
+	}
+	if fg == nil {
+		return
+	}
+	font := s.ws.font
+	line := s.content[nr.row]
+	bg := nr.bg
+	if bg == nil {
+		bg = s.ws.background
+		if s.editorBg != nil {
+			bg = s.editorBg
+		}
+	}
+	left := font.cellX(nr.start)
+	width := font.cellsWidth(nr.start, nr.end)
+	if nr.bg != nil {
+		p.FillRect5(left, nr.row*font.lineHeight, width, font.lineHeight, bg.QColor())
+	} else {
+		s.fillBackground(p, left, nr.row*font.lineHeight, width, font.lineHeight, bg)
+		s.fillHightlight(p, nr.row, nr.start, nr.end-nr.start, [2]int{0, 0})
+	}
+	s.setFontStyle(p, nr.bold, false)
+	point := core.NewQPointF()
+	for x := nr.start; x < nr.end && x < len(line); x++ {
+		char := line[x]
+		if char == nil || char.char == "" || char.char == " " {
+			continue
+		}
+		point.SetX(font.textX(x))
+		point.SetY(float64(nr.row*font.lineHeight + font.shift))
+		s.drawGlyphs(p, point, char.char, fg)
+	}
+	s.setFontStyle(p, false, false)
+}
@@ -0,0 +1,105 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+func textLine(text string) []*Char {
+	line := make([]*Char, len(text))
+	for i, r := range text {
+		line[i] = &Char{char: string(r)}
+	}
+	return line
+}
+
+func TestNumberRow(t *testing.T) {
+	s := &Screen{
+		content: [][]*Char{
+			textLine("    a long line that"),
+			textLine("  1 first line"),
+			textLine("  2 a long line that"),
+			textLine("    wraps onto a"),
+			textLine("    third row"),
+			textLine("    "),
+			textLine("  3 after a filler"),
+		},
+	}
+	cases := []struct {
+		row, top int
+		want     int
+	}{
+		{1, 0, 1},
+		{2, 0, 2},
+		{3, 0, 2},
+		{4, 0, 2},
+		{6, 0, 6},
+		{0, 0, -1},
+		{4, 3, -1},
+	}
+	for _, c := range cases {
+		row := s.numberRow(c.row, c.top, 0, 4)
+		if row != c.want {
+			t.Errorf("numberRow(%d, %d) = %d, want %d", c.row, c.top, row, c.want)
+		}
+	}
+}
+
+// with 'relativenumber' and 'cursorline' neovim puts the absolute number of
+// the cursor's line in CursorLineNr and the relative ones in LineNr
+func TestRelativeNumberCursorLine(t *testing.T) {
+	s := newGridScreen(4, 20)
+	s.ws.foreground = calcColor(0xabb2bf)
+	s.ws.background = calcColor(0x282c34)
+	lineNrHl := map[string]interface{}{"foreground": int64(0x5c6370)}
+	cursorLineNrHl := map[string]interface{}{"foreground": int64(0xe5c07b), "bold": true}
+	lines := []struct {
+		number, text string
+		hl           map[string]interface{}
+	}{
+		{"  2 ", "first", lineNrHl},
+		{"  1 ", "second", lineNrHl},
+		{"12  ", "third", cursorLineNrHl},
+		{"  1 ", "fourth", lineNrHl},
+	}
+	for row, line := range lines {
+		gridSetHighlight(s, line.hl)
+		gridPut(s, row, 0, line.number)
+		gridSetHighlight(s, map[string]interface{}{})
+		gridPut(s, row, 4, line.text)
+	}
+
+	cases := []struct {
+		row, col int
+		fg       string
+		bold     int
+	}{
+		{0, 2, "#5c6370", 0},
+		{1, 2, "#5c6370", 0},
+		{2, 0, "#e5c07b", 1},
+		{2, 1, "#e5c07b", 1},
+		{3, 2, "#5c6370", 0},
+		{2, 4, "#abb2bf", 0},
+	}
+	for _, c := range cases {
+		cell := s.cellAt(c.row, c.col)
+		if cell["fg"] != c.fg || cell["bold"] != c.bold {
+			t.Errorf("cellAt(%d, %d) = %v, want fg %s bold %d", c.row, c.col, cell, c.fg, c.bold)
+		}
+	}
+
+	// g:gonvim_cursor_line_nr follows the row with the cursor's absolute
+	// number and not a relative 1 or 2
+	s.curWins = map[nvim.Window]*Window{
+		1000: {win: 1000, pos: [2]int{0, 0}, width: 20, height: 4, numcol: 0, textstart: 4},
+	}
+	s.cursorNr = cursorLineNr{enabled: true, row: -1}
+	for _, row := range []int{2, 0} {
+		s.cursor = [2]int{row, 6}
+		s.moveCursorNr()
+		if nr := s.cursorNr; nr.row != row || nr.start != 0 || nr.end != 4 {
+			t.Errorf("cursor line number on row %d is on row %d cells %d to %d", row, nr.row, nr.start, nr.end)
+		}
+	}
+}
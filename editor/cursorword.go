@@ -0,0 +1,149 @@
+package editor
+
+import (
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+const cursorWordDelay = 100
+
+// CursorWord shades the other occurrences of the word under the cursor in
+// the focused window. It is worked out from the cells on the screen, so only
+// the visible lines are searched, after the cursor or the screen has been
+// still for cursorWordDelay ms. GonvimCursorWord on/off or
+// g:gonvim_cursor_word turns it on, the color is the background of
+// g:gonvim_cursor_word_group, Visual by default.
+type CursorWord struct {
+	s       *Screen
+	enabled bool
+	group   string
+	color   *RGBA
+	timer   *core.QTimer
+	word    string
+	matches map[int][][2]int
+}
+
+func newCursorWord(s *Screen) *CursorWord {
+	c := &CursorWord{
+		s:       s,
+		group:   "Visual",
+		timer:   core.NewQTimer(nil),
+		matches: map[int][][2]int{},
+	}
+	c.timer.SetSingleShot(true)
+	c.timer.ConnectTimeout(c.refresh)
+	return c
+}
+
+func (c *CursorWord) toggle(args []interface{}) {
+	enabled := toggleArg(c.enabled, args)
+	if enabled == c.enabled {
+		return
+	}
+	c.enabled = enabled
+	c.refresh()
+}
+
+// changed is called after every redraw and waits for things to settle
+func (c *CursorWord) changed() {
+	if !c.enabled {
+		return
+	}
+	c.timer.Start(cursorWordDelay)
+}
+
+func isWordChar(char *Char) bool {
+	if char == nil {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(char.char)
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (c *CursorWord) refresh() {
+	s := c.s
+	word := ""
+	matches := map[int][][2]int{}
+	row := s.cursor[0]
+	col := s.cursor[1]
+	win := s.windowAt(row, col)
+	if c.enabled && win != nil && row < len(s.content) && col < len(s.content[row]) && isWordChar(s.content[row][col]) {
+		line := s.content[row]
+		left := win.pos[1]
+		right := win.pos[1] + win.width
+		start := col
+		for start > left && isWordChar(line[start-1]) {
+			start--
+		}
+		end := col
+		for end < right-1 && end+1 < len(line) && isWordChar(line[end+1]) {
+			end++
+		}
+		word = cellsText(line[start : end+1])
+		for y := win.pos[0]; y < win.pos[0]+win.height && y < len(s.content); y++ {
+			line := s.content[y]
+			for x := left; x < right && x < len(line); {
+				if !isWordChar(line[x]) {
+					x++
+					continue
+				}
+				runEnd := x
+				for runEnd+1 < right && runEnd+1 < len(line) && isWordChar(line[runEnd+1]) {
+					runEnd++
+				}
+				if runEnd-x == end-start && cellsText(line[x:runEnd+1]) == word {
+					matches[y] = append(matches[y], [2]int{x, runEnd + 1})
+				}
+				x = runEnd + 1
+			}
+		}
+	}
+	if word == c.word && reflect.DeepEqual(matches, c.matches) {
+		return
+	}
+	for y, m := range c.matches {
+		for _, match := range m {
+			s.queueRedraw(match[0], y, match[1]-match[0], 1)
+		}
+	}
+	for y, m := range matches {
+		for _, match := range m {
+			s.queueRedraw(match[0], y, match[1]-match[0], 1)
+		}
+	}
+	c.word = word
+	c.matches = matches
+	s.update()
+}
+
+func (c *CursorWord) fill(p *gui.QPainter, y int, col int, cols int) {
+	if !c.enabled || c.color == nil {
+		return
+	}
+	font := c.s.ws.font
+	color := gui.NewQColor3(c.color.R, c.color.G, c.color.B, 128)
+	for _, match := range c.matches[y] {
+		start := match[0]
+		if col > start {
+			start = col
+		}
+		end := match[1]
+		if col+cols < end {
+			end = col + cols
+		}
+		if start >= end {
+			continue
+		}
+		p.FillRect5(
+			font.cellX(start),
+			y*font.lineHeight,
+			font.cellsWidth(start, end),
+			font.lineHeight,
+			color,
+		)
+	}
+}
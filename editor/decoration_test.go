@@ -0,0 +1,54 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/therecipe/qt/gui"
+)
+
+type decorationRun struct {
+	start, end, row int
+	color           string
+}
+
+func decorationRuns(s *Screen, y int, has func(*Highlight) bool) []decorationRun {
+	runs := []decorationRun{}
+	s.drawDecorationRuns(nil, y, 0, s.ws.cols, [2]int{0, 0}, has, func(p *gui.QPainter, start, end, row int, color *RGBA) {
+		runs = append(runs, decorationRun{start, end, row, color.Hex()})
+	})
+	return runs
+}
+
+// each spell group is drawn in its own guisp, whether it uses undercurl or
+// underline
+func TestSpellDecorationRuns(t *testing.T) {
+	s := newGridScreen(1, 16)
+	s.ws.foreground = calcColor(0xabb2bf)
+	gridSetHighlight(s, map[string]interface{}{"undercurl": true, "special": int64(0xff0000)})
+	gridPut(s, 0, 0, "bad")
+	gridSetHighlight(s, map[string]interface{}{"underline": true, "special": int64(0x0000ff)})
+	gridPut(s, 0, 4, "Cap")
+	gridSetHighlight(s, map[string]interface{}{"underline": true, "special": int64(0xff00ff)})
+	gridPut(s, 0, 7, "rare")
+	gridSetHighlight(s, map[string]interface{}{"underline": true})
+	gridPut(s, 0, 12, "loc")
+
+	undercurls := decorationRuns(s, 0, func(hl *Highlight) bool { return hl.undercurl })
+	if len(undercurls) != 1 || undercurls[0] != (decorationRun{0, 3, 0, "#ff0000"}) {
+		t.Errorf("undercurl runs %v", undercurls)
+	}
+	underlines := decorationRuns(s, 0, func(hl *Highlight) bool { return hl.underline && !hl.undercurl })
+	want := []decorationRun{
+		{4, 7, 0, "#0000ff"},
+		{7, 11, 0, "#ff00ff"},
+		{12, 15, 0, "#abb2bf"},
+	}
+	if len(underlines) != len(want) {
+		t.Fatalf("underline runs %v, want %v", underlines, want)
+	}
+	for i := range want {
+		if underlines[i] != want[i] {
+			t.Errorf("underline run %d is %v, want %v", i, underlines[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/therecipe/qt/gui"
+)
+
+// the diff groups in the order a line is classified by
+var diffGroups = []string{"DiffText", "DiffChange", "DiffAdd", "DiffDelete"}
+
+// hlAttrs are the attrs of highlight groups by group and attr, as
+// synIDattr returns them after following links
+type hlAttrs map[[2]string]string
+
+func (a hlAttrs) color(group, attr string) *RGBA {
+	return hexToRGBA(a[[2]string{group, attr}])
+}
+
+// getHlAttrs gets the attrs of the highlight groups in one call. The names
+// go to neovim as arguments, so they need no quoting.
+func (w *Workspace) getHlAttrs(keys [][2]string) hlAttrs {
+	attrs := hlAttrs{}
+	if len(keys) == 0 {
+		return attrs
+	}
+	list := make([][]string, len(keys))
+	for i, key := range keys {
+		list[i] = []string{key[0], key[1]}
+	}
+	result := []string{}
+	err := w.nvim.Call("map", &result, list, "synIDattr(synIDtrans(hlID(v:val[0])), v:val[1])")
+	if err != nil || len(result) != len(keys) {
+		return attrs
+	}
+	for i, key := range keys {
+		attrs[key] = result[i]
+	}
+	return attrs
+}
+
+// hlColor returns the color of the attr ("fg", "bg" or "sp") of the
+// highlight group after following links
+func (w *Workspace) hlColor(name, attr string) *RGBA {
+	return w.getHlAttrs([][2]string{{name, attr}}).color(name, attr)
+}
+
+// updateColors gets the highlight group colors the screen draws with. The
+// groups are gathered here on the GUI thread and fetched together in a
+// goroutine, and setColors swaps them in back on the GUI thread, as paint
+// reads them.
+func (s *Screen) updateColors() {
+	keys := s.colorKeys()
+	go func() {
+		attrs := s.ws.getHlAttrs(keys)
+		s.ws.guiUpdates <- []interface{}{"gonvim_colors", attrs}
+		s.ws.signal.GuiSignal()
+	}()
+}
+
+func (s *Screen) colorKeys() [][2]string {
+	keys := [][2]string{
+		{"SpecialKey", "fg"},
+		{"Folded", "fg"},
+		{"CursorLineNr", "fg"},
+		{"CursorLineNr", "bg"},
+		{"CursorLineNr", "bold"},
+		{s.cursorWord.group, "bg"},
+		{s.lineMarker.group, "fg"},
+	}
+	for _, group := range diffGroups {
+		keys = append(keys, [2]string{group, "bg"})
+	}
+	if s.inlay != nil {
+		keys = append(keys, [2]string{s.inlay.group, "fg"}, [2]string{s.inlay.group, "bg"})
+	}
+	for _, style := range s.hlStyles {
+		keys = append(keys, [2]string{style.group, "fg"}, [2]string{style.group, "bg"})
+		for _, attr := range hlStyleAttrs {
+			keys = append(keys, [2]string{style.group, attr})
+		}
+	}
+	keys = append(keys, popupColorKeys...)
+	for _, group := range messageGroups {
+		keys = append(keys, [2]string{group, "fg"})
+	}
+	return keys
+}
+
+func (s *Screen) setColors(attrs hlAttrs) {
+	s.lineCache.invalidate()
+	diffColors := map[string]*RGBA{}
+	for _, group := range diffGroups {
+		color := attrs.color(group, "bg")
+		if color != nil {
+			diffColors[group] = color
+		}
+	}
+	s.diffColors = diffColors
+	s.specialKeyColor = attrs.color("SpecialKey", "fg")
+	s.foldedFg = attrs.color("Folded", "fg")
+	if s.inlay != nil {
+		s.inlay.fg = attrs.color(s.inlay.group, "fg")
+		s.inlay.bg = attrs.color(s.inlay.group, "bg")
+	}
+	for _, style := range s.hlStyles {
+		style.fg = attrs.color(style.group, "fg")
+		style.bg = attrs.color(style.group, "bg")
+		for i, attr := range hlStyleAttrs {
+			style.attrs[i] = attrs[[2]string{style.group, attr}] == "1"
+		}
+	}
+	s.cursorWord.color = attrs.color(s.cursorWord.group, "bg")
+	s.lineMarker.color = attrs.color(s.lineMarker.group, "fg")
+	s.cursorNr.fg = attrs.color("CursorLineNr", "fg")
+	s.cursorNr.bg = attrs.color("CursorLineNr", "bg")
+	s.cursorNr.bold = attrs[[2]string{"CursorLineNr", "bold"}] == "1"
+	chrome := s.ws.themeAttrs(attrs)
+	s.ws.popup.setColors(chrome)
+	s.ws.msgPanel.setColors(chrome)
+	s.queueRedrawAll()
+	s.update()
+}
+
+// diffGroup returns the diff group of the background color
+func (s *Screen) diffGroup(bg *RGBA) string {
+	if bg == nil {
+		return ""
+	}
+	colors := s.diffColors
+	for _, group := range diffGroups {
+		color, ok := colors[group]
+		if ok && color.equals(bg) {
+			return group
+		}
+	}
+	return ""
+}
+
+func (s *Screen) diffWin(x, y int) bool {
+	win := s.posWin(x, y)
+	return win != nil && win.diff
+}
+
+// diffLine returns the diff group of the row in the window
+func (s *Screen) diffLine(win *Window, y int) string {
+	if y >= len(s.content) {
+		return ""
+	}
+	line := s.content[y]
+	found := map[string]bool{}
+	for x := win.pos[1]; x < win.pos[1]+win.width && x < len(line); x++ {
+		char := line[x]
+		if char == nil {
+			continue
+		}
+		group := s.diffGroup(char.highlight.background)
+		if group != "" {
+			found[group] = true
+		}
+	}
+	for _, group := range diffGroups {
+		if found[group] {
+			return group
+		}
+	}
+	return ""
+}
+
+// drawDiffGutter draws a bar at the left edge of the windows in diff mode for
+// every changed line, and a thin line above deleted lines
+func (s *Screen) drawDiffGutter(p *gui.QPainter, row, rows int) {
+	font := s.ws.font
+	for _, win := range s.curWins {
+		if !win.diff {
+			continue
+		}
+		last := ""
+		for y := win.pos[0]; y < win.pos[0]+win.height; y++ {
+			group := s.diffLine(win, y)
+			if y < row || y >= row+rows {
+				last = group
+				continue
+			}
+			if group == "" {
+				last = group
+				continue
+			}
+			if group == "DiffText" {
+				group = "DiffChange"
+			}
+			color := s.diffBarColor(s.diffColors[group])
+			if color == nil {
+				last = group
+				continue
+			}
+			x := font.cellX(win.pos[1])
+			p.FillRect5(x, y*font.lineHeight, 3, font.lineHeight, color.QColor())
+			if group == "DiffDelete" && last != "DiffDelete" {
+				p.FillRect5(x, y*font.lineHeight, font.cellsWidth(win.pos[1], win.pos[1]+win.width), 2, color.QColor())
+			}
+			last = group
+		}
+	}
+}
+
+// drawDiffConnectors shades the split border between two side by side
+// windows in diff mode on the rows where either has a change, with
+// g:gonvim_diff_connectors set. Diff mode keeps the windows' rows in line
+// with filler lines, so the same screen row is the matching line.
+func (s *Screen) drawDiffConnectors(p *gui.QPainter, row, rows int) {
+	if !s.diffConnectors {
+		return
+	}
+	font := s.ws.font
+	state := []string{}
+	for _, left := range s.curWins {
+		if !left.diff {
+			continue
+		}
+		border := left.pos[1] + left.width
+		for _, right := range s.curWins {
+			if !right.diff || right.pos[1] != border+1 {
+				continue
+			}
+			top := maxInt(left.pos[0], right.pos[0])
+			bottom := minInt(left.pos[0]+left.height, right.pos[0]+right.height)
+			for y := top; y < bottom; y++ {
+				group := s.diffLine(left, y)
+				if group == "" {
+					group = s.diffLine(right, y)
+				}
+				if group == "" {
+					continue
+				}
+				if group == "DiffText" {
+					group = "DiffChange"
+				}
+				state = append(state, fmt.Sprintf("%d:%d:%s", border, y, group))
+				color := s.diffBarColor(s.diffColors[group])
+				if color == nil || y < row || y >= row+rows {
+					continue
+				}
+				p.FillRect5(
+					font.cellX(border),
+					y*font.lineHeight,
+					font.cellsWidth(border, border+1),
+					font.lineHeight,
+					gui.NewQColor3(color.R, color.G, color.B, 110),
+				)
+			}
+		}
+	}
+	// the border isn't in the area neovim redraws when the windows scroll
+	sort.Strings(state)
+	connectorState := strings.Join(state, ",")
+	if connectorState != s.connectorState {
+		s.connectorState = connectorState
+		s.widget.Update()
+	}
+}
+
+// diffBarColor is the color of the diff group made to stand out from the
+// cell backgrounds when those are drawn
+func (s *Screen) diffBarColor(color *RGBA) *RGBA {
+	if color == nil {
+		return nil
+	}
+	if !s.diffBackground {
+		return color
+	}
+	return newRGBA(color.R+(255-color.R)/3, color.G+(255-color.G)/3, color.B+(255-color.B)/3, 1)
+}
@@ -0,0 +1,67 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// Files dropped on the window are opened in new tabs of the active
+// workspace, or in splits with Shift (:split) or Ctrl (:vsplit) held. Drops
+// without local files, like plain text, are ignored.
+func (e *Editor) initDragDrop() {
+	e.window.ConnectDragEnterEvent(func(event *gui.QDragEnterEvent) {
+		if len(droppedFiles(event.MimeData())) == 0 {
+			event.Ignore()
+			return
+		}
+		event.AcceptProposedAction()
+	})
+	e.window.ConnectDragMoveEvent(func(event *gui.QDragMoveEvent) {
+		event.AcceptProposedAction()
+	})
+	e.window.ConnectDropEvent(e.drop)
+}
+
+func (e *Editor) drop(event *gui.QDropEvent) {
+	files := droppedFiles(event.MimeData())
+	if len(files) == 0 {
+		event.Ignore()
+		return
+	}
+	event.AcceptProposedAction()
+	command := "tabedit"
+	modifiers := event.KeyboardModifiers()
+	if modifiers&core.Qt__ShiftModifier != 0 {
+		command = "split"
+	} else if modifiers&core.Qt__ControlModifier != 0 {
+		command = "vsplit"
+	}
+	ws := e.workspaces[e.active]
+	go func() {
+		for _, file := range files {
+			ws.nvim.Command(fmt.Sprintf("execute '%s ' . fnameescape('%s')", command, strings.Replace(file, "'", "''", -1)))
+		}
+	}()
+	e.window.ActivateWindow()
+}
+
+// droppedFiles returns the local files of the text/uri-list of the drop
+func droppedFiles(mime *core.QMimeData) []string {
+	files := []string{}
+	if mime == nil || !mime.HasUrls() {
+		return files
+	}
+	for _, url := range mime.Urls() {
+		if !url.IsLocalFile() {
+			continue
+		}
+		file := url.ToLocalFile()
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
@@ -0,0 +1,146 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// dumpGrid writes what gonvim has in its grid to a file with GonvimDumpGrid
+// <path>, one line per row with blanks for empty cells, to compare with what
+// neovim has when the screen looks wrong. With a bang the rows are followed
+// by the highlight of every cell, as runs of "col-col:id" per row and a
+// list of what each id is. The ids are only for this dump, gonvim doesn't
+// keep neovim's.
+func (s *Screen) dumpGrid(args []interface{}) {
+	path := ""
+	if len(args) > 0 {
+		path, _ = args[0].(string)
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		go s.ws.nvim.Command(`echomsg "GonvimDumpGrid needs a file path"`)
+		return
+	}
+	annotate := len(args) > 1 && reflectToInt(args[1]) != 0
+	data := s.gridText(annotate)
+	go func() {
+		path, err := homedir.Expand(path)
+		if err == nil {
+			err = ioutil.WriteFile(path, data, 0644)
+		}
+		if err != nil {
+			s.ws.nvim.Command(fmt.Sprintf("echomsg '%s'", strings.Replace(err.Error(), "'", "''", -1)))
+			return
+		}
+		s.ws.nvim.Command(fmt.Sprintf("echomsg 'grid written to %s'", strings.Replace(path, "'", "''", -1)))
+	}()
+}
+
+func (s *Screen) gridText(annotate bool) []byte {
+	var buf bytes.Buffer
+	for row := 0; row < s.ws.rows; row++ {
+		for col := 0; col < s.ws.cols; col++ {
+			char := s.charAt(row, col)
+			if char == nil {
+				buf.WriteString(" ")
+				continue
+			}
+			// the cell after a wide character is empty
+			buf.WriteString(char.char)
+		}
+		buf.WriteString("\n")
+	}
+	if !annotate {
+		return buf.Bytes()
+	}
+
+	ids := map[string]int{}
+	hls := []string{}
+	buf.WriteString("\n")
+	for row := 0; row < s.ws.rows; row++ {
+		runs := []string{}
+		start := 0
+		last := -1
+		for col := 0; col <= s.ws.cols; col++ {
+			id := -1
+			if col < s.ws.cols {
+				hl := highlightText(s.charAt(row, col))
+				var ok bool
+				id, ok = ids[hl]
+				if !ok {
+					id = len(hls)
+					ids[hl] = id
+					hls = append(hls, hl)
+				}
+			}
+			if id == last {
+				continue
+			}
+			if last >= 0 {
+				runs = append(runs, fmt.Sprintf("%d-%d:%d", start, col-1, last))
+			}
+			start = col
+			last = id
+		}
+		buf.WriteString(fmt.Sprintf("%d: %s\n", row, strings.Join(runs, " ")))
+	}
+	buf.WriteString("\n")
+	for id, hl := range hls {
+		buf.WriteString(fmt.Sprintf("%d: %s\n", id, hl))
+	}
+	return buf.Bytes()
+}
+
+func (s *Screen) charAt(row, col int) *Char {
+	if row >= len(s.content) || col >= len(s.content[row]) {
+		return nil
+	}
+	return s.content[row][col]
+}
+
+// highlightText describes the highlight of the cell, blank cells are "none"
+func highlightText(char *Char) string {
+	if char == nil {
+		return "none"
+	}
+	hl := char.highlight
+	parts := []string{}
+	if hl.foreground != nil {
+		parts = append(parts, "fg="+hl.foreground.Hex())
+	}
+	if hl.background != nil {
+		parts = append(parts, "bg="+hl.background.Hex())
+	}
+	if hl.special != nil {
+		parts = append(parts, "sp="+hl.special.Hex())
+	}
+	if hl.bold {
+		parts = append(parts, "bold")
+	}
+	if hl.italic {
+		parts = append(parts, "italic")
+	}
+	if hl.undercurl {
+		parts = append(parts, "undercurl")
+	}
+	if hl.underline {
+		parts = append(parts, "underline")
+	}
+	if hl.underdouble {
+		parts = append(parts, "underdouble")
+	}
+	if hl.strike {
+		parts = append(parts, "strikethrough")
+	}
+	if hl.reverse {
+		parts = append(parts, "reverse")
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, " ")
+}
@@ -20,8 +20,16 @@ var editor *Editor
 
 // Highlight is
 type Highlight struct {
-	foreground *RGBA
-	background *RGBA
+	foreground  *RGBA
+	background  *RGBA
+	special     *RGBA
+	bold        bool
+	italic      bool
+	undercurl   bool
+	underline   bool
+	underdouble bool
+	strike      bool
+	reverse     bool
 }
 
 // Char is
@@ -41,6 +49,10 @@ type Editor struct {
 	window     *widgets.QMainWindow
 	wsWidget   *widgets.QWidget
 	wsSide     *WorkspaceSide
+	switcher   *WorkspaceSwitcher
+	splash     *Splash
+	snapTimer  *core.QTimer
+	snapSize   bool
 
 	statuslineHeight int
 	width            int
@@ -52,6 +64,8 @@ type Editor struct {
 	stop     chan struct{}
 	stopOnce sync.Once
 
+	translucent bool
+
 	specialKeys     map[core.Qt__Key]string
 	controlModifier core.Qt__KeyboardModifier
 	cmdModifier     core.Qt__KeyboardModifier
@@ -83,6 +97,15 @@ func (hl *Highlight) copy() Highlight {
 	if hl.background != nil {
 		highlight.background = hl.background.copy()
 	}
+	if hl.special != nil {
+		highlight.special = hl.special.copy()
+	}
+	highlight.bold = hl.bold
+	highlight.italic = hl.italic
+	highlight.undercurl = hl.undercurl
+	highlight.underline = hl.underline
+	highlight.underdouble = hl.underdouble
+	highlight.strike = hl.strike
 	return highlight
 }
 
@@ -95,6 +118,10 @@ func InitEditor() {
 		stop:       make(chan struct{}),
 	}
 	e := editor
+	// lay out in logical pixels and draw in device pixels, so text is
+	// sharp on high density screens instead of scaled up
+	core.QCoreApplication_SetAttribute(core.Qt__AA_EnableHighDpiScaling, true)
+	core.QCoreApplication_SetAttribute(core.Qt__AA_UseHighDpiPixmaps, true)
 	e.app = widgets.NewQApplication(0, nil)
 	e.app.ConnectAboutToQuit(func() {
 		editor.cleanup()
@@ -111,8 +138,10 @@ func InitEditor() {
 
 	e.initSpecialKeys()
 	e.window.ConnectKeyPressEvent(e.keyPress)
+	e.window.ConnectKeyReleaseEvent(e.keyRelease)
 
 	e.window.SetAcceptDrops(true)
+	e.initDragDrop()
 
 	layout := widgets.NewQHBoxLayout()
 	widget := widgets.NewQWidget(nil, 0)
@@ -124,6 +153,8 @@ func InitEditor() {
 	layout.AddWidget(e.wsWidget, 1, 0)
 	layout.SetContentsMargins(0, 0, 0, 0)
 	layout.SetSpacing(0)
+	e.switcher = initWorkspaceSwitcher()
+	e.switcher.widget.SetParent(e.wsWidget)
 
 	e.workspaces = []*Workspace{}
 	sessionExists := false
@@ -157,16 +188,31 @@ func InitEditor() {
 		for _, ws := range e.workspaces {
 			ws.updateSize()
 		}
+		e.switcher.resize()
+		e.splash.resize(e.wsWidget.Width(), e.wsWidget.Height())
+		if e.snapSize {
+			e.snapTimer.Start(200)
+		}
 	})
+	e.snapTimer = core.NewQTimer(nil)
+	e.snapTimer.SetSingleShot(true)
+	e.snapTimer.ConnectTimeout(e.snapWindowSize)
 
 	e.window.SetCentralWidget(widget)
 
+	e.splash = initSplash(e.version)
+	if e.splash != nil {
+		e.splash.widget.SetParent(e.wsWidget)
+		e.splash.show()
+	}
+
 	go func() {
 		<-editor.stop
 		e.app.Quit()
 	}()
 
 	e.window.Show()
+	e.watchScreen()
 	// for i := len(e.workspaces) - 1; i >= 0; i-- {
 	// 	e.active = i
 	// }
@@ -174,6 +220,51 @@ func InitEditor() {
 	widgets.QApplication_Exec()
 }
 
+// watchScreen follows the window to other screens, whose device pixel
+// ratio may differ. It is called again whenever the native window is
+// created anew.
+func (e *Editor) watchScreen() {
+	handle := e.window.WindowHandle()
+	if handle == nil {
+		return
+	}
+	screenChanged := func() {
+		for _, ws := range e.workspaces {
+			ws.screenChanged()
+		}
+	}
+	handle.ConnectScreenChanged(func(screen *gui.QScreen) {
+		screenChanged()
+	})
+	screenChanged()
+}
+
+// snapWindowSize shrinks the window to whole cells once a resize is over,
+// so no partial row or column of background is left at the edges. It is
+// turned on with g:gonvim_snap_window_size.
+func (e *Editor) snapWindowSize() {
+	if !e.snapSize || e.window.IsMaximized() || e.window.IsFullScreen() {
+		return
+	}
+	ws := e.workspaces[e.active]
+	// a locked grid keeps its size, the window is left as it is
+	if ws.screen.gridLock[0] > 0 {
+		return
+	}
+	extraWidth := ws.screen.widget.Width() - ws.font.cellX(ws.cols)
+	extraHeight := ws.tabline.marginTop + ws.tabline.marginBottom - ws.tabline.marginDefault*2
+	if extraWidth <= 0 && extraHeight <= 0 {
+		return
+	}
+	if extraWidth < 0 {
+		extraWidth = 0
+	}
+	if extraHeight < 0 {
+		extraHeight = 0
+	}
+	e.window.Resize2(e.window.Width()-extraWidth, e.window.Height()-extraHeight)
+}
+
 func (e *Editor) workspaceNew() {
 	ws, err := newWorkspace("")
 	if err != nil {
@@ -186,6 +277,11 @@ func (e *Editor) workspaceNew() {
 	e.workspaceUpdate()
 }
 
+func (e *Editor) workspaceClose() {
+	ws := e.workspaces[e.active]
+	go ws.nvim.Command("confirm qa")
+}
+
 func (e *Editor) workspaceSwitch(index int) {
 	index--
 	if index < 0 || index >= len(e.workspaces) {
@@ -216,6 +312,7 @@ func (e *Editor) workspaceUpdate() {
 		if i == e.active {
 			ws.hide()
 			ws.show()
+			ws.updateSize()
 		} else {
 			ws.hide()
 		}
@@ -239,12 +336,39 @@ func (e *Editor) workspaceUpdate() {
 }
 
 func (e *Editor) keyPress(event *gui.QKeyEvent) {
+	if e.workspaces[e.active].modal.shown {
+		return
+	}
+	if e.switcher.keyPress(event) {
+		return
+	}
+	if e.workspaces[e.active].overview.keyPress(event) {
+		return
+	}
+	if e.workspaces[e.active].start.keyPress(event) {
+		return
+	}
+	ws := e.workspaces[e.active]
+	if ws.pasteKey(event) {
+		ws.cursor.resetBlink()
+		return
+	}
 	input := e.convertKey(event.Text(), event.Key(), event.Modifiers())
+	if input == "<Right>" && ws.cmdline.ghost != "" {
+		input = strings.Replace(ws.cmdline.ghost, "<", "<lt>", -1)
+	}
 	if input != "" {
-		e.workspaces[e.active].nvim.Input(input)
+		// typing keeps the cursor shown, it blinks again after blinkwait
+		ws.cursor.resetBlink()
+		ws.screen.foldKey(input)
+		ws.input(input)
 	}
 }
 
+func (e *Editor) keyRelease(event *gui.QKeyEvent) {
+	e.switcher.keyRelease(event)
+}
+
 func (e *Editor) convertKey(text string, key int, mod core.Qt__KeyboardModifier) string {
 	if mod&core.Qt__KeypadModifier > 0 {
 		switch core.Qt__Key(key) {
@@ -0,0 +1,83 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// With g:gonvim_margin_fade set the text fades into the background at the
+// top and bottom of every window, a hint that there is more to scroll to. It
+// isn't drawn at the top of a window showing the first line of its buffer or
+// at the bottom of one showing the last. g:gonvim_margin_fade_height is the
+// height in lines, 2 by default, and g:gonvim_margin_fade_strength how much
+// of the background covers the edge, from 0 to 1 and 0.8 by default.
+type marginFade struct {
+	enabled  bool
+	height   float64
+	strength float64
+	state    string
+}
+
+func (s *Screen) drawMarginFade(p *gui.QPainter) {
+	fade := &s.fade
+	if !fade.enabled || fade.height <= 0 {
+		return
+	}
+	font := s.ws.font
+	height := fade.height * float64(font.lineHeight)
+	alpha := int(fade.strength * 255)
+	for _, win := range s.curWins {
+		bg := win.bg
+		if bg == nil {
+			bg = s.ws.background
+		}
+		if bg == nil {
+			continue
+		}
+		left := float64(font.cellX(win.pos[1]))
+		width := float64(font.cellsWidth(win.pos[1], win.pos[1]+win.width))
+		top := float64(win.pos[0] * font.lineHeight)
+		bottom := float64((win.pos[0] + win.height) * font.lineHeight)
+		h := height
+		if h > (bottom-top)/2 {
+			h = (bottom - top) / 2
+		}
+		if !win.atTop {
+			s.fillFade(p, left, top, width, h, top, top+h, bg, alpha)
+		}
+		if !win.atBottom {
+			s.fillFade(p, left, bottom-h, width, h, bottom, bottom-h, bg, alpha)
+		}
+	}
+}
+
+// fillFade fills the rect with the background going from alpha at from to
+// transparent at to
+func (s *Screen) fillFade(p *gui.QPainter, x, y, width, height, from, to float64, bg *RGBA, alpha int) {
+	gradient := gui.NewQLinearGradient3(0, from, 0, to)
+	gradient.SetColorAt(0, gui.NewQColor3(bg.R, bg.G, bg.B, alpha))
+	gradient.SetColorAt(1, gui.NewQColor3(bg.R, bg.G, bg.B, 0))
+	p.FillRect(core.NewQRectF4(x, y, width, height), gui.NewQBrush10(gradient))
+}
+
+// checkMarginFade repaints the screen when a window got to or left the top
+// or bottom of its buffer, as the rows were painted before that was known
+func (s *Screen) checkMarginFade() {
+	if !s.fade.enabled {
+		return
+	}
+	states := []string{}
+	for _, win := range s.curWins {
+		states = append(states, fmt.Sprintf("%d:%t:%t", win.win, win.atTop, win.atBottom))
+	}
+	sort.Strings(states)
+	state := strings.Join(states, ",")
+	if state != s.fade.state {
+		s.fade.state = state
+		s.widget.Update()
+	}
+}
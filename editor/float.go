@@ -0,0 +1,74 @@
+package editor
+
+import (
+	"github.com/therecipe/qt/gui"
+)
+
+const floatShadow = 6
+
+// blendFloat gives a cell of a float with 'winblend' what is under it
+// showing through. Like neovim does it, a blank float cell shows the text
+// under it faded into the float background, and any other cell keeps its
+// text over a background mixed with the one under it.
+func (s *Screen) blendFloat(char, under *Char, blend int, floatBg *RGBA) *Char {
+	if char == nil || under == nil || blend <= 0 {
+		return char
+	}
+	amount := float64(minInt(blend, 100)) / 100
+	bg := char.highlight.background
+	if bg == nil {
+		bg = floatBg
+	}
+	underBg := under.highlight.background
+	if bg == nil || underBg == nil {
+		return char
+	}
+	blended := *char
+	blended.highlight.background = bg.mix(underBg, amount)
+	if (char.char == " " || char.char == "") && under.char != "" && under.highlight.foreground != nil {
+		blended.char = under.char
+		blended.normalWidth = under.normalWidth
+		blended.highlight.foreground = bg.mix(under.highlight.foreground, amount)
+	}
+	return &blended
+}
+
+// floatRects are where the floats are in cells, as row, col, width and
+// height, in the order they are drawn
+func (s *Screen) floatRects() [][4]int {
+	rects := [][4]int{}
+	for _, g := range s.grids.sorted() {
+		if !g.float {
+			continue
+		}
+		row, col := s.grids.origin(g, 0)
+		rects = append(rects, [4]int{row, col, g.width, g.height})
+	}
+	return rects
+}
+
+// drawFloatShadows draws a shadow along the right and bottom of every float
+// with g:gonvim_multigrid set, faded out like the ones along the window
+// borders
+func (s *Screen) drawFloatShadows(p *gui.QPainter) {
+	if !s.grids.enabled {
+		return
+	}
+	font := s.ws.font
+	for _, rect := range s.grids.floats {
+		x := font.cellX(rect[1])
+		y := rect[0] * font.lineHeight
+		right := font.cellX(rect[1] + rect[2])
+		bottom := (rect[0] + rect[3]) * font.lineHeight
+
+		gradient := gui.NewQLinearGradient3(float64(right), 0, float64(right+floatShadow), 0)
+		gradient.SetColorAt(0, gui.NewQColor3(10, 10, 10, 125))
+		gradient.SetColorAt(1, gui.NewQColor3(10, 10, 10, 0))
+		p.FillRect2(right, y+floatShadow, floatShadow, bottom-y, gui.NewQBrush10(gradient))
+
+		gradient = gui.NewQLinearGradient3(0, float64(bottom), 0, float64(bottom+floatShadow))
+		gradient.SetColorAt(0, gui.NewQColor3(10, 10, 10, 125))
+		gradient.SetColorAt(1, gui.NewQColor3(10, 10, 10, 0))
+		p.FillRect2(x+floatShadow, bottom, right-x-floatShadow, floatShadow, gui.NewQBrush10(gradient))
+	}
+}
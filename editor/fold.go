@@ -0,0 +1,305 @@
+package editor
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// With g:gonvim_fold_column set the "+" and "-" neovim puts in the fold
+// column at the start of closed and open folds are drawn as triangles, and
+// clicking one opens or closes the fold. g:gonvim_fold_column_width sets
+// 'foldcolumn'. The markers are read from the cells, so they follow the
+// folds as neovim redraws them. A window with 'statuscolumn' set lays out
+// its gutter as it likes, so the markers can't be told from signs there and
+// its cells are drawn as they are. This works from neovim 0.4, where
+// 'foldcolumn' is a number, on. 'statuscolumn' is only read on 0.9 and
+// later, the versions that have it.
+
+// parseFoldColumn returns the width of the fold column from 'foldcolumn',
+// a number before neovim 0.5 and a string, "N" or "auto:N", since. For
+// "auto:N" it is the widest the column gets.
+func parseFoldColumn(option interface{}) int {
+	value, ok := option.(string)
+	if !ok {
+		return reflectToInt(option)
+	}
+	value = strings.TrimPrefix(value, "auto:")
+	if value == "auto" {
+		return 1
+	}
+	width, _ := strconv.Atoi(value)
+	return width
+}
+
+// foldMarker returns the column of the fold marker on the row of the window
+// and whether the fold is closed
+func (s *Screen) foldMarker(win *Window, y int) (int, bool, bool) {
+	if win.foldcolumn <= 0 || win.statuscol != "" || y < win.pos[0] || y >= win.pos[0]+win.height || y >= len(s.content) {
+		return 0, false, false
+	}
+	line := s.content[y]
+	for x := win.pos[1]; x < win.pos[1]+win.foldcolumn && x < len(line); x++ {
+		char := line[x]
+		if char == nil {
+			continue
+		}
+		switch char.char {
+		case "+":
+			return x, true, true
+		case "-":
+			return x, false, true
+		}
+	}
+	return 0, false, false
+}
+
+func (s *Screen) drawFoldColumn(p *gui.QPainter, row, rows int) {
+	if !s.foldColumn {
+		return
+	}
+	for _, win := range s.curWins {
+		for y := row; y < row+rows; y++ {
+			x, closed, ok := s.foldMarker(win, y)
+			if ok {
+				s.drawFoldMarker(p, x, y, closed)
+			}
+		}
+	}
+}
+
+// drawFoldMarker draws a triangle over the marker cell, pointing right for a
+// closed fold and down for an open one
+func (s *Screen) drawFoldMarker(p *gui.QPainter, x, y int, closed bool) {
+	font := s.ws.font
+	char := s.content[y][x]
+	bg := char.highlight.background
+	if bg == nil {
+		bg = s.ws.background
+	}
+	fg := char.highlight.foreground
+	if fg == nil {
+		fg = s.ws.foreground
+	}
+	if bg == nil || fg == nil {
+		return
+	}
+	left := float64(font.cellX(x))
+	top := float64(y * font.lineHeight)
+	p.FillRect5(font.cellX(x), y*font.lineHeight, font.cellsWidth(x, x+1), font.lineHeight, bg.QColor())
+
+	size := math.Min(font.truewidth, float64(font.lineHeight)) * 0.6
+	cx := left + font.truewidth/2
+	cy := top + float64(font.lineHeight)/2
+	path := gui.NewQPainterPath()
+	if closed {
+		path.MoveTo2(cx-size/3, cy-size/2)
+		path.LineTo2(cx+size/2, cy)
+		path.LineTo2(cx-size/3, cy+size/2)
+	} else {
+		path.MoveTo2(cx-size/2, cy-size/3)
+		path.LineTo2(cx+size/2, cy-size/3)
+		path.LineTo2(cx, cy+size/2)
+	}
+	path.CloseSubpath()
+	p.SetRenderHint(gui.QPainter__Antialiasing, true)
+	p.FillPath(path, gui.NewQBrush3(fg.QColor(), core.Qt__SolidPattern))
+	p.SetRenderHint(gui.QPainter__Antialiasing, false)
+}
+
+// isFoldedRow is true for a row of the window showing a closed fold, as
+// foldclosed() found them on the visible lines
+func (s *Screen) isFoldedRow(win *Window, y int) bool {
+	for _, row := range win.foldRows {
+		if win.pos[0]+row == y {
+			return true
+		}
+	}
+	return false
+}
+
+// drawFoldSummary sets the closed fold lines apart with g:gonvim_fold_summary
+// on, with a shade and a line above and below in the Folded foreground and
+// the fold column marker drawn as a triangle. A click on the line opens it.
+func (s *Screen) drawFoldSummary(p *gui.QPainter, row, rows int) {
+	if !s.foldSummary {
+		return
+	}
+	fg := s.foldedFg
+	if fg == nil {
+		fg = s.ws.foreground
+	}
+	if fg == nil {
+		return
+	}
+	font := s.ws.font
+	shade := gui.NewQColor3(fg.R, fg.G, fg.B, 15)
+	line := gui.NewQColor3(fg.R, fg.G, fg.B, 60)
+	for _, win := range s.curWins {
+		for y := row; y < row+rows; y++ {
+			if !s.isFoldedRow(win, y) {
+				continue
+			}
+			left := font.cellX(win.pos[1])
+			width := font.cellsWidth(win.pos[1], win.pos[1]+win.width)
+			top := y * font.lineHeight
+			p.FillRect5(left, top, width, font.lineHeight, shade)
+			p.FillRect5(left, top, width, 1, line)
+			p.FillRect5(left, top+font.lineHeight-1, width, 1, line)
+			if !s.foldColumn {
+				x, closed, ok := s.foldMarker(win, y)
+				if ok {
+					s.drawFoldMarker(p, x, y, closed)
+				}
+			}
+		}
+	}
+}
+
+// foldClick opens or closes the fold when the click is on a fold marker, or
+// on a closed fold line with g:gonvim_fold_summary
+func (s *Screen) foldClick(event *gui.QMouseEvent) bool {
+	if event.Button() != core.Qt__LeftButton {
+		return false
+	}
+	col, row := s.pointCell(event.X(), event.Y())
+	win, command := s.foldAction(row, col)
+	if win == nil {
+		return false
+	}
+	go s.toggleFold(win, row-win.pos[0], command)
+	return true
+}
+
+// foldHover is the hover target of the fold markers and closed fold lines
+func (s *Screen) foldHover(row, col int) bool {
+	win, _ := s.foldAction(row, col)
+	return win != nil
+}
+
+// foldAction returns the window and the fold command a click on the cell
+// runs, or nil when a click there does nothing to folds
+func (s *Screen) foldAction(row, col int) (*Window, string) {
+	if !s.foldColumn && !s.foldSummary {
+		return nil, ""
+	}
+	win := s.windowAt(row, col)
+	if win == nil {
+		return nil, ""
+	}
+	if s.foldSummary && s.isFoldedRow(win, row) {
+		return win, "zo"
+	}
+	x, closed, ok := s.foldMarker(win, row)
+	if !ok || x != col {
+		return nil, ""
+	}
+	if closed {
+		return win, "zo"
+	}
+	return win, "zc"
+}
+
+// foldFunc runs a fold command on the line shown on a screen row of a
+// window, counted with gj from the top so closed folds and wrapped lines
+// are taken into account, and leaves the cursor and view as they were. It
+// is all done in one call, so no input or redraw gets in between the steps.
+// win_execute() doesn't enter the window, and before neovim 0.5 the
+// function goes to the window and back.
+const foldFunc = `function! GonvimFoldCommand(row, command) abort
+  let view = winsaveview()
+  call cursor(line('w0'), 1)
+  if a:row > 0
+    execute 'normal! ' . a:row . 'gj'
+  endif
+  execute 'normal! ' . a:command
+  call winrestview(view)
+endfunction
+function! GonvimToggleFold(winid, row, command) abort
+  if exists('*win_execute')
+    call win_execute(a:winid, 'call GonvimFoldCommand(' . a:row . ', ' . string(a:command) . ')')
+    return
+  endif
+  let current = win_getid()
+  if win_gotoid(a:winid)
+    call GonvimFoldCommand(a:row, a:command)
+    call win_gotoid(current)
+  endif
+endfunction`
+
+// toggleFold runs the fold command on the line shown on the screen row of
+// the window with GonvimToggleFold
+func (s *Screen) toggleFold(win *Window, screenRow int, command string) {
+	s.foldToggled()
+	s.ws.nvim.Call("GonvimToggleFold", nil, win.win, screenRow, command)
+}
+
+const (
+	foldAnimationDuration = 80 * time.Millisecond
+	// foldAnimationWait is how long after a fold command its redraw is
+	// looked for
+	foldAnimationWait = 300 * time.Millisecond
+	// foldKeys are the normal mode commands after "z" that open, close or
+	// remove folds
+	foldKeys = "aAcCoOvxXmMrRnNiEdD"
+)
+
+// With g:gonvim_fold_animation set the lines below a fold glide up or down
+// when it closes or opens, instead of jumping. Neovim moves them with a
+// scroll of the rows under the fold in the redraw that follows the fold
+// command, so that scroll is animated with the smooth scroll pixmap for
+// foldAnimationDuration, whether g:gonvim_smooth_scroll is set or not. The
+// content is moved at once as neovim sent it and only the painting is
+// offset, so the rows never get out of step with neovim's. Fold commands
+// are told by the keys typed in normal mode and the clicks on the fold
+// markers and summaries; the redraw batch they are looked for in ends with
+// the flush after the first animated scroll.
+type foldAnimation struct {
+	enabled bool
+	zKey    bool
+	toggled time.Time
+	shifted bool
+}
+
+// foldKey is called with the keys typed, to find the fold commands
+func (s *Screen) foldKey(input string) {
+	fa := &s.foldAnim
+	if !fa.enabled {
+		return
+	}
+	if s.ws.mode != "normal" {
+		fa.zKey = false
+		return
+	}
+	if fa.zKey && len(input) == 1 && strings.Contains(foldKeys, input) {
+		s.foldToggled()
+	}
+	fa.zKey = input == "z"
+}
+
+func (s *Screen) foldToggled() {
+	if s.foldAnim.enabled {
+		s.foldAnim.toggled = time.Now()
+		s.foldAnim.shifted = false
+	}
+}
+
+// foldShift is true for a scroll in the redraw of a fold command
+func (s *Screen) foldShift() bool {
+	fa := &s.foldAnim
+	return fa.enabled && !fa.toggled.IsZero() && time.Since(fa.toggled) < foldAnimationWait
+}
+
+// foldFlush ends the redraw batch of a fold command once its scroll is
+// animated, so the scrolls after it aren't
+func (s *Screen) foldFlush() {
+	fa := &s.foldAnim
+	if fa.shifted {
+		fa.toggled = time.Time{}
+		fa.shifted = false
+	}
+}
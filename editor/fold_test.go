@@ -0,0 +1,104 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+func TestParseFoldColumn(t *testing.T) {
+	cases := []struct {
+		option interface{}
+		width  int
+	}{
+		{int64(0), 0},
+		{int64(3), 3},
+		{uint64(2), 2},
+		{"0", 0},
+		{"4", 4},
+		{"auto", 1},
+		{"auto:3", 3},
+		{"auto:x", 0},
+		{nil, 0},
+	}
+	for _, c := range cases {
+		width := parseFoldColumn(c.option)
+		if width != c.width {
+			t.Errorf("parseFoldColumn(%#v) = %d, want %d", c.option, width, c.width)
+		}
+	}
+}
+
+func TestIsFoldedRow(t *testing.T) {
+	s := &Screen{}
+	win := &Window{pos: [2]int{3, 0}, height: 10, foldRows: []int{0, 4}}
+	for y := 0; y < 15; y++ {
+		folded := s.isFoldedRow(win, y)
+		if folded != (y == 3 || y == 7) {
+			t.Errorf("isFoldedRow(%d) = %v", y, folded)
+		}
+	}
+}
+
+// a click anywhere on a closed fold line opens it with g:gonvim_fold_summary,
+// through the same GonvimToggleFold call as a click on the fold column
+func TestFoldSummaryClick(t *testing.T) {
+	win := &Window{win: 1000, pos: [2]int{3, 0}, width: 40, height: 10, foldRows: []int{0, 4}}
+	s := &Screen{foldSummary: true, curWins: map[nvim.Window]*Window{1000: win}}
+	for y := 0; y < 15; y++ {
+		clicked, command := s.foldAction(y, 20)
+		if y == 3 || y == 7 {
+			if clicked != win || command != "zo" {
+				t.Errorf("foldAction(%d, 20) = %v, %q, want the window and zo", y, clicked, command)
+			}
+			continue
+		}
+		if clicked != nil {
+			t.Errorf("foldAction(%d, 20) = %q on a line that isn't folded", y, command)
+		}
+	}
+}
+
+func TestFoldKeys(t *testing.T) {
+	s := newGridScreen(10, 20)
+	s.ws.mode = "normal"
+	tests := []struct {
+		keys []string
+		fold bool
+	}{
+		{[]string{"z", "c"}, true},
+		{[]string{"z", "R"}, true},
+		{[]string{"z", "z"}, false},
+		{[]string{"z", "t"}, false},
+		{[]string{"c"}, false},
+		{[]string{"z", "<Esc>", "a"}, false},
+	}
+	for _, test := range tests {
+		s.foldAnim = foldAnimation{enabled: true}
+		for _, key := range test.keys {
+			s.foldKey(key)
+		}
+		if s.foldShift() != test.fold {
+			t.Errorf("keys %v: fold shift %v, want %v", test.keys, s.foldShift(), test.fold)
+		}
+	}
+
+	// the flush after the animated scroll ends it
+	s.foldToggled()
+	s.foldFlush()
+	if !s.foldShift() {
+		t.Errorf("flush before the scroll ended the fold shift")
+	}
+	s.foldAnim.shifted = true
+	s.foldFlush()
+	if s.foldShift() {
+		t.Errorf("fold shift kept after its scroll was animated")
+	}
+
+	s.ws.mode = "insert"
+	s.foldKey("z")
+	s.foldKey("c")
+	if s.foldShift() {
+		t.Errorf("fold keys taken in insert mode")
+	}
+}
@@ -18,7 +18,13 @@ type Font struct {
 	height             int
 	lineHeight         int
 	lineSpace          int
+	letterSpace        int
+	glyphWidth         float64
 	shift              int
+	boldWidth          float64
+	dpr                float64
+	colX               []int
+	styles             [4]*gui.QFont
 }
 
 func fontSizeNew(font *gui.QFont) (int, int, float64, float64) {
@@ -26,8 +32,10 @@ func fontSizeNew(font *gui.QFont) (int, int, float64, float64) {
 	h := fontMetrics.Height()
 	w := fontMetrics.Width("W")
 	ascent := fontMetrics.Ascent()
+	// the grid divides by both, a tiny font still gets a pixel
+	w = math.Max(w, 1)
 	width := int(math.Ceil(w))
-	height := int(math.Ceil(h))
+	height := int(math.Max(math.Ceil(h), 1))
 	return width, height, w, ascent
 }
 
@@ -35,36 +43,155 @@ func initFontNew(family string, size int, lineSpace int) *Font {
 	font := gui.NewQFont2(family, size, int(gui.QFont__Normal), false)
 	width, height, truewidth, ascent := fontSizeNew(font)
 	defaultFont := gui.NewQFont()
-	return &Font{
+	f := &Font{
 		fontNew:            font,
 		fontMetrics:        gui.NewQFontMetricsF(font),
 		defaultFont:        defaultFont,
 		defaultFontMetrics: gui.NewQFontMetricsF(defaultFont),
 		width:              width,
 		truewidth:          truewidth,
+		glyphWidth:         truewidth,
 		height:             height,
 		lineHeight:         height + lineSpace,
 		lineSpace:          lineSpace,
 		shift:              int(float64(lineSpace)/2 + ascent),
 		ascent:             ascent,
+		dpr:                1,
 	}
+	f.updateStyles()
+	return f
 }
 
 func (f *Font) change(family string, size int) {
 	f.fontNew.SetFamily(family)
 	f.fontNew.SetPointSize(size)
+	// the metrics are of the glyphs themselves, without the letter space
+	f.fontNew.SetLetterSpacing(gui.QFont__AbsoluteSpacing, 0)
 	f.fontMetrics = gui.NewQFontMetricsF(f.fontNew)
-	width, height, truewidth, ascent := fontSizeNew(f.fontNew)
-	f.width = width
+	_, height, truewidth, ascent := fontSizeNew(f.fontNew)
+	f.fontNew.SetLetterSpacing(gui.QFont__AbsoluteSpacing, float64(f.letterSpace))
+	f.glyphWidth = truewidth
+	f.truewidth = truewidth + float64(f.letterSpace)
+	f.width = int(math.Ceil(f.truewidth))
 	f.height = height
-	f.truewidth = truewidth
-	f.lineHeight = height + f.lineSpace
+	f.updateLineHeight()
 	f.ascent = ascent
 	f.shift = int(float64(f.lineSpace)/2 + ascent)
+	f.boldWidth = 0
+	f.colX = nil
+	f.updateStyles()
+}
+
+// updateStyles builds a font for each of bold, italic and bold italic from
+// the regular one. Painting switches between them and never changes the
+// regular font, which the metrics, the line cache, the tooltip and the
+// popups share.
+func (f *Font) updateStyles() {
+	f.styles[0] = f.fontNew
+	for i := 1; i < len(f.styles); i++ {
+		style := copyFont(f.fontNew)
+		style.SetBold(i&1 != 0)
+		style.SetItalic(i&2 != 0)
+		style.SetLetterSpacing(gui.QFont__AbsoluteSpacing, float64(f.letterSpace))
+		style.SetStyleStrategy(f.fontNew.StyleStrategy())
+		f.styles[i] = style
+	}
+}
+
+// copyFont returns a copy of the font. PointSize is -1 for a font sized in
+// pixels, so a font built from the family and point size would lose the size.
+func copyFont(font *gui.QFont) *gui.QFont {
+	c := gui.NewQFont()
+	c.FromString(font.ToString())
+	return c
+}
+
+// styleFont returns the font for the style
+func (f *Font) styleFont(bold, italic bool) *gui.QFont {
+	i := 0
+	if bold {
+		i |= 1
+	}
+	if italic {
+		i |= 2
+	}
+	return f.styles[i]
+}
+
+// setDevicePixelRatio sets the ratio of the screen the font is drawn on
+func (f *Font) setDevicePixelRatio(dpr float64) {
+	f.dpr = dpr
+	f.updateLineHeight()
+}
+
+// updateLineHeight sets the line height from the font height and line space.
+// On a screen with a fractional device pixel ratio it is rounded up to a
+// whole number of device pixels, or the rows would start between pixels and
+// their edges would blur.
+func (f *Font) updateLineHeight() {
+	lineHeight := maxInt(f.height+f.lineSpace, 1)
+	for extra := 0; extra < 8; extra++ {
+		device := float64(lineHeight+extra) * f.dpr
+		if math.Abs(device-math.Round(device)) < 0.01 {
+			lineHeight += extra
+			break
+		}
+	}
+	f.lineHeight = lineHeight
+}
+
+// boldScale is how much bold text is squeezed to keep to its cells, for
+// fonts whose bold glyphs advance further than the regular ones. The letter
+// space is added after bold glyphs too, so it is part of both advances.
+func (f *Font) boldScale() float64 {
+	if f.boldWidth == 0 {
+		bold := copyFont(f.fontNew)
+		bold.SetLetterSpacing(gui.QFont__AbsoluteSpacing, 0)
+		bold.SetBold(true)
+		f.boldWidth = gui.NewQFontMetricsF(bold).Width("W")
+	}
+	if f.boldWidth <= f.glyphWidth {
+		return 1
+	}
+	letterSpace := float64(f.letterSpace)
+	return (f.glyphWidth + letterSpace) / (f.boldWidth + letterSpace)
+}
+
+// cellX is the x of the left edge of the column. Every edge is rounded from
+// truewidth the same way and kept per column until the font changes, so the
+// right edge of a column is always the left edge of the next one, where
+// adding up fractional widths would leave a pixel gap or overlap between
+// neighbouring fills.
+func (f *Font) cellX(col int) int {
+	if col < 0 {
+		return int(math.Round(float64(col) * f.truewidth))
+	}
+	for len(f.colX) <= col {
+		f.colX = append(f.colX, int(math.Round(float64(len(f.colX))*f.truewidth)))
+	}
+	return f.colX[col]
+}
+
+// textX is the x a glyph in the column is drawn at, centered in the cell
+// when the letter space widens it
+func (f *Font) textX(col int) float64 {
+	return float64(f.cellX(col)) + float64(f.letterSpace)/2
+}
+
+// cellsWidth is the width of the columns from col up to but not including end
+func (f *Font) cellsWidth(col, end int) int {
+	return f.cellX(end) - f.cellX(col)
+}
+
+// changeLetterSpace sets the pixels added between glyphs, which widens every
+// cell by as much
+func (f *Font) changeLetterSpace(letterSpace int) {
+	f.letterSpace = letterSpace
+	f.change(f.fontNew.Family(), f.fontNew.PointSize())
 }
 
 func (f *Font) changeLineSpace(lineSpace int) {
 	f.lineSpace = lineSpace
-	f.lineHeight = f.height + lineSpace
+	f.updateLineHeight()
 	f.shift = int(float64(lineSpace)/2 + f.ascent)
 }
@@ -0,0 +1,186 @@
+package editor
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+func TestMain(m *testing.M) {
+	// the tests that draw glyphs or make widgets need an application,
+	// which needs no display on the offscreen platform
+	os.Setenv("QT_QPA_PLATFORM", "offscreen")
+	widgets.NewQApplication(len(os.Args), os.Args)
+	os.Exit(m.Run())
+}
+
+func TestBoldScale(t *testing.T) {
+	cases := []struct {
+		glyphWidth, boldWidth float64
+		letterSpace           int
+		scale                 float64
+	}{
+		{8, 8, 0, 1},
+		{8, 7.5, 0, 1},
+		{8, 9, 0, 8.0 / 9},
+		{8, 9, 2, 10.0 / 11},
+		{8, 10, 4, 12.0 / 14},
+	}
+	for _, c := range cases {
+		f := &Font{glyphWidth: c.glyphWidth, boldWidth: c.boldWidth, letterSpace: c.letterSpace}
+		scale := f.boldScale()
+		if scale != c.scale {
+			t.Errorf("boldScale() with widths %v, %v and letter space %d = %v, want %v", c.glyphWidth, c.boldWidth, c.letterSpace, scale, c.scale)
+		}
+		// the squeezed bold cell must not be wider than a regular one
+		truewidth := c.glyphWidth + float64(c.letterSpace)
+		if bold := (c.boldWidth + float64(c.letterSpace)) * scale; bold > truewidth+1e-9 {
+			t.Errorf("bold cell %v is wider than the cell %v", bold, truewidth)
+		}
+	}
+}
+
+func TestUpdateLineHeight(t *testing.T) {
+	cases := []struct {
+		height, lineSpace int
+		dpr               float64
+		lineHeight        int
+	}{
+		{17, 0, 1, 17},
+		{17, 0, 2, 17},
+		{17, 0, 1.5, 18},
+		{17, 2, 1.5, 20},
+		{17, 0, 1.25, 20},
+		{17, 0, 1.75, 20},
+		{17, -40, 1, 1},
+		// no height up to 7 pixels more is whole on the device
+		{17, 0, 1.37, 17},
+	}
+	for _, c := range cases {
+		f := &Font{height: c.height, lineSpace: c.lineSpace, dpr: c.dpr}
+		f.updateLineHeight()
+		if f.lineHeight != c.lineHeight {
+			t.Errorf("updateLineHeight() with height %d, line space %d and ratio %v = %d, want %d", c.height, c.lineSpace, c.dpr, f.lineHeight, c.lineHeight)
+		}
+	}
+}
+
+// every cell must end where the next one starts, whatever the fraction of
+// the cell width
+func TestCellEdges(t *testing.T) {
+	for _, truewidth := range []float64{7, 7.2, 7.5, 8.33, 9.6} {
+		f := &Font{truewidth: truewidth, letterSpace: 2}
+		for col := -3; col < 300; col++ {
+			if x, want := f.cellX(col), int(math.Round(float64(col)*truewidth)); x != want {
+				t.Fatalf("truewidth %v: column %d starts at %d, want %d", truewidth, col, x, want)
+			}
+			if width, want := f.cellsWidth(col, col+1), int(math.Round(float64(col+1)*truewidth)-math.Round(float64(col)*truewidth)); width != want {
+				t.Fatalf("truewidth %v: column %d is %d wide, want %d", truewidth, col, width, want)
+			}
+		}
+		if x := f.textX(4); x != math.Round(4*truewidth)+1 {
+			t.Errorf("truewidth %v: textX(4) = %v, want the cell's x plus half the letter space", truewidth, x)
+		}
+	}
+}
+
+// the backgrounds of neighbouring cells meet, with no pixel column left
+// unpainted or painted twice between them
+func TestCellBackgroundsMeet(t *testing.T) {
+	colors := []int64{0xff0000, 0x0000ff}
+	for _, truewidth := range []float64{7, 7.2, 7.5, 8.33, 9.6} {
+		cols := 40
+		s := newGridScreen(1, cols)
+		s.ws.screen = s
+		s.ws.font = &Font{truewidth: truewidth, lineHeight: 4}
+		for col := 0; col < cols; col++ {
+			gridSetHighlight(s, map[string]interface{}{"background": colors[col%2]})
+			gridPut(s, 0, col, "a")
+		}
+		width := int(math.Ceil(float64(cols)*truewidth)) + 2
+		image := gui.NewQImage3(width, 4, gui.QImage__Format_ARGB32)
+		image.Fill2(gui.NewQColor3(0, 0, 0, 0))
+		p := gui.NewQPainter2(image)
+		s.fillHightlight(p, 0, 0, cols, [2]int{0, 0})
+		p.End()
+
+		for x := 0; x < width; x++ {
+			// the column the pixel is in, worked out from the pixel
+			col := int(math.Floor((float64(x) + 0.5) / truewidth))
+			if float64(x) < math.Round(float64(col)*truewidth) {
+				col--
+			} else if float64(x) >= math.Round(float64(col+1)*truewidth) {
+				col++
+			}
+			color := image.PixelColor2(x, 1)
+			got := int64(color.Red())<<16 | int64(color.Green())<<8 | int64(color.Blue())
+			if col >= cols {
+				if color.Alpha() != 0 {
+					t.Errorf("truewidth %v: x %d past the last column is painted", truewidth, x)
+				}
+				continue
+			}
+			if color.Alpha() != 255 || got != colors[col%2] {
+				t.Errorf("truewidth %v: x %d in column %d is %06x, want %06x", truewidth, x, col, got, colors[col%2])
+			}
+		}
+	}
+}
+
+func TestBoldRunKeepsToCells(t *testing.T) {
+	f := initFontNew("Monospace", 20, 0)
+	// cells narrower than the regular glyphs stand in for a font whose
+	// bold glyphs are wider than its regular ones
+	f.glyphWidth *= 0.8
+	f.truewidth = f.glyphWidth
+	s := &Screen{ws: &Workspace{font: f}, textContrast: 1}
+	cols := 10
+	width := f.cellX(cols)
+	image := gui.NewQImage3(width*2, f.lineHeight, gui.QImage__Format_ARGB32)
+	image.Fill2(gui.NewQColor3(0, 0, 0, 0))
+	p := gui.NewQPainter2(image)
+	p.SetFont(f.styleFont(true, false))
+	point := core.NewQPointF3(0, float64(f.shift))
+	s.drawRun(p, point, f.textX(0), strings.Repeat("W", cols), true, newRGBA(255, 255, 255, 1))
+	p.End()
+
+	right := -1
+	for x := 0; x < image.Width(); x++ {
+		for y := 0; y < image.Height(); y++ {
+			if image.PixelColor2(x, y).Alpha() != 0 {
+				right = x
+			}
+		}
+	}
+	if right < 0 {
+		t.Fatal("the bold run drew nothing")
+	}
+	// a glyph may ink a pixel past its advance
+	if right > width+1 {
+		t.Errorf("the bold run of %d cells inks up to x %d, past the cells' edge %d", cols, right, width)
+	}
+}
+
+func TestStylesKeepPixelSize(t *testing.T) {
+	f := initFontNew("Monospace", 14, 0)
+	f.fontNew.SetPixelSize(60)
+	f.boldWidth = 0
+	f.updateStyles()
+	for _, bold := range []bool{false, true} {
+		for _, italic := range []bool{false, true} {
+			if size := f.styleFont(bold, italic).PixelSize(); size != 60 {
+				t.Errorf("styleFont(%v, %v) has pixel size %d, want 60", bold, italic, size)
+			}
+		}
+	}
+	regular := gui.NewQFontMetricsF(f.fontNew).Width("W")
+	// a bold font falling back to the default size would be far narrower
+	if f.boldScale(); f.boldWidth < regular*0.9 {
+		t.Errorf("bold width %v of a pixel sized font, want at least about %v", f.boldWidth, regular)
+	}
+}
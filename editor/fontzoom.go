@@ -0,0 +1,33 @@
+package editor
+
+import (
+	"github.com/therecipe/qt/core"
+)
+
+const fontZoomDelay = 150
+
+// fontZoom changes the font size with Ctrl and the mouse wheel, a point per
+// notch. The notches are added up while the wheel turns and the font is
+// changed once it stops, so a fast turn resizes the grid, and neovim with
+// it, a single time.
+type fontZoom struct {
+	timer *core.QTimer
+	size  int
+}
+
+// zoomFont adds steps points to the size the font is going to
+func (s *Screen) zoomFont(steps int) {
+	z := &s.fontZoom
+	if z.timer == nil {
+		z.timer = core.NewQTimer(nil)
+		z.timer.SetSingleShot(true)
+		z.timer.ConnectTimeout(func() {
+			s.ws.changeFontSize(z.size)
+		})
+	}
+	if !z.timer.IsActive() {
+		z.size = s.ws.font.fontNew.PointSize()
+	}
+	z.size = clampInt(z.size+steps, minFontSize, maxFontSize)
+	z.timer.Start(fontZoomDelay)
+}
@@ -0,0 +1,73 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// maxWidgetSize is QWIDGETSIZE_MAX, the maximum size of an unlocked screen
+const maxWidgetSize = 16777215
+
+// lockGrid takes GonvimResize's argument. A size like 100x30 locks the grid
+// to 100 columns and 30 rows: the screen keeps that size when the window is
+// resized, centered in the space the window has for it with the pixels
+// around it in the background color, and neovim is no longer resized with
+// the window. Without an argument the grid follows the window again.
+func (w *Workspace) lockGrid(args []interface{}) {
+	arg := ""
+	if len(args) > 0 {
+		arg, _ = args[0].(string)
+	}
+	arg = strings.ToLower(strings.TrimSpace(arg))
+	s := w.screen
+	if arg == "" {
+		if s.gridLock[0] == 0 {
+			return
+		}
+		s.gridLock = [2]int{}
+		s.widget.SetMinimumSize2(0, 0)
+		s.widget.SetMaximumSize2(maxWidgetSize, maxWidgetSize)
+		w.screenLayout.SetAlignment(s.widget, 0)
+		w.widget.SetAutoFillBackground(false)
+		w.updateSize()
+		return
+	}
+	cols, rows := 0, 0
+	_, err := fmt.Sscanf(arg, "%dx%d", &cols, &rows)
+	if err != nil || cols <= 0 || rows <= 0 {
+		go w.nvim.Command(`echomsg "GonvimResize takes a size like 80x24, or nothing to unlock"`)
+		return
+	}
+	s.gridLock = [2]int{cols, rows}
+	w.screenLayout.SetAlignment(s.widget, core.Qt__AlignCenter)
+	w.updateSize()
+}
+
+// lockSize fixes the screen to the locked grid, it is called by updateSize
+// with the grid locked
+func (s *Screen) lockSize() (int, int) {
+	cols, rows := s.gridLock[0], s.gridLock[1]
+	font := s.ws.font
+	s.height = rows * font.lineHeight
+	s.widget.SetFixedSize2(font.cellX(cols), s.height)
+	s.ws.letterbox()
+	return cols, rows
+}
+
+// letterbox fills the space around the locked screen in the background color
+func (w *Workspace) letterbox() {
+	bg := w.background
+	if w.screen.editorBg != nil {
+		bg = w.screen.editorBg
+	}
+	if bg == nil {
+		return
+	}
+	palette := w.widget.Palette()
+	palette.SetColor2(gui.QPalette__Window, bg.QColor())
+	w.widget.SetPalette(palette)
+	w.widget.SetAutoFillBackground(true)
+}
@@ -0,0 +1,99 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// g:gonvim_highlight_style makes the cells of highlight groups bold or
+// italic whatever the colorscheme says, e.g.
+//
+//	let g:gonvim_highlight_style = {'Comment': 'italic', 'Keyword': 'bold', 'Todo': 'nobold'}
+//
+// Cells come without their group, so they are matched by the group's
+// colors, bold, italic, underline and undercurl. Another group with all of
+// those the same, which is rare, gets the style too.
+type hlStyle struct {
+	group  string
+	fg     *RGBA
+	bg     *RGBA
+	attrs  [4]bool
+	bold   int
+	italic int
+}
+
+// hlStyleAttrs are the attrs a cell must share with the group, in the order
+// of hlStyle.attrs
+var hlStyleAttrs = []string{"bold", "italic", "underline", "undercurl"}
+
+func (s *Screen) setHighlightStyles(config interface{}) {
+	groups, _ := config.(map[string]interface{})
+	names := []string{}
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	exists := []int{}
+	if len(names) > 0 {
+		s.ws.nvim.Call("map", &exists, names, "hlexists(v:val)")
+	}
+	missing := []string{}
+	styles := []*hlStyle{}
+	for i, name := range names {
+		value, _ := groups[name].(string)
+		if i >= len(exists) || exists[i] == 0 {
+			missing = append(missing, name)
+			continue
+		}
+		style := &hlStyle{
+			group: name,
+		}
+		for _, part := range strings.Split(value, ",") {
+			switch strings.TrimSpace(part) {
+			case "bold":
+				style.bold = 1
+			case "nobold":
+				style.bold = -1
+			case "italic":
+				style.italic = 1
+			case "noitalic":
+				style.italic = -1
+			}
+		}
+		if style.bold == 0 && style.italic == 0 {
+			continue
+		}
+		styles = append(styles, style)
+	}
+	if len(missing) > 0 {
+		msg := "gonvim_highlight_style: no highlight group " + strings.Join(missing, ", ")
+		s.ws.nvim.Command(fmt.Sprintf("echomsg '%s'", strings.Replace(msg, "'", "''", -1)))
+	}
+	s.hlStyles = styles
+}
+
+// fontStyle returns whether the highlight is drawn bold and italic
+func (s *Screen) fontStyle(hl *Highlight) (bool, bool) {
+	bold := hl.bold
+	italic := hl.italic
+	for _, style := range s.hlStyles {
+		if style.fg == nil || !sameColor(hl.foreground, style.fg) {
+			continue
+		}
+		if style.bg != nil && !sameColor(hl.background, style.bg) {
+			continue
+		}
+		if style.attrs != [4]bool{hl.bold, hl.italic, hl.underline, hl.undercurl} {
+			continue
+		}
+		if style.bold != 0 {
+			bold = style.bold > 0
+		}
+		if style.italic != 0 {
+			italic = style.italic > 0
+		}
+		break
+	}
+	return bold, italic
+}
@@ -0,0 +1,28 @@
+package editor
+
+import "testing"
+
+func TestFontStyleMatchesGroupAttrs(t *testing.T) {
+	fg := newRGBA(128, 128, 128, 1)
+	s := &Screen{
+		hlStyles: []*hlStyle{
+			{group: "Comment", fg: fg, attrs: [4]bool{false, true, false, false}, bold: 1},
+		},
+	}
+	cases := []struct {
+		name       string
+		hl         Highlight
+		bold, ital bool
+	}{
+		{"same colors and attrs", Highlight{foreground: fg, italic: true}, true, true},
+		{"other color", Highlight{foreground: newRGBA(1, 2, 3, 1), italic: true}, false, true},
+		{"same color, not italic", Highlight{foreground: fg}, false, false},
+		{"same color, underlined", Highlight{foreground: fg, italic: true, underline: true}, false, true},
+	}
+	for _, c := range cases {
+		bold, italic := s.fontStyle(&c.hl)
+		if bold != c.bold || italic != c.ital {
+			t.Errorf("%s: fontStyle = %v, %v, want %v, %v", c.name, bold, italic, c.bold, c.ital)
+		}
+	}
+}
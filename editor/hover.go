@@ -0,0 +1,45 @@
+package editor
+
+import (
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// hoverTarget is true when the cell at row, col is something a click acts
+// on, like a fold marker. The screen shows a pointing hand over any cell a
+// registered target claims.
+type hoverTarget func(row, col int) bool
+
+// addHoverTarget registers a part of the screen that takes clicks
+func (s *Screen) addHoverTarget(target hoverTarget) {
+	s.hoverTargets = append(s.hoverTargets, target)
+}
+
+// hover sets the mouse cursor for the cell under the mouse. It is a pointing
+// hand over the hover targets, an I-beam over the text with
+// g:gonvim_mouse_ibeam set, and the arrow elsewhere.
+func (s *Screen) hover(event *gui.QMouseEvent) {
+	col, row := s.pointCell(event.X(), event.Y())
+	shape := core.Qt__ArrowCursor
+	if s.mouseIBeam && s.windowAt(row, col) != nil {
+		shape = core.Qt__IBeamCursor
+	}
+	for _, target := range s.hoverTargets {
+		if target(row, col) {
+			shape = core.Qt__PointingHandCursor
+			break
+		}
+	}
+	if shape == s.hoverShape {
+		return
+	}
+	s.hoverShape = shape
+	s.widget.SetCursor(gui.NewQCursor2(shape))
+}
+
+// setClickable gives a widget that opens something on click the pointing
+// hand
+func setClickable(widget *widgets.QWidget) {
+	widget.SetCursor(gui.NewQCursor2(core.Qt__PointingHandCursor))
+}
@@ -0,0 +1,101 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// Inlay hints reach the screen as ordinary cells, so they are recognized by
+// the colors of their highlight group, g:gonvim_inlay_hint_group or
+// LspInlayHint. g:gonvim_inlay_hint_style turns the styling on and is a comma
+// separated list of dim, italic and small. As the group often links to
+// Comment, comments are styled the same unless it gets its own colors.
+type inlayHints struct {
+	group  string
+	dim    bool
+	italic bool
+	small  bool
+	fg     *RGBA
+	bg     *RGBA
+	font   *gui.QFont
+}
+
+func (s *Screen) setInlayHintStyle(style, group string) {
+	hints := &inlayHints{
+		group: group,
+	}
+	if hints.group == "" {
+		hints.group = "LspInlayHint"
+	}
+	for _, part := range strings.Split(style, ",") {
+		switch strings.TrimSpace(part) {
+		case "dim":
+			hints.dim = true
+		case "italic":
+			hints.italic = true
+		case "small":
+			hints.small = true
+		}
+	}
+	if !hints.dim && !hints.italic && !hints.small {
+		s.inlay = nil
+		return
+	}
+	s.inlay = hints
+}
+
+// isInlayHint is true for a cell drawn with the inlay hint colors
+func (s *Screen) isInlayHint(char *Char) bool {
+	hints := s.inlay
+	if hints == nil || hints.fg == nil {
+		return false
+	}
+	if !sameColor(char.highlight.foreground, hints.fg) {
+		return false
+	}
+	return hints.bg == nil || sameColor(char.highlight.background, hints.bg)
+}
+
+func (h *inlayHints) getFont(font *Font) *gui.QFont {
+	size := font.fontNew.PointSizeF()
+	if h.small {
+		size *= 0.85
+	}
+	if h.font == nil || h.font.Family() != font.fontNew.Family() || h.font.PointSizeF() != size {
+		h.font = gui.NewQFont2(font.fontNew.Family(), font.fontNew.PointSize(), int(gui.QFont__Normal), h.italic)
+		h.font.SetPointSizeF(size)
+	}
+	return h.font
+}
+
+// drawInlayHints draws the runs of inlay hint cells in their style, each
+// run starting at its own cell so the cells after it keep their columns
+func (s *Screen) drawInlayHints(p *gui.QPainter, line []*Char, xs []int, y int, pos [2]int) {
+	hints := s.inlay
+	if hints == nil || len(xs) == 0 {
+		return
+	}
+	fg := hints.fg
+	alpha := fg.A
+	if hints.dim {
+		alpha *= 0.6
+	}
+	p.SetFont(hints.getFont(s.ws.font))
+	p.SetPen2(gui.NewQColor3(fg.R, fg.G, fg.B, int(alpha*255)))
+	pointF := core.NewQPointF()
+	pointF.SetY(float64((y-pos[0])*s.ws.font.lineHeight + s.ws.font.shift))
+	for i := 0; i < len(xs); {
+		start := xs[i]
+		text := line[start].char
+		j := i + 1
+		for ; j < len(xs) && xs[j] == xs[j-1]+1; j++ {
+			text += line[xs[j]].char
+		}
+		pointF.SetX(s.ws.font.textX(start - pos[1]))
+		p.DrawText(pointF, text)
+		i = j
+	}
+	p.SetFont(s.ws.font.fontNew)
+}
@@ -0,0 +1,137 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/therecipe/qt/core"
+)
+
+const (
+	latencySamples = 20
+	latencyTimeout = 2000
+)
+
+// Latency measures the time from an input sent to neovim to the redraw it
+// causes and to the paint showing it. Every sample sends <C-l>, so it only
+// runs in normal mode. A sample that gets no redraw or paint within
+// latencyTimeout ms stops the run.
+type Latency struct {
+	ws      *Workspace
+	timer   *core.QTimer
+	running bool
+	file    string
+	waiting string
+	sent    time.Time
+	redrawn time.Time
+	nvim    []time.Duration
+	render  []time.Duration
+	total   []time.Duration
+}
+
+func (l *Latency) start(args []interface{}) {
+	if l.ws.mode != "normal" {
+		go l.ws.nvim.Command(`echomsg "GonvimLatency can only run in normal mode"`)
+		return
+	}
+	l.file = ""
+	if len(args) > 0 {
+		l.file, _ = args[0].(string)
+	}
+	if l.timer == nil {
+		l.timer = core.NewQTimer(nil)
+		l.timer.SetSingleShot(true)
+		l.timer.ConnectTimeout(l.timeout)
+	}
+	l.running = true
+	l.nvim = []time.Duration{}
+	l.render = []time.Duration{}
+	l.total = []time.Duration{}
+	l.send()
+}
+
+func (l *Latency) send() {
+	l.waiting = "redraw"
+	l.sent = time.Now()
+	l.timer.Start(latencyTimeout)
+	l.ws.input("<C-l>")
+}
+
+func (l *Latency) timeout() {
+	if !l.running {
+		return
+	}
+	msg := fmt.Sprintf("GonvimLatency: no %s within %dms, stopped after %d samples", l.waiting, latencyTimeout, len(l.total))
+	l.running = false
+	l.waiting = ""
+	go l.ws.nvim.Command(fmt.Sprintf("echomsg '%s'", msg))
+}
+
+func (l *Latency) redraw() {
+	if !l.running || l.waiting != "redraw" {
+		return
+	}
+	l.waiting = "paint"
+	l.redrawn = time.Now()
+}
+
+func (l *Latency) paintDone() {
+	if !l.running || l.waiting != "paint" {
+		return
+	}
+	l.waiting = ""
+	l.timer.Stop()
+	now := time.Now()
+	l.nvim = append(l.nvim, l.redrawn.Sub(l.sent))
+	l.render = append(l.render, now.Sub(l.redrawn))
+	l.total = append(l.total, now.Sub(l.sent))
+	if len(l.total) < latencySamples {
+		l.send()
+		return
+	}
+	l.running = false
+	l.report()
+}
+
+func (l *Latency) report() {
+	msg := fmt.Sprintf(
+		"latency median of %d: nvim+rpc %s, render %s, total %s",
+		len(l.total),
+		formatDuration(medianDuration(l.nvim)),
+		formatDuration(medianDuration(l.render)),
+		formatDuration(medianDuration(l.total)),
+	)
+	file := l.file
+	go func() {
+		l.ws.nvim.Command(fmt.Sprintf("echomsg '%s'", strings.Replace(msg, "'", "''", -1)))
+		if file == "" {
+			return
+		}
+		f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			l.ws.nvim.Command(fmt.Sprintf("echomsg '%s'", strings.Replace(err.Error(), "'", "''", -1)))
+			return
+		}
+		defer f.Close()
+		fmt.Fprintf(f, "%s %s\n", time.Now().Format(time.RFC3339), msg)
+	}()
+}
+
+func medianDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted[len(sorted)/2]
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
@@ -0,0 +1,49 @@
+package editor
+
+import (
+	"github.com/therecipe/qt/gui"
+)
+
+// Qt shapes every run of text it draws, so fonts with ligatures join "->"
+// and the like. g:gonvim_ligatures turns that off when set to 0, and
+// g:gonvim_ligature_filetypes overrides it per filetype, e.g.
+//
+//	let g:gonvim_ligature_filetypes = {'haskell': 1, 'markdown': 0, 'text': 0}
+//
+// The filetype of the current buffer is checked on BufEnter and FileType.
+type ligatureConfig struct {
+	enabled   bool
+	filetypes map[string]interface{}
+	current   bool
+}
+
+func (w *Workspace) setLigatureFiletype(args []interface{}) {
+	filetype := ""
+	if len(args) > 0 {
+		filetype, _ = args[0].(string)
+	}
+	ligatures := w.ligature.enabled
+	if value, ok := w.ligature.filetypes[filetype]; ok {
+		ligatures = isTrue(value)
+	}
+	if ligatures == w.ligature.current {
+		return
+	}
+	w.ligature.current = ligatures
+	w.updateFontShaping()
+	// the glyphs change but not the cell size, so only repaint
+	w.screen.queueRedrawAll()
+	w.screen.update()
+}
+
+// updateFontShaping sets the font's style strategy for the ligature setting
+func (w *Workspace) updateFontShaping() {
+	font := w.font.fontNew
+	strategy := font.StyleStrategy() &^ gui.QFont__PreferNoShaping
+	if !w.ligature.current {
+		strategy |= gui.QFont__PreferNoShaping
+	}
+	font.SetStyleStrategy(strategy)
+	w.font.updateStyles()
+	w.screen.lineCache.invalidate()
+}
@@ -0,0 +1,99 @@
+package editor
+
+import (
+	"math"
+	"sync/atomic"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// lineCache keeps the text of every row drawn in a pixmap with
+// g:gonvim_line_cache set, so a repaint of a row that hasn't changed copies
+// the pixmap instead of shaping the text again. A full width scroll moves
+// the pixmaps with the rows. put, eol_clear and a partial scroll drop the
+// rows they touch. clear, a resize and anything changing how text looks
+// (colors, font, text contrast) drop them all. The pixmaps are transparent
+// under the text, so it is drawn with grayscale antialiasing rather than
+// subpixel, which is why the cache is off by default.
+type lineCache struct {
+	enabled bool
+	stale   int32
+	rows    []*gui.QPixmap
+}
+
+// invalidate drops all rows. It can be called off the Qt thread, so the
+// flag is set atomically and the rows are dropped by the next drawLine.
+func (c *lineCache) invalidate() {
+	atomic.StoreInt32(&c.stale, 1)
+}
+
+// invalidateRows drops the rows from top to bottom
+func (c *lineCache) invalidateRows(top, bottom int) {
+	for row := top; row <= bottom && row < len(c.rows); row++ {
+		if row >= 0 {
+			c.rows[row] = nil
+		}
+	}
+}
+
+// scroll moves the rows between top and bottom like scroll moves the content
+func (c *lineCache) scroll(top, bottom, count int) {
+	if bottom >= len(c.rows) {
+		c.invalidate()
+		return
+	}
+	if count > 0 {
+		copy(c.rows[top:bottom-count+1], c.rows[top+count:bottom+1])
+		c.invalidateRows(bottom-count+1, bottom)
+	} else {
+		copy(c.rows[top-count:bottom+1], c.rows[top:bottom+count+1])
+		c.invalidateRows(top, top-count-1)
+	}
+}
+
+// drawLine draws the text of the columns of the row, from the cache when it
+// is on
+func (s *Screen) drawLine(p *gui.QPainter, y int, col int, cols int) {
+	c := &s.lineCache
+	if !c.enabled || s.hasRightLeft() {
+		s.drawText(p, y, col, cols, [2]int{0, 0})
+		return
+	}
+	if atomic.SwapInt32(&c.stale, 0) == 1 || len(c.rows) != s.ws.rows {
+		c.rows = make([]*gui.QPixmap, s.ws.rows)
+	}
+	font := s.ws.font
+	dpr := s.widget.DevicePixelRatioF()
+	pixmap := c.rows[y]
+	if pixmap == nil {
+		width := font.cellX(s.ws.cols)
+		pixmap = gui.NewQPixmap3(int(math.Ceil(float64(width)*dpr)), int(math.Ceil(float64(font.lineHeight)*dpr)))
+		pixmap.SetDevicePixelRatio(dpr)
+		pixmap.Fill(gui.NewQColor3(0, 0, 0, 0))
+		pp := gui.NewQPainter2(pixmap)
+		pp.SetFont(font.fontNew)
+		s.drawText(pp, y, 0, s.ws.cols, [2]int{y, 0})
+		pp.DestroyQPainter()
+		c.rows[y] = pixmap
+	}
+	x := float64(font.cellX(col))
+	width := float64(font.cellsWidth(col, col+cols))
+	height := float64(font.lineHeight)
+	p.DrawPixmap(
+		core.NewQRectF4(x, float64(y*font.lineHeight), width, height),
+		pixmap,
+		core.NewQRectF4(x*dpr, 0, width*dpr, height*dpr),
+	)
+}
+
+// hasRightLeft is true when a window is 'rightleft', whose text is laid out
+// by window and not by row
+func (s *Screen) hasRightLeft() bool {
+	for _, win := range s.curWins {
+		if win.rightleft {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package editor
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/therecipe/qt/gui"
+)
+
+func TestLineCacheScroll(t *testing.T) {
+	rows := make([]*gui.QPixmap, 6)
+	for i := range rows {
+		rows[i] = &gui.QPixmap{}
+	}
+	cases := []struct {
+		top, bottom, count int
+		want               []int
+	}{
+		{0, 5, 2, []int{2, 3, 4, 5, -1, -1}},
+		{0, 5, -2, []int{-1, -1, 0, 1, 2, 3}},
+		{1, 4, 1, []int{0, 2, 3, 4, -1, 5}},
+		{1, 4, -1, []int{0, -1, 1, 2, 3, 5}},
+	}
+	for _, c := range cases {
+		cache := &lineCache{enabled: true, rows: append([]*gui.QPixmap{}, rows...)}
+		cache.scroll(c.top, c.bottom, c.count)
+		for i, index := range c.want {
+			var want *gui.QPixmap
+			if index >= 0 {
+				want = rows[index]
+			}
+			if cache.rows[i] != want {
+				t.Errorf("scroll(%d, %d, %d): row %d is not the old row %d", c.top, c.bottom, c.count, i, index)
+			}
+		}
+	}
+
+	cache := &lineCache{enabled: true, rows: append([]*gui.QPixmap{}, rows...)}
+	cache.scroll(0, 6, 1)
+	if atomic.LoadInt32(&cache.stale) != 1 {
+		t.Error("scrolling past the cached rows did not drop them")
+	}
+}
+
+func benchmarkScroll(b *testing.B, cached bool) {
+	s := newGridScreen(60, 200)
+	line := ""
+	for i := 0; i < 200; i++ {
+		line += string(rune('a' + i%26))
+	}
+	for row := 0; row < 60; row++ {
+		gridPut(s, row, 0, line)
+	}
+	s.lineCache.enabled = cached
+	s.lineCache.rows = make([]*gui.QPixmap, 60)
+	up := []interface{}{[]interface{}{int64(1)}}
+	down := []interface{}{[]interface{}{int64(-1)}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.scroll(up)
+		s.scroll(down)
+	}
+}
+
+// the cost of moving the grid and the cached rows for a full width scroll,
+// painting is left out
+func BenchmarkScroll(b *testing.B) {
+	benchmarkScroll(b, false)
+}
+
+func BenchmarkScrollLineCache(b *testing.B) {
+	benchmarkScroll(b, true)
+}
@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"math"
+
+	"github.com/therecipe/qt/gui"
+)
+
+// LineMarker is a bar at the left edge of the cursor's line in the focused
+// window, a quieter hint of the current line than 'cursorline'.
+// GonvimLineMarker on/off or g:gonvim_line_marker turns it on,
+// g:gonvim_line_marker_width is its width in pixels, 3 by default, and the
+// color is the foreground of g:gonvim_line_marker_group, CursorLineNr by
+// default.
+type LineMarker struct {
+	s       *Screen
+	enabled bool
+	width   int
+	group   string
+	color   *RGBA
+	row     int
+	col     int
+}
+
+func newLineMarker(s *Screen) *LineMarker {
+	return &LineMarker{
+		s:     s,
+		width: 3,
+		group: "CursorLineNr",
+		row:   -1,
+	}
+}
+
+func (m *LineMarker) toggle(args []interface{}) {
+	enabled := toggleArg(m.enabled, args)
+	if enabled == m.enabled {
+		return
+	}
+	m.enabled = enabled
+	m.moved()
+	m.s.update()
+}
+
+// moved is called after every redraw and repaints the rows the marker was
+// on and goes to
+func (m *LineMarker) moved() {
+	s := m.s
+	row, col := -1, 0
+	if m.enabled {
+		win := s.cursorWin()
+		if win != nil {
+			row = s.cursor[0]
+			col = win.pos[1]
+		}
+	}
+	if row == m.row && col == m.col {
+		return
+	}
+	cells := int(math.Ceil(float64(m.width) / s.ws.font.truewidth))
+	if m.row >= 0 {
+		s.queueRedraw(m.col, m.row, cells, 1)
+	}
+	if row >= 0 {
+		s.queueRedraw(col, row, cells, 1)
+	}
+	m.row = row
+	m.col = col
+}
+
+func (m *LineMarker) draw(p *gui.QPainter, row, rows int) {
+	if !m.enabled || m.row < row || m.row >= row+rows || m.width <= 0 {
+		return
+	}
+	color := m.color
+	if color == nil {
+		color = m.s.ws.foreground
+	}
+	if color == nil {
+		return
+	}
+	font := m.s.ws.font
+	p.FillRect5(
+		font.cellX(m.col),
+		m.row*font.lineHeight,
+		m.width,
+		font.lineHeight,
+		color.QColor(),
+	)
+}
@@ -0,0 +1,205 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// Modal is a centered dialog opened by plugins through
+// GonvimModal(title, body, buttons [, callback]). It blocks input to the
+// editor until one of its buttons is chosen, and then the callback is called
+// with the index of the button, or -1 for Escape. GonvimModal returns at
+// once, and dialogs opened while one is shown are shown after it.
+type Modal struct {
+	ws           *Workspace
+	widget       *widgets.QWidget
+	panel        *widgets.QWidget
+	title        *widgets.QLabel
+	body         *widgets.QLabel
+	buttonLayout *widgets.QHBoxLayout
+	buttons      []*widgets.QPushButton
+	shown        bool
+	queue        []*modalRequest
+}
+
+type modalRequest struct {
+	title   string
+	body    string
+	buttons []string
+}
+
+func initModal() *Modal {
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetContentsMargins(0, 0, 0, 0)
+	widget.SetStyleSheet(".QWidget {background-color: rgba(0, 0, 0, 0.4);}")
+
+	padding := 16
+	layout := widgets.NewQVBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(padding)
+	panel := widgets.NewQWidget(widget, 0)
+	panel.SetLayout(layout)
+	panel.SetContentsMargins(padding, padding, padding, padding)
+	panel.SetObjectName("modal")
+	panel.SetStyleSheet(`
+	QWidget#modal {
+		border: 1px solid #000;
+		background-color: rgba(24, 29, 34, 1);
+	}
+	* {
+		color: rgba(205, 211, 222, 1);
+	}
+	QPushButton {
+		border: none;
+		padding: 6px 16px;
+		background-color: #3c3c3c;
+	}
+	QPushButton:focus {
+		background-color: rgba(81, 154, 186, 1);
+	}
+	`)
+	shadow := widgets.NewQGraphicsDropShadowEffect(nil)
+	shadow.SetBlurRadius(20)
+	shadow.SetColor(gui.NewQColor3(0, 0, 0, 255))
+	shadow.SetOffset3(0, 2)
+	panel.SetGraphicsEffect(shadow)
+
+	title := widgets.NewQLabel(nil, 0)
+	title.SetStyleSheet("font-weight: bold;")
+	body := widgets.NewQLabel(nil, 0)
+	body.SetWordWrap(true)
+	buttonLayout := widgets.NewQHBoxLayout()
+	buttonLayout.SetContentsMargins(0, 0, 0, 0)
+	buttonLayout.SetSpacing(8)
+	buttonLayout.AddStretch(1)
+	layout.AddWidget(title, 0, 0)
+	layout.AddWidget(body, 0, 0)
+	layout.AddLayout(buttonLayout, 0)
+
+	m := &Modal{
+		widget:       widget,
+		panel:        panel,
+		title:        title,
+		body:         body,
+		buttonLayout: buttonLayout,
+	}
+	widget.ConnectKeyPressEvent(m.keyPress)
+	widget.ConnectMousePressEvent(func(event *gui.QMouseEvent) {})
+	widget.ConnectMouseReleaseEvent(func(event *gui.QMouseEvent) {})
+	widget.ConnectWheelEvent(func(event *gui.QWheelEvent) {})
+	widget.Hide()
+	return m
+}
+
+func (m *Modal) subscribe() {
+	apiInfo, err := m.ws.nvim.APIInfo()
+	if err != nil || len(apiInfo) == 0 {
+		return
+	}
+	m.ws.nvim.Command(fmt.Sprintf("let g:gonvim_channel_id = %d", reflectToInt(apiInfo[0])))
+	m.ws.nvim.Command("let g:gonvim_modal_callbacks = []")
+	m.ws.nvim.Command(`execute "function! GonvimModal(title, body, buttons, ...) abort\n call add(g:gonvim_modal_callbacks, get(a:, 1, {index -> 0}))\n call rpcnotify(g:gonvim_channel_id, 'Gui', 'gonvim_modal', a:title, a:body, a:buttons)\nendfunction"`)
+	m.ws.nvim.Command(`execute "function! GonvimModalDone(index) abort\n let Callback = remove(g:gonvim_modal_callbacks, 0)\n call call(Callback, [a:index])\nendfunction"`)
+}
+
+// open shows the dialog GonvimModal asked for, or queues it behind the one
+// shown
+func (m *Modal) open(args []interface{}) {
+	if len(args) < 3 {
+		return
+	}
+	req := &modalRequest{}
+	req.title, _ = args[0].(string)
+	req.body, _ = args[1].(string)
+	buttons, _ := args[2].([]interface{})
+	for _, button := range buttons {
+		text, ok := button.(string)
+		if ok {
+			req.buttons = append(req.buttons, text)
+		}
+	}
+	if m.shown {
+		m.queue = append(m.queue, req)
+		return
+	}
+	m.show(req)
+}
+
+func (m *Modal) show(req *modalRequest) {
+	for _, button := range m.buttons {
+		button.DeleteLater()
+	}
+	m.buttons = []*widgets.QPushButton{}
+	if len(req.buttons) == 0 {
+		req.buttons = []string{"OK"}
+	}
+	for i, text := range req.buttons {
+		index := i
+		button := widgets.NewQPushButton2(text, nil)
+		button.ConnectClicked(func(checked bool) {
+			m.finish(index)
+		})
+		m.buttonLayout.AddWidget(button, 0, 0)
+		m.buttons = append(m.buttons, button)
+	}
+	m.title.SetText(req.title)
+	m.body.SetText(req.body)
+	m.shown = true
+	m.resize()
+	m.widget.Show()
+	m.widget.Raise()
+	m.buttons[0].SetFocus2()
+}
+
+func (m *Modal) resize() {
+	if !m.shown {
+		return
+	}
+	width := m.ws.screen.widget.Width()
+	height := m.ws.screen.widget.Height()
+	m.widget.Resize2(width, height)
+	m.panel.SetMaximumWidth(width * 2 / 3)
+	m.panel.AdjustSize()
+	m.panel.Move2((width-m.panel.Width())/2, (height-m.panel.Height())/2)
+}
+
+func (m *Modal) keyPress(event *gui.QKeyEvent) {
+	focused := 0
+	for i, button := range m.buttons {
+		if button.HasFocus() {
+			focused = i
+		}
+	}
+	switch core.Qt__Key(event.Key()) {
+	case core.Qt__Key_Escape:
+		m.finish(-1)
+	case core.Qt__Key_Return, core.Qt__Key_Enter:
+		m.finish(focused)
+	case core.Qt__Key_Left:
+		if focused > 0 {
+			m.buttons[focused-1].SetFocus2()
+		}
+	case core.Qt__Key_Right:
+		if focused < len(m.buttons)-1 {
+			m.buttons[focused+1].SetFocus2()
+		}
+	}
+}
+
+func (m *Modal) finish(index int) {
+	if !m.shown {
+		return
+	}
+	m.shown = false
+	m.widget.Hide()
+	m.ws.widget.SetFocus2()
+	m.ws.nvim.Command(fmt.Sprintf("call GonvimModalDone(%d)", index))
+	if len(m.queue) > 0 {
+		req := m.queue[0]
+		m.queue = m.queue[1:]
+		m.show(req)
+	}
+}
@@ -0,0 +1,302 @@
+package editor
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/widgets"
+)
+
+// MessagePanel shows the msg_show and msg_history_show events of
+// ext_messages in a panel docked at the bottom of the screen, so long
+// output like :messages no longer scrolls the grid. It grows with the
+// messages up to a third of the screen and scrolls past that. Each message
+// is in the color of its kind. The panel never takes the focus, so the keys
+// answering a confirm prompt still go to neovim.
+//
+// Neovim only sends ext_messages to UIs drawing ext_linegrid, which gonvim
+// does with g:gonvim_multigrid, so the panel is only used with it set;
+// without it the messages stay on the grid as before. With the panel,
+// 'cmdheight' is set to 0 on neovim 0.8 and later, so the rows the messages
+// were in go to the windows.
+//
+// neovim no longer draws the mode, 'showcmd' and the ruler on the last row
+// with ext_messages, they come as msg_showmode, msg_showcmd and msg_ruler and
+// are shown by the panel as small badges in the bottom corners, above the
+// messages while they are shown, the mode at the left and the other two at
+// the right.
+type MessagePanel struct {
+	enabled  bool
+	ws       *Workspace
+	widget   *widgets.QScrollArea
+	label    *widgets.QLabel
+	messages []panelMessage
+	colors   map[string]*RGBA
+	mode     *widgets.QLabel
+	cmd      *widgets.QLabel
+	showmode string
+	showcmd  string
+	ruler    string
+}
+
+type panelMessage struct {
+	kind string
+	text string
+}
+
+func initMessagePanel() *MessagePanel {
+	label := widgets.NewQLabel(nil, 0)
+	label.SetContentsMargins(8, 4, 8, 4)
+	label.SetWordWrap(true)
+	label.SetTextFormat(core.Qt__RichText)
+	label.SetAlignment(core.Qt__AlignLeft | core.Qt__AlignTop)
+	widget := widgets.NewQScrollArea(nil)
+	widget.SetWidget(label)
+	widget.SetWidgetResizable(true)
+	widget.SetFocusPolicy(core.Qt__NoFocus)
+	widget.SetHorizontalScrollBarPolicy(core.Qt__ScrollBarAlwaysOff)
+	widget.SetFrameShape(widgets.QFrame__NoFrame)
+	widget.SetStyleSheet(`
+	QScrollArea, QLabel {
+		background-color: rgba(24, 29, 34, 1);
+		color: rgba(205, 211, 222, 1);
+		border-top: 1px solid #000;
+	}
+	`)
+	widget.Hide()
+	return &MessagePanel{
+		widget: widget,
+		label:  label,
+		colors: map[string]*RGBA{},
+		mode:   newMessageStatusLabel(core.Qt__AlignLeft),
+		cmd:    newMessageStatusLabel(core.Qt__AlignRight),
+	}
+}
+
+// newMessageStatusLabel makes a badge for the mode or the ruler, sized to
+// its text. The mouse goes through it to the grid.
+func newMessageStatusLabel(align core.Qt__AlignmentFlag) *widgets.QLabel {
+	label := widgets.NewQLabel(nil, 0)
+	label.SetTextFormat(core.Qt__RichText)
+	label.SetAlignment(align | core.Qt__AlignVCenter)
+	label.SetAttribute(core.Qt__WA_TransparentForMouseEvents, true)
+	label.SetContentsMargins(6, 1, 6, 1)
+	label.SetStyleSheet(`
+	QLabel {
+		background-color: rgba(24, 29, 34, 0.9);
+		border: 1px solid #000;
+		border-radius: 3px;
+	}
+	`)
+	label.Hide()
+	return label
+}
+
+// messageGroups are the highlight groups the message kinds are shown in
+var messageGroups = map[string]string{
+	"emsg":          "ErrorMsg",
+	"echoerr":       "ErrorMsg",
+	"lua_error":     "ErrorMsg",
+	"rpc_error":     "ErrorMsg",
+	"wmsg":          "WarningMsg",
+	"confirm":       "Question",
+	"confirm_sub":   "Question",
+	"return_prompt": "Question",
+	"quickfix":      "Title",
+}
+
+// setColors takes the colors of messageGroups from the other highlight
+// colors, on the GUI thread
+func (m *MessagePanel) setColors(attrs hlAttrs) {
+	colors := map[string]*RGBA{}
+	for kind, group := range messageGroups {
+		color := attrs.color(group, "fg")
+		if color != nil {
+			colors[kind] = color
+		}
+	}
+	m.colors = colors
+}
+
+// show handles msg_show, with the kind, the [attr, text] chunks and whether
+// it replaces the last message
+func (m *MessagePanel) show(args []interface{}) {
+	for _, arg := range args {
+		msg, ok := arg.([]interface{})
+		if !ok || len(msg) < 2 {
+			continue
+		}
+		kind, _ := msg[0].(string)
+		text := messageText(msg[1])
+		replace := false
+		if len(msg) > 2 {
+			replace, _ = msg[2].(bool)
+		}
+		if replace && len(m.messages) > 0 {
+			m.messages = m.messages[:len(m.messages)-1]
+		}
+		m.messages = append(m.messages, panelMessage{kind: kind, text: text})
+	}
+	m.update()
+}
+
+// historyShow handles msg_history_show, the entries of :messages as [kind,
+// chunks]
+func (m *MessagePanel) historyShow(args []interface{}) {
+	m.messages = []panelMessage{}
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) == 0 {
+			continue
+		}
+		entries, _ := params[0].([]interface{})
+		for _, entry := range entries {
+			e, ok := entry.([]interface{})
+			if !ok || len(e) < 2 {
+				continue
+			}
+			kind, _ := e[0].(string)
+			m.messages = append(m.messages, panelMessage{kind: kind, text: messageText(e[1])})
+		}
+	}
+	m.update()
+}
+
+func (m *MessagePanel) clear() {
+	m.messages = []panelMessage{}
+	m.update()
+}
+
+// messageText joins the text of the [attr, text] chunks of a message
+func messageText(content interface{}) string {
+	return strings.TrimRight(cmdlineText(content), "\n")
+}
+
+func (m *MessagePanel) update() {
+	if len(m.messages) == 0 {
+		m.widget.Hide()
+		m.resize()
+		return
+	}
+	fg := newRGBA(205, 211, 222, 1)
+	if m.ws.foreground != nil {
+		fg = m.ws.foreground
+	}
+	text := ""
+	for _, msg := range m.messages {
+		color := fg
+		if c, ok := m.colors[msg.kind]; ok {
+			color = c
+		}
+		text += fmt.Sprintf(
+			`<p style="margin: 0; white-space: pre-wrap; color: %s;">%s</p>`,
+			color.String(),
+			html.EscapeString(msg.text),
+		)
+	}
+	m.label.SetFont(m.ws.font.fontNew)
+	m.label.SetText(text)
+	m.resize()
+	m.widget.Show()
+	m.widget.Raise()
+	bar := m.widget.VerticalScrollBar()
+	bar.SetValue(bar.Maximum())
+}
+
+// showMode handles msg_showmode, msg_showcmd and msg_ruler, each sending
+// the [attr, text] chunks to show in place of the last ones
+func (m *MessagePanel) showMode(args []interface{}) {
+	m.showmode = m.statusText(args)
+	m.updateStatus()
+}
+
+func (m *MessagePanel) showCmd(args []interface{}) {
+	m.showcmd = m.statusText(args)
+	m.updateStatus()
+}
+
+func (m *MessagePanel) showRuler(args []interface{}) {
+	m.ruler = m.statusText(args)
+	m.updateStatus()
+}
+
+// statusText is the rich text of the chunks of the last event in args, in
+// the colors of their attr ids
+func (m *MessagePanel) statusText(args []interface{}) string {
+	if len(args) == 0 {
+		return ""
+	}
+	params, ok := args[len(args)-1].([]interface{})
+	if !ok || len(params) == 0 {
+		return ""
+	}
+	chunks, _ := params[0].([]interface{})
+	text := ""
+	for _, chunk := range chunks {
+		parts, ok := chunk.([]interface{})
+		if !ok || len(parts) < 2 {
+			continue
+		}
+		str, _ := parts[1].(string)
+		if str == "" {
+			continue
+		}
+		hl := m.ws.screen.gridHighlight(reflectToInt(parts[0]))
+		fg := hl.foreground
+		if fg == nil {
+			fg = m.ws.foreground
+		}
+		style := "white-space: pre;"
+		if fg != nil {
+			style += fmt.Sprintf(" color: %s;", fg.String())
+		}
+		if hl.bold {
+			style += " font-weight: bold;"
+		}
+		text += fmt.Sprintf(`<span style="%s">%s</span>`, style, html.EscapeString(str))
+	}
+	return text
+}
+
+func (m *MessagePanel) updateStatus() {
+	cmd := m.showcmd
+	if cmd != "" && m.ruler != "" {
+		cmd += "&nbsp;&nbsp;&nbsp;"
+	}
+	cmd += m.ruler
+	for _, status := range []struct {
+		label *widgets.QLabel
+		text  string
+	}{
+		{m.mode, m.showmode},
+		{m.cmd, cmd},
+	} {
+		if status.text == "" {
+			status.label.Hide()
+			continue
+		}
+		status.label.SetFont(m.ws.font.fontNew)
+		status.label.SetText(status.text)
+		status.label.AdjustSize()
+		status.label.Show()
+		status.label.Raise()
+	}
+	m.resize()
+}
+
+func (m *MessagePanel) resize() {
+	width := m.ws.screen.widget.Width()
+	height := m.label.HeightForWidth(width) + 1
+	height = minInt(height, m.ws.screen.height/3)
+	m.widget.Resize2(width, height)
+	m.widget.Move2(0, m.ws.screen.height-height)
+	bottom := m.ws.screen.height
+	if len(m.messages) > 0 {
+		bottom -= height
+	}
+	margin := 4
+	m.mode.Move2(margin, bottom-m.mode.Height()-margin)
+	m.cmd.Move2(width-m.cmd.Width()-margin, bottom-m.cmd.Height()-margin)
+}
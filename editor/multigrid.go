@@ -0,0 +1,529 @@
+package editor
+
+import (
+	"math"
+	"sort"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// multigrid keeps the grids neovim sends with g:gonvim_multigrid set, which
+// attaches with ext_linegrid and ext_multigrid. Every window, float and the
+// message area is then a grid of its own with its position sent along,
+// instead of one grid with all of them drawn in. After each batch of redraw
+// events the grids are composed into the screen's content, the global grid
+// first, then the windows, the message area and the floats by zindex, so
+// everything reading the content keeps working. A grid scrolls on its own
+// and only the rows that changed in the composed content are repainted.
+type multigrid struct {
+	enabled bool
+	changed bool
+	grids   map[int]*grid
+	hlDefs  map[int]map[string]interface{}
+	hls     map[int]Highlight
+	cursor  [3]int
+	floats  [][4]int
+}
+
+type grid struct {
+	id      int
+	width   int
+	height  int
+	content [][]*Char
+	win     nvim.Window
+	row     int
+	col     int
+	hidden  bool
+	float   bool
+	message bool
+	anchor  string
+	anchorG int
+	anchorR float64
+	anchorC float64
+	zindex  int
+}
+
+func (m *multigrid) grid(id int) *grid {
+	if m.grids == nil {
+		m.grids = map[int]*grid{}
+	}
+	g, ok := m.grids[id]
+	if !ok {
+		g = &grid{id: id}
+		m.grids[id] = g
+	}
+	return g
+}
+
+func (g *grid) resize(width, height int) {
+	content := make([][]*Char, height)
+	for row := range content {
+		content[row] = make([]*Char, width)
+		if row < len(g.content) {
+			copy(content[row], g.content[row])
+		}
+	}
+	g.width = width
+	g.height = height
+	g.content = content
+}
+
+// origin is where the grid goes on the screen, floats are placed by their
+// anchor on the grid they are relative to
+func (m *multigrid) origin(g *grid, depth int) (int, int) {
+	if !g.float {
+		return g.row, g.col
+	}
+	row := g.anchorR
+	col := g.anchorC
+	if anchor, ok := m.grids[g.anchorG]; ok && anchor != g && depth < 10 {
+		r, c := m.origin(anchor, depth+1)
+		row += float64(r)
+		col += float64(c)
+	}
+	switch g.anchor {
+	case "NE":
+		col -= float64(g.width)
+	case "SW":
+		row -= float64(g.height)
+	case "SE":
+		row -= float64(g.height)
+		col -= float64(g.width)
+	}
+	return int(math.Max(row, 0)), int(math.Max(col, 0))
+}
+
+// defaultColorsSet handles default_colors_set, which replaces update_fg,
+// update_bg and update_sp with ext_linegrid
+func (s *Screen) defaultColorsSet(args []interface{}) {
+	for _, arg := range args {
+		colors, ok := arg.([]interface{})
+		if !ok || len(colors) < 3 {
+			continue
+		}
+		s.updateBg(colors[1:2])
+		fg := reflectToInt(colors[0])
+		if fg == -1 {
+			s.ws.foreground = newRGBA(255, 255, 255, 1)
+		} else {
+			s.ws.foreground = calcColor(fg)
+		}
+		sp := reflectToInt(colors[2])
+		if sp == -1 {
+			s.ws.special = newRGBA(255, 255, 255, 1)
+		} else {
+			s.ws.special = calcColor(sp)
+		}
+	}
+	// the highlights without colors of their own take the defaults
+	s.grids.hls = map[int]Highlight{}
+	s.lineCache.invalidate()
+	s.queueRedrawAll()
+}
+
+// hlAttrDefine keeps the highlights grid_line refers to by id, they are
+// resolved when first used so the default colors they fall back to are the
+// current ones
+func (s *Screen) hlAttrDefine(args []interface{}) {
+	m := &s.grids
+	if m.hlDefs == nil {
+		m.hlDefs = map[int]map[string]interface{}{}
+	}
+	for _, arg := range args {
+		def, ok := arg.([]interface{})
+		if !ok || len(def) < 2 {
+			continue
+		}
+		id := reflectToInt(def[0])
+		attrs, _ := def[1].(map[string]interface{})
+		m.hlDefs[id] = attrs
+		delete(m.hls, id)
+	}
+}
+
+func (s *Screen) gridHighlight(id int) Highlight {
+	m := &s.grids
+	if m.hls == nil {
+		m.hls = map[int]Highlight{}
+	}
+	if hl, ok := m.hls[id]; ok {
+		return hl
+	}
+	attrs := m.hlDefs[id]
+	highlight := Highlight{
+		foreground: s.ws.foreground,
+		background: s.ws.background,
+		special:    s.ws.special,
+	}
+	if fg, ok := attrs["foreground"]; ok {
+		highlight.foreground = calcColor(reflectToInt(fg))
+	}
+	if bg, ok := attrs["background"]; ok {
+		highlight.background = calcColor(reflectToInt(bg))
+	}
+	if sp, ok := attrs["special"]; ok {
+		highlight.special = calcColor(reflectToInt(sp))
+	}
+	if _, ok := attrs["reverse"]; ok {
+		highlight.foreground, highlight.background = highlight.background, highlight.foreground
+		highlight.reverse = true
+	}
+	_, highlight.bold = attrs["bold"]
+	_, highlight.italic = attrs["italic"]
+	_, highlight.undercurl = attrs["undercurl"]
+	_, highlight.underline = attrs["underline"]
+	highlight.underdouble = hasUnderdouble(attrs)
+	_, highlight.strike = attrs["strikethrough"]
+	m.hls[id] = highlight
+	return highlight
+}
+
+func (s *Screen) gridResize(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 3 {
+			continue
+		}
+		g := s.grids.grid(reflectToInt(params[0]))
+		g.resize(reflectToInt(params[1]), reflectToInt(params[2]))
+	}
+	s.grids.changed = true
+}
+
+func (s *Screen) gridClear(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 1 {
+			continue
+		}
+		g := s.grids.grid(reflectToInt(params[0]))
+		for _, line := range g.content {
+			for col := range line {
+				line[col] = nil
+			}
+		}
+	}
+	s.grids.changed = true
+}
+
+func (s *Screen) gridDestroy(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 1 {
+			continue
+		}
+		delete(s.grids.grids, reflectToInt(params[0]))
+	}
+	s.grids.changed = true
+}
+
+func (s *Screen) gridCursorGoto(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 3 {
+			continue
+		}
+		s.grids.cursor = [3]int{reflectToInt(params[0]), reflectToInt(params[1]), reflectToInt(params[2])}
+	}
+	s.grids.changed = true
+}
+
+// gridLine handles grid_line, whose cells are [text, hl_id, repeat] with
+// hl_id left out when it is the one of the cell before and repeat when it
+// is 1
+func (s *Screen) gridLine(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 4 {
+			continue
+		}
+		g := s.grids.grid(reflectToInt(params[0]))
+		row := reflectToInt(params[1])
+		col := reflectToInt(params[2])
+		cells, _ := params[3].([]interface{})
+		if row < 0 || row >= len(g.content) {
+			continue
+		}
+		line := g.content[row]
+		hlID := 0
+		for _, c := range cells {
+			cell, ok := c.([]interface{})
+			if !ok || len(cell) == 0 {
+				continue
+			}
+			text, _ := cell[0].(string)
+			if len(cell) > 1 {
+				hlID = reflectToInt(cell[1])
+			}
+			repeat := 1
+			if len(cell) > 2 {
+				repeat = reflectToInt(cell[2])
+			}
+			highlight := s.gridHighlight(hlID)
+			normalWidth := s.isNormalWidth(text)
+			for i := 0; i < repeat && col < len(line); i++ {
+				line[col] = &Char{
+					char:        text,
+					normalWidth: normalWidth,
+					highlight:   highlight,
+				}
+				col++
+			}
+		}
+	}
+	s.grids.changed = true
+}
+
+// gridScroll handles grid_scroll, the region is top to bot and left to right
+// with bot and right excluded
+func (s *Screen) gridScroll(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 6 {
+			continue
+		}
+		g := s.grids.grid(reflectToInt(params[0]))
+		top := reflectToInt(params[1])
+		bot := minInt(reflectToInt(params[2]), g.height)
+		left := reflectToInt(params[3])
+		right := minInt(reflectToInt(params[4]), g.width)
+		count := reflectToInt(params[5])
+		if count > 0 {
+			for row := top; row < bot; row++ {
+				for col := left; col < right; col++ {
+					if row+count < bot {
+						g.content[row][col] = g.content[row+count][col]
+					} else {
+						g.content[row][col] = nil
+					}
+				}
+			}
+		} else {
+			for row := bot - 1; row >= top; row-- {
+				for col := left; col < right; col++ {
+					if row+count >= top {
+						g.content[row][col] = g.content[row+count][col]
+					} else {
+						g.content[row][col] = nil
+					}
+				}
+			}
+		}
+	}
+	s.grids.changed = true
+}
+
+func (s *Screen) winPos(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 4 {
+			continue
+		}
+		g := s.grids.grid(reflectToInt(params[0]))
+		g.win, _ = params[1].(nvim.Window)
+		g.row = reflectToInt(params[2])
+		g.col = reflectToInt(params[3])
+		g.float = false
+		g.hidden = false
+	}
+	s.grids.changed = true
+}
+
+func (s *Screen) winFloatPos(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 6 {
+			continue
+		}
+		g := s.grids.grid(reflectToInt(params[0]))
+		g.win, _ = params[1].(nvim.Window)
+		g.anchor, _ = params[2].(string)
+		g.anchorG = reflectToInt(params[3])
+		g.anchorR = reflectToFloat(params[4])
+		g.anchorC = reflectToFloat(params[5])
+		g.zindex = 50
+		if len(params) > 7 {
+			g.zindex = reflectToInt(params[7])
+		}
+		g.float = true
+		g.hidden = false
+	}
+	s.grids.changed = true
+}
+
+// winHide handles win_hide and win_close, the grid is kept until
+// grid_destroy
+func (s *Screen) winHide(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 1 {
+			continue
+		}
+		if g, ok := s.grids.grids[reflectToInt(params[0])]; ok {
+			g.hidden = true
+		}
+	}
+	s.grids.changed = true
+}
+
+// msgSetPos places the message grid, which is full width and drawn over the
+// windows when messages scroll
+func (s *Screen) msgSetPos(args []interface{}) {
+	for _, arg := range args {
+		params, ok := arg.([]interface{})
+		if !ok || len(params) < 2 {
+			continue
+		}
+		g := s.grids.grid(reflectToInt(params[0]))
+		g.row = reflectToInt(params[1])
+		g.col = 0
+		g.message = true
+		g.hidden = false
+	}
+	s.grids.changed = true
+}
+
+// placeWindows sets the window positions from the grids. They come with
+// the redraw events, so they are never older than the content, unlike the
+// ones getWindows fetched.
+func (m *multigrid) placeWindows(wins map[nvim.Window]*Window) {
+	if !m.enabled {
+		return
+	}
+	for _, g := range m.grids {
+		win, ok := wins[g.win]
+		if !ok || g.hidden || g.message {
+			continue
+		}
+		row, col := m.origin(g, 0)
+		win.pos = [2]int{row + win.winbar, col}
+		win.float = g.float
+	}
+}
+
+// composeGrids puts the grids together into the content and queues the
+// cells that changed
+func (s *Screen) composeGrids() {
+	m := &s.grids
+	if !m.enabled || !m.changed {
+		return
+	}
+	m.changed = false
+	m.placeWindows(s.curWins)
+	rows := s.ws.rows
+	cols := s.ws.cols
+
+	content := make([][]*Char, rows)
+	for row := range content {
+		content[row] = make([]*Char, cols)
+	}
+	for _, g := range m.sorted() {
+		top, left := m.origin(g, 0)
+		blend := 0
+		var floatBg *RGBA
+		if win, ok := s.curWins[g.win]; ok && g.float {
+			blend = win.blend
+			floatBg = win.bg
+		}
+		for r, line := range g.content {
+			row := top + r
+			if row < 0 || row >= rows {
+				continue
+			}
+			for c, char := range line {
+				col := left + c
+				if col < 0 || col >= cols {
+					continue
+				}
+				content[row][col] = s.blendFloat(char, content[row][col], blend, floatBg)
+			}
+		}
+		if g.id == m.cursor[0] {
+			s.cursor[0] = top + m.cursor[1]
+			s.cursor[1] = left + m.cursor[2]
+		}
+	}
+
+	// the shadows are outside of the cells, where a float was and is now
+	// is repainted when it moves
+	floats := s.floatRects()
+	if !equalRects(floats, m.floats) {
+		for _, rect := range append(m.floats, floats...) {
+			s.queueRedraw(rect[1], rect[0], rect[2]+1, rect[3]+1)
+		}
+		m.floats = floats
+	}
+
+	if len(s.content) != rows {
+		s.lineCache.invalidate()
+		s.queueRedrawAll()
+		s.content = content
+		return
+	}
+	for row, line := range content {
+		old := s.content[row]
+		first := -1
+		last := -1
+		for col, char := range line {
+			if col < len(old) && old[col] == char {
+				continue
+			}
+			if first < 0 {
+				first = col
+			}
+			last = col
+		}
+		if first < 0 {
+			continue
+		}
+		// a wide char on either side is drawn over both its cells
+		s.lineCache.invalidateRows(row, row)
+		s.queueRedraw(first-1, row, last-first+3, 1)
+	}
+	s.content = content
+}
+
+// sorted is the grids shown in the order they are drawn in, the global grid
+// first, then the windows, the message area and the floats by zindex
+func (m *multigrid) sorted() []*grid {
+	grids := []*grid{}
+	for _, g := range m.grids {
+		if !g.hidden {
+			grids = append(grids, g)
+		}
+	}
+	layer := func(g *grid) int {
+		switch {
+		case g.id == 1:
+			return 0
+		case g.float:
+			return 3
+		case g.message:
+			return 2
+		}
+		return 1
+	}
+	sort.Slice(grids, func(i, j int) bool {
+		a, b := grids[i], grids[j]
+		if layer(a) != layer(b) {
+			return layer(a) < layer(b)
+		}
+		if a.float && a.zindex != b.zindex {
+			return a.zindex < b.zindex
+		}
+		return a.id < b.id
+	})
+	return grids
+}
+
+func equalRects(a, b [][4]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,41 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+func TestPlaceWindows(t *testing.T) {
+	s := newGridScreen(20, 80)
+	s.grids.enabled = true
+	s.winPos([]interface{}{
+		[]interface{}{int64(2), nvim.Window(1000), int64(0), int64(0), int64(40), int64(19)},
+		[]interface{}{int64(3), nvim.Window(1001), int64(0), int64(41), int64(39), int64(19)},
+	})
+	s.winFloatPos([]interface{}{
+		[]interface{}{int64(4), nvim.Window(1002), "NW", int64(3), float64(5), float64(2)},
+	})
+	// fetched before the second window moved down for a split above it
+	wins := map[nvim.Window]*Window{
+		1000: {win: 1000, pos: [2]int{0, 0}},
+		1001: {win: 1001, pos: [2]int{0, 41}, winbar: 1},
+		1002: {win: 1002, pos: [2]int{9, 9}},
+	}
+	s.winPos([]interface{}{
+		[]interface{}{int64(3), nvim.Window(1001), int64(10), int64(41), int64(39), int64(9)},
+	})
+	s.grids.placeWindows(wins)
+	for id, want := range map[nvim.Window][2]int{
+		1000: {0, 0},
+		1001: {11, 41},
+		1002: {15, 43},
+	} {
+		if wins[id].pos != want {
+			t.Errorf("window %d at %v, want %v", id, wins[id].pos, want)
+		}
+	}
+	if !wins[1002].float {
+		t.Errorf("float not marked from its grid")
+	}
+}
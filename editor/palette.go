@@ -2,6 +2,7 @@ package editor
 
 import (
 	"fmt"
+	"html"
 
 	"github.com/dzhou121/gonvim/fuzzy"
 	"github.com/therecipe/qt/core"
@@ -198,6 +199,19 @@ func (p *Palette) setPattern(text string) {
 	p.pattern.SetText(text)
 }
 
+// setPatternGhost shows ghost after the pattern in a muted color
+func (p *Palette) setPatternGhost(ghost string) {
+	if ghost == "" {
+		p.pattern.SetText(p.patternText)
+		return
+	}
+	p.pattern.SetText(fmt.Sprintf(
+		"<span style=\"white-space: pre;\">%s<span style=\"color: rgba(131, 131, 131, 1);\">%s</span></span>",
+		html.EscapeString(p.patternText),
+		html.EscapeString(ghost),
+	))
+}
+
 func (p *Palette) cursorMove(x int) {
 	p.cursorX = int(p.ws.font.defaultFontMetrics.Width(string(p.patternText[:x])))
 	p.cursor.Move2(p.cursorX+p.patternPadding, p.patternPadding)
@@ -0,0 +1,74 @@
+package editor
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// Shift+Insert, and Cmd+V on macOS, paste the system clipboard in insert
+// mode without a clipboard provider configured in neovim. The text goes
+// through nvim_paste, so it is inserted as one paste: no mappings or
+// abbreviations are triggered and it isn't autoindented line by line.
+// Ctrl+V and the middle click mean something to neovim in insert mode
+// (i_CTRL-V and <MiddleMouse>), so they are only taken over with
+// g:gonvim_clipboard_paste set to 1, Ctrl+V then pasting the clipboard and
+// on X11 a middle click pasting the primary selection. With
+// g:gonvim_clipboard_paste set to 0 all of them go to neovim.
+
+// pasteKey pastes the clipboard when the key is the paste shortcut in insert
+// mode
+func (w *Workspace) pasteKey(event *gui.QKeyEvent) bool {
+	if !w.pasteShortcut || w.mode != "insert" || !event.Matches(gui.QKeySequence__Paste) {
+		return false
+	}
+	// Cmd+V on macOS comes as Key_V with the control modifier, the Ctrl
+	// key is the meta modifier there
+	if !w.clipboardPaste && event.Key() == int(core.Qt__Key_V) && runtime.GOOS != "darwin" {
+		return false
+	}
+	text := widgets.QApplication_Clipboard().Text(gui.QClipboard__Clipboard)
+	if text == "" {
+		return true
+	}
+	w.inputs <- func() {
+		w.paste("", text)
+	}
+	return true
+}
+
+// pasteSelection pastes the primary selection at the mouse on a middle click.
+// The drag and release of a click that pasted are swallowed.
+func (s *Screen) pasteSelection(event *gui.QMouseEvent, col, row int) bool {
+	clipboard := widgets.QApplication_Clipboard()
+	if !s.ws.clipboardPaste || !clipboard.SupportsSelection() {
+		return false
+	}
+	if event.Type() != core.QEvent__MouseButtonPress {
+		return s.pastedSelection
+	}
+	text := clipboard.Text(gui.QClipboard__Selection)
+	s.pastedSelection = text != ""
+	if !s.pastedSelection {
+		return false
+	}
+	click := fmt.Sprintf("<LeftMouse><%d,%d><LeftRelease><%d,%d>", col, row, col, row)
+	s.ws.inputs <- func() {
+		s.ws.paste(click, text)
+	}
+	return true
+}
+
+// paste sends the input first, then the text with nvim_paste in a single
+// call. It runs on the input queue, after the keys typed before the paste.
+// CRLF line endings are left for neovim to convert.
+func (w *Workspace) paste(input string, text string) {
+	if input != "" {
+		w.nvim.Input(input)
+	}
+	var result bool
+	w.nvim.Call("nvim_paste", &result, text, true, -1)
+}
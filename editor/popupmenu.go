@@ -7,7 +7,15 @@ import (
 	"github.com/therecipe/qt/widgets"
 )
 
-// PopupMenu is the popupmenu
+const popupmenuRows = 15
+
+// PopupMenu is the popupmenu. It shows at most g:gonvim_popupmenu_max_rows
+// items, or 'pumheight' when that isn't set, or popupmenuRows when neither
+// is, and fewer when the screen has no room for them below the cursor. The
+// rest of the items are scrolled to, keeping the selected one in view. When
+// there is more room above the cursor than below it the menu opens upwards.
+// The colors are those of Pmenu and PmenuSel, and a click on an item picks
+// it.
 type PopupMenu struct {
 	ws              *Workspace
 	widget          *widgets.QWidget
@@ -25,10 +33,19 @@ type PopupMenu struct {
 	scrollCol       *widgets.QWidget
 	x               int
 	y               int
+	blend           int
+	maxRows         int
+	pumheight       int
+	fg              *RGBA
+	bg              *RGBA
+	selFg           *RGBA
+	selBg           *RGBA
+	style           string
 }
 
 // PopupItem is
 type PopupItem struct {
+	popup           *PopupMenu
 	kindLable       *widgets.QLabel
 	kindText        string
 	kindColor       *RGBA
@@ -36,6 +53,9 @@ type PopupItem struct {
 	menuLable       *widgets.QLabel
 	menuText        string
 	menuTextRequest string
+	detailLable     *widgets.QLabel
+	detailText      string
+	style           string
 	selected        bool
 	selectedRequest bool
 	hidden          bool
@@ -65,34 +85,72 @@ func initPopupmenuNew(font *Font) *PopupMenu {
 	shadow.SetColor(gui.NewQColor3(0, 0, 0, 255))
 	shadow.SetOffset3(0, 2)
 	widget.SetGraphicsEffect(shadow)
-	max := 15
-	var popupItems []*PopupItem
-	for i := 0; i < max; i++ {
+
+	popup := &PopupMenu{
+		widget:    widget,
+		layout:    layout,
+		scrollBar: scrollBar,
+		scrollCol: scrollCol,
+	}
+	popup.addItems(popupmenuRows, font)
+	return popup
+}
+
+// addItems makes sure there are the labels for n items
+func (p *PopupMenu) addItems(n int, font *Font) {
+	for i := len(p.items); i < n; i++ {
 		kind := widgets.NewQLabel(nil, 0)
 		kind.SetContentsMargins(8, 8, 8, 8)
 		kind.SetFont(font.fontNew)
 		menu := widgets.NewQLabel(nil, 0)
 		menu.SetContentsMargins(8, 8, 8, 8)
 		menu.SetFont(font.fontNew)
-		layout.AddWidget(kind, i, 0, 0)
-		layout.AddWidget(menu, i, 1, 0)
+		detail := widgets.NewQLabel(nil, 0)
+		detail.SetContentsMargins(0, 8, 8, 8)
+		detail.SetFont(font.fontNew)
+		p.layout.AddWidget(kind, i, 0, 0)
+		p.layout.AddWidget(menu, i, 1, 0)
+		p.layout.AddWidget(detail, i, 2, 0)
 
 		popupItem := &PopupItem{
-			kindLable: kind,
-			menuLable: menu,
+			popup:       p,
+			kindLable:   kind,
+			menuLable:   menu,
+			detailLable: detail,
+		}
+		index := i
+		click := func(event *gui.QMouseEvent) {
+			p.clickItem(index)
 		}
-		popupItems = append(popupItems, popupItem)
+		kind.ConnectMousePressEvent(click)
+		menu.ConnectMousePressEvent(click)
+		detail.ConnectMousePressEvent(click)
+		if i >= popupmenuRows {
+			popupItem.hide()
+		}
+		p.items = append(p.items, popupItem)
 	}
+	p.total = len(p.items)
+}
 
-	popup := &PopupMenu{
-		widget:    widget,
-		layout:    layout,
-		items:     popupItems,
-		total:     max,
-		scrollBar: scrollBar,
-		scrollCol: scrollCol,
+// rows is the most items shown at once
+func (p *PopupMenu) rows() int {
+	rows := p.maxRows
+	if rows <= 0 {
+		rows = p.pumheight
 	}
-	return popup
+	if rows <= 0 {
+		rows = popupmenuRows
+	}
+	p.addItems(rows, p.ws.font)
+	return rows
+}
+
+func (p *PopupMenu) setPumheight(args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	p.pumheight = reflectToInt(args[0])
 }
 
 func (p *PopupMenu) updateFont(font *Font) {
@@ -100,7 +158,98 @@ func (p *PopupMenu) updateFont(font *Font) {
 		popupItem := p.items[i]
 		popupItem.kindLable.SetFont(font.fontNew)
 		popupItem.menuLable.SetFont(font.fontNew)
+		popupItem.detailLable.SetFont(font.fontNew)
+	}
+}
+
+var popupColorKeys = [][2]string{
+	{"Pmenu", "fg"},
+	{"Pmenu", "bg"},
+	{"PmenuSel", "fg"},
+	{"PmenuSel", "bg"},
+}
+
+// setColors takes the Pmenu and PmenuSel colors from the other highlight
+// colors, on the GUI thread
+func (p *PopupMenu) setColors(attrs hlAttrs) {
+	p.fg = attrs.color("Pmenu", "fg")
+	p.bg = attrs.color("Pmenu", "bg")
+	p.selFg = attrs.color("PmenuSel", "fg")
+	p.selBg = attrs.color("PmenuSel", "bg")
+}
+
+// setBlend applies 'pumblend' by making the popup background translucent
+// over the content beneath it
+func (p *PopupMenu) setBlend(blend int) {
+	if blend < 0 {
+		blend = 0
+	}
+	if blend > 100 {
+		blend = 100
+	}
+	p.blend = blend
+	bg := newRGBA(24, 29, 34, 1)
+	if p.bg != nil {
+		bg = p.bg.copy()
+	}
+	bg.A = float64(100-blend) / 100
+	fg := newRGBA(205, 211, 222, 1)
+	if p.fg != nil {
+		fg = p.fg
+	}
+	style := fmt.Sprintf("* {background-color: %s; color: %s;}", bg.String(), fg.String())
+	if style == p.style {
+		return
+	}
+	p.style = style
+	p.widget.SetStyleSheet(style)
+}
+
+// selectedStyle is the style of the word of the selected item
+func (p *PopupMenu) selectedStyle() string {
+	bg := editor.selectedBg
+	if p.selBg != nil {
+		bg = p.selBg
+	}
+	style := fmt.Sprintf("background-color: %s;", bg.String())
+	if p.selFg != nil {
+		style += fmt.Sprintf(" color: %s;", p.selFg.String())
 	}
+	return style
+}
+
+// detailStyle is the style of the menu column, the item's extra text
+func (p *PopupMenu) detailStyle() string {
+	fg := newRGBA(205, 211, 222, 1)
+	if p.fg != nil {
+		fg = p.fg.copy()
+	}
+	fg.A = 0.6
+	return fmt.Sprintf("color: %s;", fg.String())
+}
+
+// clickItem picks the item of the label clicked, by moving the selection to
+// it with <C-n> or <C-p> and accepting it with <C-y>
+func (p *PopupMenu) clickItem(index int) {
+	target := index + p.top
+	if target >= len(p.rawItems) {
+		return
+	}
+	input := ""
+	key := "<C-n>"
+	steps := target - p.selected
+	if p.selected < 0 {
+		steps = target + 1
+	}
+	if steps < 0 {
+		key = "<C-p>"
+		steps = -steps
+	}
+	for i := 0; i < steps; i++ {
+		input += key
+	}
+	input += "<C-y>"
+	p.ws.input(input)
 }
 
 func (p *PopupMenu) showItems(args []interface{}) {
@@ -112,20 +261,33 @@ func (p *PopupMenu) showItems(args []interface{}) {
 	p.rawItems = items
 	p.selected = selected
 	p.top = 0
+	p.setBlend(p.ws.pumblend)
 
 	popupItems := p.items
 	itemHeight := p.ws.font.height + 20
 	heightLeft := p.ws.screen.height - (row+1)*p.ws.font.lineHeight
 	total := heightLeft / itemHeight
-	if total < p.total {
+	rows := minInt(p.rows(), len(items))
+	above := row * p.ws.font.lineHeight / itemHeight
+	upwards := total < rows && above > total
+	if upwards {
+		total = above
+	}
+	if total < 1 {
+		total = 1
+	}
+	if total < rows {
 		p.showTotal = total
 	} else {
-		p.showTotal = p.total
+		p.showTotal = rows
+	}
+	if p.showTotal < 1 {
+		p.showTotal = 1
 	}
 
 	for i := 0; i < p.total; i++ {
 		popupItem := popupItems[i]
-		if i >= len(items) || i >= total {
+		if i >= len(items) || i >= p.showTotal {
 			popupItem.hide()
 			continue
 		}
@@ -145,9 +307,14 @@ func (p *PopupMenu) showItems(args []interface{}) {
 		p.scrollCol.Hide()
 	}
 
+	y := (row + 1) * p.ws.font.lineHeight
+	if upwards {
+		// the height of the rows shown, with the 1px margins
+		y = row*p.ws.font.lineHeight - p.showTotal*itemHeight - 2
+	}
 	p.widget.Move2(
 		int(float64(col)*p.ws.font.truewidth)-popupItems[0].kindLable.Width()-8,
-		(row+1)*p.ws.font.lineHeight,
+		y,
 	)
 	p.show()
 }
@@ -162,6 +329,7 @@ func (p *PopupMenu) hide() {
 
 func (p *PopupMenu) selectItem(args []interface{}) {
 	selected := reflectToInt(args[0].([]interface{})[0])
+	p.selected = selected
 	if selected == -1 && p.top > 0 {
 		p.scroll(-p.top)
 	}
@@ -199,13 +367,16 @@ func (p *PopupItem) updateKind() {
 }
 
 func (p *PopupItem) updateMenu() {
-	if p.selected != p.selectedRequest {
-		p.selected = p.selectedRequest
-		if p.selected {
-			p.menuLable.SetStyleSheet(fmt.Sprintf("background-color: %s;", editor.selectedBg.String()))
-		} else {
-			p.menuLable.SetStyleSheet("")
-		}
+	p.selected = p.selectedRequest
+	menuStyle := ""
+	if p.selected {
+		menuStyle = p.popup.selectedStyle() + " "
+	}
+	style := menuStyle + p.popup.detailStyle()
+	if style != p.style {
+		p.style = style
+		p.menuLable.SetStyleSheet(menuStyle)
+		p.detailLable.SetStyleSheet(style)
 	}
 	if p.menuTextRequest != p.menuText {
 		p.menuText = p.menuTextRequest
@@ -223,55 +394,48 @@ func (p *PopupItem) setItem(item []interface{}, selected bool) {
 	kindText := item[1].(string)
 	p.setKind(kindText, selected)
 	p.menuTextRequest = text
+	detail := ""
+	if len(item) > 2 {
+		detail, _ = item[2].(string)
+	}
+	if detail != p.detailText {
+		p.detailText = detail
+		p.detailLable.SetText(detail)
+	}
 	p.setSelected(selected)
 }
 
 func (p *PopupItem) setKind(kindText string, selected bool) {
-	color := newRGBA(151, 195, 120, 1)
-	bg := newRGBA(151, 195, 120, 0.2)
+	kindText, color, bg := kindStyle(kindText)
+	if kindText != p.kindText {
+		p.kindText = kindText
+		p.kindColor = color
+		p.kindBg = bg
+		p.updateKind()
+	}
+}
 
-	switch kindText {
+// kindStyle returns the letter and colors showing a completion or symbol kind
+func kindStyle(kind string) (string, *RGBA, *RGBA) {
+	switch kind {
 	case "function", "func":
-		kindText = "f"
-		color = newRGBA(97, 174, 239, 1)
-		bg = newRGBA(97, 174, 239, 0.2)
+		return "f", newRGBA(97, 174, 239, 1), newRGBA(97, 174, 239, 0.2)
 	case "var", "statement", "instance", "param", "import":
-		kindText = "v"
-		color = newRGBA(223, 106, 115, 1)
-		bg = newRGBA(223, 106, 115, 0.2)
+		return "v", newRGBA(223, 106, 115, 1), newRGBA(223, 106, 115, 0.2)
 	case "const":
-		kindText = "c"
-		color = newRGBA(223, 106, 115, 1)
-		bg = newRGBA(223, 106, 115, 0.2)
+		return "c", newRGBA(223, 106, 115, 1), newRGBA(223, 106, 115, 0.2)
 	case "class":
-		kindText = "c"
-		color = newRGBA(229, 193, 124, 1)
-		bg = newRGBA(229, 193, 124, 0.2)
+		return "c", newRGBA(229, 193, 124, 1), newRGBA(229, 193, 124, 0.2)
 	case "type":
-		kindText = "t"
-		color = newRGBA(229, 193, 124, 1)
-		bg = newRGBA(229, 193, 124, 0.2)
+		return "t", newRGBA(229, 193, 124, 1), newRGBA(229, 193, 124, 0.2)
 	case "module":
-		kindText = "m"
-		color = newRGBA(42, 161, 152, 1)
-		bg = newRGBA(42, 161, 152, 0.2)
+		return "m", newRGBA(42, 161, 152, 1), newRGBA(42, 161, 152, 0.2)
 	case "keyword":
-		kindText = "k"
-		color = newRGBA(42, 161, 152, 1)
-		bg = newRGBA(42, 161, 152, 0.2)
+		return "k", newRGBA(42, 161, 152, 1), newRGBA(42, 161, 152, 0.2)
 	case "package":
-		kindText = "p"
-		color = newRGBA(42, 161, 152, 1)
-		bg = newRGBA(42, 161, 152, 0.2)
-	default:
-		kindText = "b"
-	}
-	if kindText != p.kindText {
-		p.kindText = kindText
-		p.kindColor = color
-		p.kindBg = bg
-		p.updateKind()
+		return "p", newRGBA(42, 161, 152, 1), newRGBA(42, 161, 152, 0.2)
 	}
+	return "b", newRGBA(151, 195, 120, 1), newRGBA(151, 195, 120, 0.2)
 }
 
 func (p *PopupItem) hide() {
@@ -281,6 +445,7 @@ func (p *PopupItem) hide() {
 	p.hidden = true
 	p.kindLable.Hide()
 	p.menuLable.Hide()
+	p.detailLable.Hide()
 }
 
 func (p *PopupItem) show() {
@@ -290,4 +455,5 @@ func (p *PopupItem) show() {
 	p.hidden = false
 	p.kindLable.Show()
 	p.menuLable.Show()
+	p.detailLable.Show()
 }
@@ -0,0 +1,63 @@
+package editor
+
+import (
+	"math"
+
+	"github.com/therecipe/qt/gui"
+)
+
+const (
+	presentFontScale = 1.5
+	presentContrast  = 1.2
+)
+
+// presentSettings are the settings GonvimPresentMode changes, kept from
+// before it was turned on so turning it off puts them back exactly. The
+// cursor blink isn't among them, present mode keeps the cursor steady with
+// its own flag so a GonvimCursorBlink in the meantime isn't undone.
+type presentSettings struct {
+	fontSize        int
+	styleStrategy   gui.QFont__StyleStrategy
+	textContrast    float64
+	borderAntialias string
+}
+
+// setPresentMode handles GonvimPresentMode, for screen sharing. It makes the
+// font bigger and antialiased at the best quality, raises the text contrast,
+// antialiases the borders and stops the cursor blinking.
+func (w *Workspace) setPresentMode(args []interface{}) {
+	enable := toggleArg(w.present != nil, args)
+	if enable == (w.present != nil) {
+		return
+	}
+	font := w.font.fontNew
+	screen := w.screen
+	if enable {
+		w.present = &presentSettings{
+			fontSize:        font.PointSize(),
+			styleStrategy:   font.StyleStrategy(),
+			textContrast:    screen.textContrast,
+			borderAntialias: screen.borderAntialias,
+		}
+		font.SetStyleStrategy(w.present.styleStrategy | gui.QFont__PreferAntialias | gui.QFont__PreferQuality)
+		w.font.change(font.Family(), int(math.Ceil(float64(w.present.fontSize)*presentFontScale)))
+		screen.textContrast = math.Max(screen.textContrast, presentContrast)
+		screen.borderAntialias = "on"
+	} else {
+		saved := w.present
+		w.present = nil
+		font.SetStyleStrategy(saved.styleStrategy)
+		w.updateFontShaping()
+		w.font.change(font.Family(), saved.fontSize)
+		screen.textContrast = saved.textContrast
+		screen.borderAntialias = saved.borderAntialias
+	}
+	w.cursor.presentSteady = enable
+	w.updateSize()
+	w.popup.updateFont(w.font)
+	screen.toolTipFont(w.font)
+	w.cursor.updateShape()
+	w.cursor.updateBlink()
+	screen.queueRedrawAll()
+	screen.update()
+}
@@ -0,0 +1,126 @@
+package editor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/neovim/go-client/msgpack"
+)
+
+// The redraw log written when GONVIM_RECORD_REDRAW is set to a file path is a
+// stream of msgpack arrays, one per redraw notification:
+//
+//	[time, workspace, updates]
+//
+// time is the unix time in nanoseconds the notification was received,
+// workspace the number of the workspace it was sent to counting from 0 in
+// the order they were created, and updates the notification's arguments as
+// neovim sent them. New fields are only ever appended to the array.
+type redrawRecorder struct {
+	mutex   sync.Mutex
+	file    *os.File
+	encoder *msgpack.Encoder
+	next    int
+}
+
+// redrawRecord is a redraw notification read back from a redraw log
+type redrawRecord struct {
+	time      time.Time
+	workspace int
+	updates   [][]interface{}
+}
+
+var recorder = newRedrawRecorder()
+
+func newRedrawRecorder() *redrawRecorder {
+	path := os.Getenv("GONVIM_RECORD_REDRAW")
+	if path == "" {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Println("can't record redraw events", err)
+		return nil
+	}
+	return &redrawRecorder{
+		file:    file,
+		encoder: msgpack.NewEncoder(file),
+	}
+}
+
+// workspace returns the number of a newly created workspace
+func (r *redrawRecorder) workspace() int {
+	if r == nil {
+		return 0
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	n := r.next
+	r.next++
+	return n
+}
+
+func (r *redrawRecorder) record(workspace int, updates [][]interface{}) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err := r.encoder.Encode([]interface{}{time.Now().UnixNano(), workspace, updates})
+	if err != nil {
+		fmt.Println("can't record redraw events", err)
+	}
+}
+
+// readRedrawLog reads a redraw log so it can be replayed into a screen
+// through the same handling as live redraw events
+func readRedrawLog(r io.Reader) ([]*redrawRecord, error) {
+	records := []*redrawRecord{}
+	decoder := msgpack.NewDecoder(r)
+	for {
+		var item []interface{}
+		err := decoder.Decode(&item)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		if len(item) < 3 {
+			return records, fmt.Errorf("invalid redraw record %v", item)
+		}
+		record := &redrawRecord{
+			time:      time.Unix(0, int64(reflectToInt(item[0]))),
+			workspace: reflectToInt(item[1]),
+		}
+		updates, _ := item[2].([]interface{})
+		for _, update := range updates {
+			u, ok := update.([]interface{})
+			if ok {
+				record.updates = append(record.updates, u)
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// replay applies the grid events of the records sent to the workspace to
+// the screen, as they were handled when recorded. Nothing is painted, so it
+// works on a screen without a widget and the content can be checked after.
+func (s *Screen) replay(records []*redrawRecord, workspace int) {
+	for _, record := range records {
+		if record.workspace != workspace {
+			continue
+		}
+		for _, update := range record.updates {
+			if len(update) == 0 {
+				continue
+			}
+			event, _ := update[0].(string)
+			s.handleGridEvent(event, update[1:])
+		}
+	}
+}
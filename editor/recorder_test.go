@@ -0,0 +1,51 @@
+package editor
+
+import (
+	"os"
+	"testing"
+)
+
+// testdata/redraw.log is a recorded session of a 20x5 screen. Workspace 0
+// writes four lines, scrolls the top four rows up by one, writes "new" on
+// the row that opened up and clears the first row from column 2. Workspace
+// 1 writes an X, which must not show up on workspace 0.
+func TestReplayRedrawLog(t *testing.T) {
+	file, err := os.Open("testdata/redraw.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := readRedrawLog(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("read %d records, want 3", len(records))
+	}
+	if records[1].workspace != 1 || !records[0].time.Before(records[2].time) {
+		t.Errorf("records: workspace %d, times %v %v", records[1].workspace, records[0].time, records[2].time)
+	}
+
+	s := &Screen{
+		ws:           &Workspace{rows: 5, cols: 20},
+		scrollRegion: []int{0, 0, 0, 0},
+	}
+	s.replay(records, 0)
+	want := "wo                  \n" +
+		"line 3              \n" +
+		"line 4              \n" +
+		"new                 \n" +
+		"                    \n"
+	if text := string(s.gridText(false)); text != want {
+		t.Errorf("replayed grid:\n%s\nwant:\n%s", text, want)
+	}
+	if s.cursor != [2]int{0, 2} {
+		t.Errorf("cursor %v, want [0 2]", s.cursor)
+	}
+	if char := s.content[1][0]; char == nil || char.highlight.bold {
+		t.Errorf("row 1 should be the plain line 3 scrolled up")
+	}
+	if char := s.content[0][0]; char == nil || !char.highlight.bold || !sameColor(char.highlight.foreground, calcColor(0xff0000)) {
+		t.Errorf("row 0 should keep the bold red of world")
+	}
+}
@@ -0,0 +1,121 @@
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQueueRedraw(t *testing.T) {
+	s := newGridScreen(10, 80)
+	s.queueRedraw(5, 1, 10, 2)
+	s.queueRedraw(20, 2, 5, 1)
+	s.queueRedraw(-3, 6, 8, 1)
+	s.queueRedraw(0, 8, 80, 5)
+	s.queueRedraw(0, 0, 0, 3)
+
+	want := [][4]int{
+		{5, 1, 10, 1},
+		{5, 2, 20, 1},
+		{0, 6, 5, 1},
+		{0, 8, 80, 2},
+	}
+	if rects := s.dirtyRects(); !reflect.DeepEqual(rects, want) {
+		t.Errorf("dirtyRects() = %v, want %v", rects, want)
+	}
+	if s.dirtyAll {
+		t.Error("dirtyAll set by queueRedraw")
+	}
+}
+
+func TestQueueRedrawResized(t *testing.T) {
+	s := newGridScreen(10, 80)
+	s.ws.rows = 12
+	s.queueRedraw(0, 0, 1, 1)
+	if !s.dirtyAll {
+		t.Error("queueRedraw after a resize did not queue everything")
+	}
+}
+
+// BenchmarkQueueRedrawScattered queues one cell on every other row, like a
+// :%s changing a word on many lines
+func BenchmarkQueueRedrawScattered(b *testing.B) {
+	s := newGridScreen(60, 200)
+	for i := 0; i < b.N; i++ {
+		for row := 0; row < 60; row += 2 {
+			s.queueRedraw(row%40, row, 5, 1)
+		}
+		s.dirtyRects()
+		for y := range s.dirtyLines {
+			s.dirtyLines[y] = [2]int{}
+		}
+	}
+}
+
+// the rows scroll queues after moving the content include the region's
+// last column, right being inclusive
+func TestScrollQueuesLastColumn(t *testing.T) {
+	for _, count := range []int64{2, -2} {
+		s := newGridScreen(10, 40)
+		s.handleGridEvent("set_scroll_region", []interface{}{[]interface{}{int64(2), int64(7), int64(5), int64(19)}})
+		s.handleGridEvent("scroll", []interface{}{[]interface{}{count}})
+		for row := 0; row < 10; row++ {
+			span := s.dirtyLines[row]
+			if span[1] <= span[0] {
+				continue
+			}
+			if span[0] != 5 || span[1] != 20 {
+				t.Errorf("scroll %d: row %d queued columns %d to %d, want 5 to 20", count, row, span[0], span[1])
+			}
+		}
+		for row := 2; row <= 7; row++ {
+			if s.dirtyLines[row] != [2]int{5, 20} {
+				t.Errorf("scroll %d: row %d of the region not queued", count, row)
+			}
+		}
+		// the rows next to the region are queued too, only by the redraw
+		// after the move
+		outside := []int{0, 1}
+		if count < 0 {
+			outside = []int{8, 9}
+		}
+		for _, row := range outside {
+			if s.dirtyLines[row] != [2]int{5, 20} {
+				t.Errorf("scroll %d: row %d next to the region queued %v", count, row, s.dirtyLines[row])
+			}
+		}
+	}
+}
+
+// a scroll confined to columns 10 to 40 moves only those columns of the
+// region's rows and leaves the columns on either side alone
+func TestScrollKeepsToRegionColumns(t *testing.T) {
+	for _, count := range []int{3, -3} {
+		s := newGridScreen(12, 50)
+		for row := 0; row < 12; row++ {
+			gridPut(s, row, 0, strings.Repeat(string(rune('a'+row)), 50))
+		}
+		s.handleGridEvent("set_scroll_region", []interface{}{[]interface{}{int64(2), int64(9), int64(10), int64(40)}})
+		s.handleGridEvent("scroll", []interface{}{[]interface{}{int64(count)}})
+		for row := 0; row < 12; row++ {
+			for col := 0; col < 50; col++ {
+				want := string(rune('a' + row))
+				if row >= 2 && row <= 9 && col >= 10 && col <= 40 {
+					from := row + count
+					if from < 2 || from > 9 {
+						want = ""
+					} else {
+						want = string(rune('a' + from))
+					}
+				}
+				got := ""
+				if char := s.content[row][col]; char != nil {
+					got = char.char
+				}
+				if got != want {
+					t.Errorf("scroll %d: row %d column %d = %q, want %q", count, row, col, got, want)
+				}
+			}
+		}
+	}
+}
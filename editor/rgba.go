@@ -53,6 +53,19 @@ func calcColor(c int) *RGBA {
 	}
 }
 
+func hexToRGBA(hex string) *RGBA {
+	var r, g, b int
+	format := "#%02x%02x%02x"
+	n, err := fmt.Sscanf(hex, format, &r, &g, &b)
+	if err != nil {
+		return nil
+	}
+	if n != 3 {
+		return nil
+	}
+	return newRGBA(r, g, b, 1)
+}
+
 func newRGBA(r int, g int, b int, a float64) *RGBA {
 	return &RGBA{
 		R: r,
@@ -61,3 +74,11 @@ func newRGBA(r int, g int, b int, a float64) *RGBA {
 		A: a,
 	}
 }
+
+// mix returns the color amount of the way from rgba to other, from 0 to 1
+func (rgba *RGBA) mix(other *RGBA, amount float64) *RGBA {
+	m := func(a, b int) int {
+		return int(float64(a)*(1-amount) + float64(b)*amount + 0.5)
+	}
+	return newRGBA(m(rgba.R, other.R), m(rgba.G, other.G), m(rgba.B, other.B), rgba.A)
+}
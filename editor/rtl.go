@@ -0,0 +1,90 @@
+package editor
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// neovim has no bidi support, it puts the characters in the cells in logical
+// order, reversed in 'rightleft' windows. Qt would reorder right-to-left
+// script again when drawing, so runs of cells are drawn with a left-to-right
+// override to keep every character in its cell.
+const (
+	leftToRightOverride = "\u202d"
+	popDirectional      = "\u202c"
+)
+
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+func isRTLChar(char string) bool {
+	r, _ := utf8.DecodeRuneInString(char)
+	return isRTL(r)
+}
+
+func hasRTL(text string) bool {
+	for _, r := range text {
+		if r >= 0x590 && isRTL(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepCellOrder returns the text to draw so it shows in the order of the cells
+func keepCellOrder(text string) string {
+	if !hasRTL(text) {
+		return text
+	}
+	return leftToRightOverride + text + popDirectional
+}
+
+// drawRightLeftRuns draws the cells of right-to-left script in 'rightleft'
+// windows. The cells of a run hold the text reversed, so it is reversed back
+// and drawn right-to-left ending at the run's last cell, which gives the same
+// order with the letters joined as the script needs.
+func (s *Screen) drawRightLeftRuns(p *gui.QPainter, line []*Char, xs []int, y int, pos [2]int) {
+	pointF := core.NewQPointF()
+	pointF.SetY(float64((y-pos[0])*s.ws.font.lineHeight + s.ws.font.shift))
+	for i := 0; i < len(xs); {
+		x := xs[i]
+		char := line[x]
+		text := char.char
+		end := x
+		j := i + 1
+		for ; j < len(xs); j++ {
+			next := line[xs[j]]
+			adjacent := xs[j] == end+1
+			// a wide cell is followed by an empty one
+			if xs[j] == end+2 && line[end+1] != nil && line[end+1].char == "" {
+				adjacent = true
+			}
+			if !adjacent || !sameStyle(&next.highlight, &char.highlight) {
+				break
+			}
+			end = xs[j]
+			text = next.char + text
+		}
+		fg := char.highlight.foreground
+		if fg == nil {
+			fg = s.ws.foreground
+		}
+		bold, italic := s.fontStyle(&char.highlight)
+		s.setFontStyle(p, bold, italic)
+		// the letter space is drawn after every glyph
+		letterSpace := float64(s.ws.font.letterSpace)
+		width := s.ws.font.fontMetrics.Width(text) + letterSpace*float64(utf8.RuneCountInString(text))
+		last := end + 1
+		if last < len(line) && line[last] != nil && line[last].char == "" {
+			last++
+		}
+		right := float64(s.ws.font.cellX(last - pos[1]))
+		pointF.SetX(right - width + letterSpace/2)
+		s.drawGlyphs(p, pointF, text, fg)
+		i = j
+	}
+}
@@ -3,9 +3,13 @@ package editor
 import (
 	"fmt"
 	"math"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/neovim/go-client/nvim"
 	"github.com/therecipe/qt/core"
@@ -13,6 +17,13 @@ import (
 	"github.com/therecipe/qt/widgets"
 )
 
+// resizeDelay is how long, in ms, the window size has to settle before it
+// is sent to neovim
+const resizeDelay = 30
+
+// wheelLines is how many lines a wheel step scrolls, neovim's default
+const wheelLines = 3
+
 // Window is
 type Window struct {
 	win        nvim.Window
@@ -20,10 +31,30 @@ type Window struct {
 	height     int
 	pos        [2]int
 	tab        nvim.Tabpage
+	buf        nvim.Buffer
 	hl         string
 	bg         *RGBA
 	statusline bool
 	bufName    string
+	bufType    string
+	diff       bool
+	textoff    int
+	topline    int
+	lines      int
+	qfIdx      int
+	blend      int
+	rightleft  bool
+	foldcolumn int
+	statuscol  string
+	atTop      bool
+	atBottom   bool
+	textstart  int
+	textwidth  int
+	leftcol    int
+	numcol     int
+	foldRows   []int
+	float      bool
+	winbar     int
 }
 
 // Screen is the main editor area
@@ -42,11 +73,66 @@ type Screen struct {
 	cmdheight       int
 	highlight       Highlight
 	curWins         map[nvim.Window]*Window
-	queueRedrawArea [4]int
+	cursorWinID     nvim.Window
+	clampRefresh    [2]int
+	windowsFetching bool
+	windowsRefetch  bool
+	windowsCursor   bool
+	dirtyLines      [][2]int
+	dirtyAll        bool
 	paintMutex      sync.Mutex
 	redrawMutex     sync.Mutex
 	drawSplit       bool
+	undercurlStyle  string
+	scrollPastEnd   int
+	wheelDelta      [2]int
+	bufferBgs       map[nvim.Buffer]*RGBA
+	editorBg        *RGBA
+	diffColors      map[string]*RGBA
+	diffBackground  bool
+	diffConnectors  bool
+	connectorState  string
+	gutterSeparator bool
+	signSeparator   bool
+	borderAntialias string
+	controlChars    bool
+	specialKeyColor *RGBA
+	inlay           *inlayHints
+	hlStyles        []*hlStyle
+	cursorWord      *CursorWord
+	lineMarker      *LineMarker
+	hoverTargets    []hoverTarget
+	hoverShape      core.Qt__CursorShape
+	mouseIBeam      bool
+	lineCache       lineCache
+	smooth          smoothScroll
+	context         contextMenu
+	zoom            windowZoom
+	grids           multigrid
+	fontZoom        fontZoom
+	cursorNr        cursorLineNr
+	foldColumn      bool
+	foldSummary     bool
+	foldAnim        foldAnimation
+	hasStatuscol    bool
+	foldedFg        *RGBA
+	fade            marginFade
+	twDim           textwidthDim
+	textContrast    float64
+	focusOutline    bool
+	outlineColor    *RGBA
+	outlineWidth    int
+	focusRect       [5]int
+	dimInactive     bool
+	quickfixShading bool
+	quickfixState   string
+	pastedSelection bool
+	gridLock        [2]int
+	resizeTimer     *core.QTimer
+	resizedUI       bool
 	tooltip         *widgets.QLabel
+	tooltipText     string
+	tooltipFitWidth int
 }
 
 func newScreen() *Screen {
@@ -68,108 +154,295 @@ func newScreen() *Screen {
 		cursor:       [2]int{0, 0},
 		lastCursor:   [2]int{0, 0},
 		scrollRegion: []int{0, 0, 0, 0},
+		bufferBgs:    map[nvim.Buffer]*RGBA{},
+		textContrast: 1,
 		tooltip:      tooltip,
 	}
+	screen.cursorWord = newCursorWord(screen)
+	screen.cursorNr.row = -1
+	screen.clampRefresh = [2]int{-1, -1}
+	screen.smooth = newSmoothScroll()
+	screen.lineMarker = newLineMarker(screen)
+	screen.addHoverTarget(screen.foldHover)
 	widget.ConnectPaintEvent(screen.paint)
 	widget.ConnectMousePressEvent(screen.mouseEvent)
 	widget.ConnectMouseReleaseEvent(screen.mouseEvent)
 	widget.ConnectMouseMoveEvent(screen.mouseEvent)
+	widget.ConnectWheelEvent(screen.wheelEvent)
 	widget.ConnectResizeEvent(func(event *gui.QResizeEvent) {
 		screen.updateSize()
 	})
 	widget.SetAttribute(core.Qt__WA_KeyCompression, false)
+	widget.SetMouseTracking(true)
 
 	return screen
 }
 
 func (s *Screen) updateSize() {
 	w := s.ws
+	s.lineCache.invalidate()
 	s.width = s.widget.Width()
 	cols := int(float64(s.width) / w.font.truewidth)
 	rows := s.height / w.font.lineHeight
-
-	if w.uiAttached {
-		if cols != w.cols || rows != w.rows {
-			w.nvim.TryResizeUI(cols, rows)
-		}
+	if s.gridLock[0] > 0 {
+		cols, rows = s.lockSize()
 	}
+
+	changed := cols != w.cols || rows != w.rows
 	w.cols = cols
 	w.rows = rows
+	if w.uiAttached && changed {
+		s.resizeUI()
+	}
+}
+
+// resizeUI sends the grid size to neovim. Dragging the window edge resizes
+// it over and over, so apart from the first resize, which is sent at once,
+// only the size it settles on is sent, resizeDelay after the last change.
+// The size used for the layout changes right away.
+func (s *Screen) resizeUI() {
+	if !s.resizedUI {
+		s.resizedUI = true
+		s.ws.nvim.TryResizeUI(s.ws.cols, s.ws.rows)
+		return
+	}
+	if s.resizeTimer == nil {
+		s.resizeTimer = core.NewQTimer(nil)
+		s.resizeTimer.SetSingleShot(true)
+		s.resizeTimer.ConnectTimeout(func() {
+			s.ws.nvim.TryResizeUI(s.ws.cols, s.ws.rows)
+		})
+	}
+	s.resizeTimer.Start(resizeDelay)
 }
 
 func (s *Screen) toolTipFont(font *Font) {
 	s.tooltip.SetFont(font.fontNew)
 	s.tooltip.SetContentsMargins(0, font.lineSpace/2, 0, font.lineSpace/2)
+	s.tooltipFitWidth = 0
 }
 
 func (s *Screen) toolTip(text string) {
-	s.tooltip.SetText(text)
-	s.tooltip.AdjustSize()
+	if text != s.tooltipText {
+		s.tooltipText = text
+		s.tooltip.SetText(text)
+		s.tooltipFitWidth = 0
+	}
 	s.tooltip.Show()
-
-	row := s.cursor[0]
-	col := s.cursor[1]
+	row, col := s.clampCursor(s.cursor[0], s.cursor[1])
 	c := s.ws.cursor
-	c.x = int(float64(col)*s.ws.font.truewidth) + s.tooltip.Width()
-	c.y = row * s.ws.font.lineHeight
+	c.x, c.y = s.placeToolTip(row, col)
 	c.move()
 }
 
+// fitToolTip sizes the tooltip to its text. Text wider than the screen is
+// wrapped at the screen width.
+func (s *Screen) fitToolTip(screenWidth int) {
+	s.tooltip.SetWordWrap(false)
+	s.tooltip.AdjustSize()
+	if s.tooltip.Width() > screenWidth {
+		s.tooltip.SetWordWrap(true)
+		s.tooltip.Resize2(screenWidth, s.tooltip.HeightForWidth(screenWidth))
+	}
+}
+
+// placeToolTip puts the tooltip at the cell and returns the x and y of the
+// cursor, which goes after it. The tooltip is measured again only when its
+// text, its font or the screen width changed. It is kept within the
+// screen: it is moved left from the right edge, and above the cursor line
+// when it would go past the bottom.
+func (s *Screen) placeToolTip(row, col int) (int, int) {
+	font := s.ws.font
+	screenWidth := s.widget.Width()
+	screenHeight := s.widget.Height()
+	if s.tooltipFitWidth != screenWidth {
+		s.tooltipFitWidth = screenWidth
+		s.fitToolTip(screenWidth)
+	}
+	width := s.tooltip.Width()
+	height := s.tooltip.Height()
+
+	x := font.cellX(col)
+	y := row * font.lineHeight
+	if x+width > screenWidth {
+		x = maxInt(screenWidth-width, 0)
+	}
+	if y+height > screenHeight {
+		y = maxInt(y-height, 0)
+	}
+	s.tooltip.Move2(x, y)
+
+	return minInt(x+width, screenWidth-s.ws.cursor.width), maxInt(y+height-font.lineHeight, 0)
+}
+
 func (s *Screen) paint(vqp *gui.QPaintEvent) {
 	s.paintMutex.Lock()
 	defer s.paintMutex.Unlock()
 
+	start := time.Now()
 	rect := vqp.M_rect()
 	font := s.ws.font
-	top := rect.Y()
-	left := rect.X()
-	width := rect.Width()
-	height := rect.Height()
-	right := left + width
-	bottom := top + height
-	row := int(float64(top) / float64(font.lineHeight))
-	col := int(float64(left) / font.truewidth)
-	rows := int(math.Ceil(float64(bottom)/float64(font.lineHeight))) - row
-	cols := int(math.Ceil(float64(right)/font.truewidth)) - col
+	row, col, rows, cols := s.rectCells(rect)
 
 	p := gui.NewQPainter2(s.widget)
-	if s.ws.background != nil {
-		p.FillRect5(
-			left,
-			top,
-			width,
-			height,
-			s.ws.background.QColor(),
-		)
+	background := s.ws.background
+	if s.editorBg != nil {
+		background = s.editorBg
 	}
-
 	p.SetFont(font.fontNew)
 
-	for y := row; y < row+rows; y++ {
-		if y >= s.ws.rows {
-			continue
+	// only the rows queued as dirty are in the region, the rest of the
+	// bounding rect is clipped anyway
+	rects := vqp.Region().Rects()
+	if len(rects) == 0 {
+		rects = []*core.QRect{rect}
+	}
+	area := 0
+	for _, r := range rects {
+		area += r.Width() * r.Height()
+		s.fillBackground(p, r.X(), r.Y(), r.Width(), r.Height(), background)
+		rRow, rCol, rRows, rCols := s.rectCells(r)
+		for y := rRow; y < rRow+rRows; y++ {
+			if y >= s.ws.rows {
+				continue
+			}
+			s.fillHightlight(p, y, rCol, rCols, [2]int{0, 0})
+			s.fillQuickfix(p, y, rCol, rCols)
+			s.cursorWord.fill(p, y, rCol, rCols)
+			s.drawLine(p, y, rCol, rCols)
 		}
-		s.fillHightlight(p, y, col, cols, [2]int{0, 0})
-		s.drawText(p, y, col, cols, [2]int{0, 0})
 	}
+	s.drawSmoothScroll(p)
+	s.drawCursorNr(p)
+	s.drawZoom(p)
 
 	s.drawBorder(p, row, col, rows, cols)
+	s.drawFloatShadows(p)
+	s.drawDiffGutter(p, row, rows)
+	s.drawDiffConnectors(p, row, rows)
+	s.drawFoldSummary(p, row, rows)
+	s.drawFoldColumn(p, row, rows)
+	s.drawTextwidthDim(p)
+	s.drawMarginFade(p)
+	s.checkFocus()
+	s.drawInactiveDim(p)
+	s.drawFocusOutline(p)
+	s.lineMarker.draw(p, row, rows)
+	s.checkQuickfix()
+	s.checkMarginFade()
+	s.ws.stats.draw(p)
 	p.DestroyQPainter()
+	s.ws.stats.paintDone(start, area)
+	s.ws.latency.paintDone()
 	s.ws.markdown.updatePos()
+	if editor.splash.waiting() && s.hasContent() {
+		editor.splash.fadeOut()
+	}
+}
+
+// rectCells returns the rows and columns of cells the rect touches
+func (s *Screen) rectCells(rect *core.QRect) (int, int, int, int) {
+	font := s.ws.font
+	right := rect.X() + rect.Width()
+	bottom := rect.Y() + rect.Height()
+	row := int(float64(rect.Y()) / float64(font.lineHeight))
+	col := int(float64(rect.X()) / font.truewidth)
+	rows := int(math.Ceil(float64(bottom)/float64(font.lineHeight))) - row
+	cols := int(math.Ceil(float64(right)/font.truewidth)) - col
+	return row, col, rows, cols
+}
+
+// hasContent is true once anything but blanks is on the screen
+func (s *Screen) hasContent() bool {
+	for _, line := range s.content {
+		for _, char := range line {
+			if char != nil && char.char != "" && char.char != " " {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (s *Screen) mouseEvent(event *gui.QMouseEvent) {
+	if event.Type() == core.QEvent__MouseMove {
+		s.hover(event)
+	}
+	if s.contextClick(event) {
+		return
+	}
+	if event.Type() == core.QEvent__MouseButtonPress && s.foldClick(event) {
+		return
+	}
 	inp := s.convertMouse(event)
 	if inp == "" {
 		return
 	}
-	s.ws.nvim.Input(inp)
+	s.ws.input(inp)
+}
+
+func (s *Screen) wheelEvent(event *gui.QWheelEvent) {
+	col, row := s.pointCell(event.X(), event.Y())
+	mod := editor.modPrefix(event.Modifiers())
+	delta := event.AngleDelta()
+	s.wheelDelta[0] += delta.X()
+	s.wheelDelta[1] += delta.Y()
+	hSteps := s.wheelDelta[0] / 120
+	vSteps := s.wheelDelta[1] / 120
+	s.wheelDelta[0] %= 120
+	s.wheelDelta[1] %= 120
+
+	if event.Modifiers()&core.Qt__ControlModifier != 0 {
+		if vSteps != 0 {
+			s.zoomFont(vSteps)
+		}
+		return
+	}
+	for ; vSteps > 0; vSteps-- {
+		s.ws.input(fmt.Sprintf("<%sScrollWheelUp><%d,%d>", mod, col, row))
+	}
+	pastEnd := s.rowsPastEnd(col, row)
+	for ; vSteps < 0; vSteps++ {
+		if s.scrollPastEnd >= 0 && pastEnd >= 0 {
+			if pastEnd >= s.scrollPastEnd {
+				break
+			}
+			pastEnd += wheelLines
+		}
+		s.ws.input(fmt.Sprintf("<%sScrollWheelDown><%d,%d>", mod, col, row))
+	}
+	for ; hSteps > 0; hSteps-- {
+		s.ws.input(fmt.Sprintf("<%sScrollWheelLeft><%d,%d>", mod, col, row))
+	}
+	for ; hSteps < 0; hSteps++ {
+		s.ws.input(fmt.Sprintf("<%sScrollWheelRight><%d,%d>", mod, col, row))
+	}
+}
+
+// rowsPastEnd returns how many rows of the window under the mouse are below
+// the last line of its buffer, going by its top line and line count, so
+// wrapped lines and folds are not counted. The wheel scrolls neovim until
+// only the last line is left; with g:gonvim_scroll_past_end set to a number
+// of rows it stops once that many are past the end. It is -1 when the window
+// isn't known.
+//
+// Scrolling past the end is left to neovim, there is no paint offset on the
+// GUI side. Neovim already scrolls a window until its last line is at the
+// top, which is the one screen of room past the end, with the rows below
+// drawn as EndOfBuffer (blank with fillchars+=eob:\ ), and those scrolls
+// glide with g:gonvim_smooth_scroll. An offset on top of that could only
+// move the last line off the screen, and would put the painted rows out of
+// step with neovim's grid, the cursor and the mouse.
+func (s *Screen) rowsPastEnd(col, row int) int {
+	win := s.windowAt(row, col)
+	if win == nil || win.topline == 0 {
+		return -1
+	}
+	return maxInt(win.height-(win.lines-win.topline+1), 0)
 }
 
 func (s *Screen) convertMouse(event *gui.QMouseEvent) string {
-	font := s.ws.font
-	x := int(float64(event.X()) / font.truewidth)
-	y := int(float64(event.Y()) / float64(font.lineHeight))
+	x, y := s.pointCell(event.X(), event.Y())
 	pos := []int{x, y}
 
 	bt := event.Button()
@@ -193,6 +466,9 @@ func (s *Screen) convertMouse(event *gui.QMouseEvent) string {
 	case core.Qt__RightButton:
 		buttonName += "Right"
 	case core.Qt__MidButton:
+		if s.pasteSelection(event, x, y) {
+			return ""
+		}
 		buttonName += "Middle"
 	case core.Qt__NoButton:
 	default:
@@ -217,15 +493,6 @@ func (s *Screen) convertMouse(event *gui.QMouseEvent) string {
 }
 
 func (s *Screen) drawBorder(p *gui.QPainter, row, col, rows, cols int) {
-	done := make(chan struct{})
-	go func() {
-		s.getWindows()
-		close(done)
-	}()
-	select {
-	case <-done:
-	case <-time.After(50 * time.Millisecond):
-	}
 	for _, win := range s.curWins {
 		if win.pos[0]+win.height < row && (win.pos[1]+win.width+1) < col {
 			continue
@@ -234,66 +501,310 @@ func (s *Screen) drawBorder(p *gui.QPainter, row, col, rows, cols int) {
 			continue
 		}
 
-		win.drawBorder(p, s)
+		if !win.float {
+			win.drawBorder(p, s)
+		}
+		win.drawGutterSeparator(p, s)
+		win.drawSignSeparator(p, s)
+	}
+}
+
+// clampCursor keeps the cursor inside the focused window. When the cursor
+// moved to another window, or is outside of every known window, the
+// geometry may be from before a split or a <C-w> movement, so it is fetched
+// again in the background and the cursor placed again once it is there.
+// Until then the known geometry is used: if the cursor is outside of every
+// window it stays in the window it was last in.
+func (s *Screen) clampCursor(row, col int) (int, int) {
+	win := s.windowAt(row, col)
+	if win != nil && win.win == s.cursorWinID {
+		return row, col
+	}
+	if row >= s.ws.rows-s.cmdheight {
+		return row, col
+	}
+	if s.clampRefresh != [2]int{row, col} {
+		s.clampRefresh = [2]int{row, col}
+		s.windowsCursor = true
+		s.fetchWindows()
+	}
+	if win != nil {
+		s.cursorWinID = win.win
+		return row, col
+	}
+	focused, ok := s.curWins[s.cursorWinID]
+	if !ok {
+		return row, col
+	}
+	return focused.clamp(row, col)
+}
+
+// clamp moves the cell to the nearest cell of the window
+func (w *Window) clamp(row, col int) (int, int) {
+	row = clampInt(row, w.pos[0], w.pos[0]+w.height-1)
+	col = clampInt(col, w.pos[1], w.pos[1]+w.width-1)
+	return row, col
+}
+
+// windowAt returns the window whose text area has the cell
+func (s *Screen) windowAt(row, col int) *Window {
+	for _, win := range s.curWins {
+		if row >= win.pos[0] && row < win.pos[0]+win.height && col >= win.pos[1] && col < win.pos[1]+win.width {
+			return win
+		}
 	}
+	return nil
+}
+
+// windowLayout is the window geometry and options getWindows gets
+type windowLayout struct {
+	wins      map[nvim.Window]*Window
+	curtab    nvim.Tabpage
+	cmdheight int
 }
 
-func (s *Screen) getWindows() {
+// fetchWindows gets the window geometry in the background after a redraw
+// batch, and when the cursor is out of the known windows, so painting
+// never waits on neovim for it. A fetch asked for while one is running is
+// made once that one is in. It is called on the GUI thread, and the layout
+// is set by setWindows on the GUI thread too, so curWins is only ever
+// written there.
+func (s *Screen) fetchWindows() {
+	if s.windowsFetching {
+		s.windowsRefetch = true
+		return
+	}
+	s.windowsFetching = true
+	go func() {
+		layout := s.getWindows()
+		s.ws.guiUpdates <- []interface{}{"gonvim_windows", layout}
+		s.ws.signal.GuiSignal()
+	}()
+}
+
+// setWindows takes the layout fetchWindows got. The screen is repainted
+// when the windows changed, since their borders and the rest drawn from
+// them were painted with the old ones.
+func (s *Screen) setWindows(layout *windowLayout) {
+	s.windowsFetching = false
+	if s.windowsRefetch {
+		s.windowsRefetch = false
+		s.fetchWindows()
+	}
+	if layout == nil {
+		return
+	}
+	s.curtab = layout.curtab
+	s.cmdheight = layout.cmdheight
+	s.grids.placeWindows(layout.wins)
+	changed := !reflect.DeepEqual(s.curWins, layout.wins)
+	s.curWins = layout.wins
+	if changed {
+		s.widget.Update()
+		s.checkZoom()
+		s.ws.crumbs.move()
+	}
+	if changed || s.windowsCursor {
+		s.windowsCursor = false
+		s.ws.cursor.update()
+	}
+}
+
+func (s *Screen) getWindows() *windowLayout {
 	wins := map[nvim.Window]*Window{}
 	neovim := s.ws.nvim
 	curtab, _ := neovim.CurrentTabpage()
-	s.curtab = curtab
 	nwins, _ := neovim.TabpageWindows(curtab)
 	b := neovim.NewBatch()
+	foldcolumns := map[nvim.Window]*interface{}{}
+	configs := map[nvim.Window]*map[string]interface{}{}
 	for _, nwin := range nwins {
 		win := &Window{
-			win: nwin,
+			win:    nwin,
+			numcol: -1,
 		}
 		b.WindowWidth(nwin, &win.width)
 		b.WindowHeight(nwin, &win.height)
 		b.WindowPosition(nwin, &win.pos)
 		b.WindowTabpage(nwin, &win.tab)
+		b.WindowBuffer(nwin, &win.buf)
+		b.WindowOption(nwin, "diff", &win.diff)
+		b.WindowOption(nwin, "rightleft", &win.rightleft)
+		b.WindowOption(nwin, "winhl", &win.hl)
+		if s.foldColumn || s.foldSummary || s.signSeparator {
+			var foldcolumn interface{}
+			foldcolumns[nwin] = &foldcolumn
+			b.WindowOption(nwin, "foldcolumn", &foldcolumn)
+			if s.hasStatuscol {
+				b.WindowOption(nwin, "statuscolumn", &win.statuscol)
+			}
+		}
+		if s.grids.enabled {
+			var config map[string]interface{}
+			configs[nwin] = &config
+			b.Call("nvim_win_get_config", &config, nwin)
+		}
+		if s.dimInactive || s.grids.enabled {
+			b.WindowOption(nwin, "winblend", &win.blend)
+		}
 		wins[nwin] = win
 	}
-	b.Option("cmdheight", &s.cmdheight)
+	cmdheight := 0
+	b.Option("cmdheight", &cmdheight)
 	err := b.Execute()
 	if err != nil {
+		return nil
+	}
+	for nwin, foldcolumn := range foldcolumns {
+		wins[nwin].foldcolumn = parseFoldColumn(*foldcolumn)
+	}
+	for nwin, config := range configs {
+		relative, _ := (*config)["relative"].(string)
+		wins[nwin].float = relative != ""
+	}
+
+	b = neovim.NewBatch()
+	normalBgs := map[nvim.Window]*string{}
+	for _, win := range wins {
+		b.BufferName(win.buf, &win.bufName)
+		b.BufferOption(win.buf, "buftype", &win.bufType)
+		win.statusline = win.height+win.pos[0] < s.ws.rows-cmdheight
+		for _, part := range strings.Split(win.hl, ",") {
+			if strings.HasPrefix(part, "Normal:") {
+				var bg string
+				normalBgs[win.win] = &bg
+				b.Eval(fmt.Sprintf("synIDattr(hlID('%s'), 'bg')", part[7:]), &bg)
+			}
+		}
+	}
+	var infos []map[string]interface{}
+	if s.gutterSeparator || s.signSeparator || s.cursorNr.enabled || s.quickfixShading || s.fade.enabled || s.twDim.enabled || s.scrollPastEnd >= 0 || s.foldSummary || s.ws.crumbs.enabled {
+		b.Call("GonvimWinInfo", &infos, s.foldSummary, s.twDim.enabled)
+	}
+	err = b.Execute()
+	if err == nil {
+		for nwin, bg := range normalBgs {
+			if *bg != "" {
+				wins[nwin].bg = hexToRGBA(*bg)
+			}
+		}
+		s.setWinInfo(wins, infos)
+	}
+	return &windowLayout{
+		wins:      wins,
+		curtab:    curtab,
+		cmdheight: cmdheight,
+	}
+}
+
+// checkQuickfix repaints the screen when a quickfix window scrolled or its
+// current item changed, as the rows were painted before that was known
+func (s *Screen) checkQuickfix() {
+	if !s.quickfixShading {
 		return
 	}
-	s.curWins = wins
+	states := []string{}
 	for _, win := range s.curWins {
-		buf, _ := neovim.WindowBuffer(win.win)
-		win.bufName, _ = neovim.BufferName(buf)
+		if win.bufType == "quickfix" {
+			states = append(states, fmt.Sprintf("%d:%d:%d", win.win, win.topline, win.qfIdx))
+		}
+	}
+	sort.Strings(states)
+	state := strings.Join(states, ",")
+	if state != s.quickfixState {
+		s.quickfixState = state
+		s.widget.Update()
+	}
+}
 
-		if win.height+win.pos[0] < s.ws.rows-s.cmdheight {
-			win.statusline = true
-		} else {
-			win.statusline = false
-		}
-		neovim.WindowOption(win.win, "winhl", &win.hl)
-		if win.hl != "" {
-			parts := strings.Split(win.hl, ",")
-			for _, part := range parts {
-				if strings.HasPrefix(part, "Normal:") {
-					hl := part[7:]
-					result := ""
-					neovim.Eval(fmt.Sprintf("synIDattr(hlID('%s'), 'bg')", hl), &result)
-					if result != "" {
-						var r, g, b int
-						format := "#%02x%02x%02x"
-						n, err := fmt.Sscanf(result, format, &r, &g, &b)
-						if err != nil {
-							continue
-						}
-						if n != 3 {
-							continue
-						}
-						win.bg = newRGBA(r, g, b, 1)
-					}
-				}
+// fillQuickfix shades every other row of the quickfix windows and highlights
+// their current item, under the text
+func (s *Screen) fillQuickfix(p *gui.QPainter, y int, col int, cols int) {
+	if !s.quickfixShading {
+		return
+	}
+	for _, win := range s.curWins {
+		if win.bufType != "quickfix" || win.topline == 0 {
+			continue
+		}
+		if y < win.pos[0] || y >= win.pos[0]+win.height {
+			continue
+		}
+		start := win.pos[1]
+		if col > start {
+			start = col
+		}
+		end := win.pos[1] + win.width
+		if col+cols < end {
+			end = col + cols
+		}
+		if start >= end {
+			continue
+		}
+		lnum := win.topline + y - win.pos[0]
+		var color *gui.QColor
+		if lnum == win.qfIdx {
+			color = gui.NewQColor3(editor.selectedBg.R, editor.selectedBg.G, editor.selectedBg.B, 90)
+		} else if lnum%2 == 0 {
+			fg := s.ws.foreground
+			if fg == nil {
+				continue
 			}
+			color = gui.NewQColor3(fg.R, fg.G, fg.B, 12)
+		} else {
+			continue
+		}
+		p.FillRect5(
+			s.ws.font.cellX(start),
+			y*s.ws.font.lineHeight,
+			s.ws.font.cellsWidth(start, end),
+			s.ws.font.lineHeight,
+			color,
+		)
+	}
+}
+
+func (s *Screen) setBufferBg(args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	bufnr := reflectToInt(args[0])
+	arg, ok := args[0].(string)
+	if ok {
+		var err error
+		bufnr, err = strconv.Atoi(arg)
+		if err != nil {
+			return
+		}
+	}
+	buf := nvim.Buffer(bufnr)
+	color := ""
+	if len(args) > 1 {
+		color, _ = args[1].(string)
+	}
+	bg := hexToRGBA(color)
+	if bg == nil {
+		delete(s.bufferBgs, buf)
+	} else {
+		s.bufferBgs[buf] = bg
+	}
+	s.queueRedrawAll()
+	s.update()
+}
+
+// bufferBg returns the GonvimBufferBg override of the window at the cell,
+// if that window's buffer has one
+func (s *Screen) bufferBg(x, y int) *RGBA {
+	for _, win := range s.curWins {
+		bg, ok := s.bufferBgs[win.buf]
+		if !ok {
+			continue
+		}
+		if y >= win.pos[0] && y < win.pos[0]+win.height && x >= win.pos[1] && x < win.pos[1]+win.width {
+			return bg
 		}
 	}
+	return nil
 }
 
 func (s *Screen) updateBg(args []interface{}) {
@@ -304,6 +815,9 @@ func (s *Screen) updateBg(args []interface{}) {
 		bg := calcColor(reflectToInt(args[0]))
 		s.ws.background = bg
 	}
+	if s.gridLock[0] > 0 {
+		s.ws.letterbox()
+	}
 }
 
 func (s *Screen) size() (int, int) {
@@ -311,6 +825,32 @@ func (s *Screen) size() (int, int) {
 	return geo.Width(), geo.Height()
 }
 
+// handleGridEvent applies a redraw event of the grid to the content, and is
+// false for the other events. Recorded redraw logs are replayed through it.
+func (s *Screen) handleGridEvent(event string, args []interface{}) bool {
+	switch event {
+	case "cursor_goto":
+		s.cursorGoto(args)
+	case "put":
+		s.put(args)
+	case "eol_clear":
+		s.eolClear(args)
+	case "clear":
+		s.clear(args)
+	case "resize":
+		s.resize(args)
+	case "highlight_set":
+		s.highlightSet(args)
+	case "set_scroll_region":
+		s.setScrollRegion(args)
+	case "scroll":
+		s.scroll(args)
+	default:
+		return false
+	}
+	return true
+}
+
 func (s *Screen) resize(args []interface{}) {
 	s.cursor[0] = 0
 	s.cursor[1] = 0
@@ -318,6 +858,7 @@ func (s *Screen) resize(args []interface{}) {
 	for i := 0; i < s.ws.rows; i++ {
 		s.content[i] = make([]*Char, s.ws.cols)
 	}
+	s.lineCache.invalidate()
 	s.queueRedrawAll()
 }
 
@@ -328,6 +869,7 @@ func (s *Screen) clear(args []interface{}) {
 	for i := 0; i < s.ws.rows; i++ {
 		s.content[i] = make([]*Char, s.ws.cols)
 	}
+	s.lineCache.invalidate()
 	s.queueRedrawAll()
 }
 
@@ -343,6 +885,7 @@ func (s *Screen) eolClear(args []interface{}) {
 		line[x] = nil
 		numChars++
 	}
+	s.lineCache.invalidateRows(row, row)
 	s.queueRedraw(col, row, numChars+1, 1)
 }
 
@@ -399,6 +942,7 @@ func (s *Screen) put(args []interface{}) {
 	if !oldNormalWidth {
 		numChars++
 	}
+	s.lineCache.invalidateRows(row, row)
 	s.cursor[1] = col
 	if x > 0 {
 		char := line[x-1]
@@ -418,15 +962,6 @@ func (s *Screen) put(args []interface{}) {
 func (s *Screen) highlightSet(args []interface{}) {
 	for _, arg := range args {
 		hl := arg.([]interface{})[0].(map[string]interface{})
-		_, ok := hl["reverse"]
-		if ok {
-			highlight := Highlight{}
-			highlight.foreground = s.highlight.background
-			highlight.background = s.highlight.foreground
-			s.highlight = highlight
-			continue
-		}
-
 		highlight := Highlight{}
 		fg, ok := hl["foreground"]
 		if ok {
@@ -443,6 +978,24 @@ func (s *Screen) highlightSet(args []interface{}) {
 		} else {
 			highlight.background = s.ws.background
 		}
+
+		sp, ok := hl["special"]
+		if ok {
+			highlight.special = calcColor(reflectToInt(sp))
+		} else {
+			highlight.special = s.ws.special
+		}
+
+		if _, ok := hl["reverse"]; ok {
+			highlight.foreground, highlight.background = highlight.background, highlight.foreground
+			highlight.reverse = true
+		}
+		_, highlight.bold = hl["bold"]
+		_, highlight.italic = hl["italic"]
+		_, highlight.undercurl = hl["undercurl"]
+		_, highlight.underline = hl["underline"]
+		highlight.underdouble = hasUnderdouble(hl)
+		_, highlight.strike = hl["strikethrough"]
 		s.highlight = highlight
 	}
 }
@@ -473,7 +1026,13 @@ func (s *Screen) scroll(args []interface{}) {
 		right = s.ws.cols - 1
 	}
 
+	s.startSmoothScroll(top, bot, left, right, count)
 	s.queueRedraw(left, top, (right - left + 1), (bot - top + 1))
+	if left == 0 && right == s.ws.cols-1 {
+		s.lineCache.scroll(top, bot, count)
+	} else {
+		s.lineCache.invalidateRows(top, bot)
+	}
 
 	if count > 0 {
 		for row := top; row <= bot-count; row++ {
@@ -486,9 +1045,9 @@ func (s *Screen) scroll(args []interface{}) {
 				s.content[row][col] = nil
 			}
 		}
-		s.queueRedraw(left, (bot - count + 1), (right - left), count)
+		s.queueRedraw(left, (bot - count + 1), (right - left + 1), count)
 		if top > 0 {
-			s.queueRedraw(left, (top - count), (right - left), count)
+			s.queueRedraw(left, (top - count), (right - left + 1), count)
 		}
 	} else {
 		for row := bot; row >= top-count; row-- {
@@ -501,49 +1060,90 @@ func (s *Screen) scroll(args []interface{}) {
 				s.content[row][col] = nil
 			}
 		}
-		s.queueRedraw(left, top, (right - left), -count)
+		s.queueRedraw(left, top, (right - left + 1), -count)
 		if bot < s.ws.rows-1 {
-			s.queueRedraw(left, bot+1, (right - left), -count)
+			s.queueRedraw(left, bot+1, (right - left + 1), -count)
 		}
 	}
 }
 
+// update repaints the queued cells, merging the rows next to each other with
+// the same dirty columns into one rect
 func (s *Screen) update() {
-	x := s.queueRedrawArea[0]
-	y := s.queueRedrawArea[1]
-	width := s.queueRedrawArea[2] - x
-	height := s.queueRedrawArea[3] - y
-	if width > 0 && height > 0 {
-		// s.item.SetPixmap(s.pixmap)
-		s.widget.Update2(
-			int(float64(x)*s.ws.font.truewidth),
-			y*s.ws.font.lineHeight,
-			int(float64(width)*s.ws.font.truewidth),
-			height*s.ws.font.lineHeight,
-		)
+	if s.dirtyAll {
+		s.widget.Update()
+	} else {
+		for _, rect := range s.dirtyRects() {
+			s.widget.Update2(
+				s.ws.font.cellX(rect[0]),
+				rect[1]*s.ws.font.lineHeight,
+				s.ws.font.cellsWidth(rect[0], rect[0]+rect[2]),
+				rect[3]*s.ws.font.lineHeight,
+			)
+		}
+	}
+	s.dirtyAll = false
+	if len(s.dirtyLines) != s.ws.rows {
+		s.dirtyLines = make([][2]int, s.ws.rows)
+		return
+	}
+	for y := range s.dirtyLines {
+		s.dirtyLines[y] = [2]int{}
 	}
-	s.queueRedrawArea[0] = s.ws.cols
-	s.queueRedrawArea[1] = s.ws.rows
-	s.queueRedrawArea[2] = 0
-	s.queueRedrawArea[3] = 0
+}
+
+// dirtyRects returns the queued cells as col, row, cols, rows rects, one for
+// each run of rows with the same dirty columns
+func (s *Screen) dirtyRects() [][4]int {
+	rects := [][4]int{}
+	for y := 0; y < len(s.dirtyLines); {
+		span := s.dirtyLines[y]
+		if span[1] <= span[0] {
+			y++
+			continue
+		}
+		end := y + 1
+		for end < len(s.dirtyLines) && s.dirtyLines[end] == span {
+			end++
+		}
+		rects = append(rects, [4]int{span[0], y, span[1] - span[0], end - y})
+		y = end
+	}
+	return rects
 }
 
 func (s *Screen) queueRedrawAll() {
-	s.queueRedrawArea = [4]int{0, 0, s.ws.cols, s.ws.rows}
+	s.dirtyAll = true
 }
 
+// queueRedraw marks the cells dirty, as a span of columns for every row
 func (s *Screen) queueRedraw(x, y, width, height int) {
-	if x < s.queueRedrawArea[0] {
-		s.queueRedrawArea[0] = x
+	if s.dirtyAll || width <= 0 || height <= 0 {
+		return
 	}
-	if y < s.queueRedrawArea[1] {
-		s.queueRedrawArea[1] = y
+	if len(s.dirtyLines) != s.ws.rows {
+		s.dirtyAll = true
+		return
 	}
-	if (x + width) > s.queueRedrawArea[2] {
-		s.queueRedrawArea[2] = x + width
+	if x < 0 {
+		width += x
+		x = 0
 	}
-	if (y + height) > s.queueRedrawArea[3] {
-		s.queueRedrawArea[3] = y + height
+	for row := y; row < y+height && row < len(s.dirtyLines); row++ {
+		if row < 0 {
+			continue
+		}
+		span := &s.dirtyLines[row]
+		if span[1] <= span[0] {
+			*span = [2]int{x, x + width}
+			continue
+		}
+		if x < span[0] {
+			span[0] = x
+		}
+		if x+width > span[1] {
+			span[1] = x + width
+		}
 	}
 }
 
@@ -560,6 +1160,27 @@ func (s *Screen) cursorWin() *Window {
 	return s.posWin(s.cursor[1], s.cursor[0])
 }
 
+// widenToGlyphs widens the cells col to col+cols of the line to whole wide
+// glyphs. A wide char always fills exactly two cells, so it pulls in the
+// leading half when the cells start on its trailing cell and the trailing
+// half when they end on its leading cell.
+func widenToGlyphs(line []*Char, col, cols int) (int, int) {
+	if col > 0 && col-1 < len(line) {
+		char := line[col-1]
+		if char != nil && char.char != "" && !char.normalWidth {
+			col--
+			cols++
+		}
+	}
+	if last := col + cols - 1; last >= 0 && last < len(line)-1 {
+		char := line[last]
+		if char != nil && char.char != "" && !char.normalWidth {
+			cols++
+		}
+	}
+	return col, cols
+}
+
 func (s *Screen) fillHightlight(p *gui.QPainter, y int, col int, cols int, pos [2]int) {
 	rectF := core.NewQRectF()
 	screen := s.ws.screen
@@ -572,6 +1193,9 @@ func (s *Screen) fillHightlight(p *gui.QPainter, y int, col int, cols int, pos [
 	var lastBg *RGBA
 	var bg *RGBA
 	var lastChar *Char
+
+	col, cols = widenToGlyphs(line, col, cols)
+
 	for x := col; x < col+cols; x++ {
 		if x >= len(line) {
 			continue
@@ -585,6 +1209,23 @@ func (s *Screen) fillHightlight(p *gui.QPainter, y int, col int, cols int, pos [
 		if lastChar != nil && !lastChar.normalWidth {
 			bg = lastChar.highlight.background
 		}
+		if len(s.bufferBgs) > 0 && (bg == nil || (s.ws.background != nil && bg.equals(s.ws.background))) {
+			override := s.bufferBg(x, y)
+			if override != nil {
+				bg = override
+			}
+		}
+		if !s.diffBackground && s.diffGroup(bg) != "" && s.diffWin(x, y) {
+			bg = nil
+		}
+		// cells in the Normal background show the editor background instead
+		if s.editorBg != nil && bg != nil && s.ws.background != nil && bg.equals(s.ws.background) {
+			bg = nil
+		}
+		// and with g:gonvim_transparent the see-through background
+		if s.transparentBg(bg) {
+			bg = nil
+		}
 		if bg != nil {
 			if lastBg == nil {
 				start = x
@@ -596,9 +1237,9 @@ func (s *Screen) fillHightlight(p *gui.QPainter, y int, col int, cols int, pos [
 				} else {
 					// last bg is different; draw the previous and start a new one
 					rectF.SetRect(
-						float64(start-pos[1])*s.ws.font.truewidth,
+						float64(s.ws.font.cellX(start-pos[1])),
 						float64((y-pos[0])*s.ws.font.lineHeight),
-						float64(end-start+1)*s.ws.font.truewidth,
+						float64(s.ws.font.cellsWidth(start-pos[1], end-pos[1]+1)),
 						float64(s.ws.font.lineHeight),
 					)
 					p.FillRect4(
@@ -615,9 +1256,9 @@ func (s *Screen) fillHightlight(p *gui.QPainter, y int, col int, cols int, pos [
 		} else {
 			if lastBg != nil {
 				rectF.SetRect(
-					float64(start-pos[1])*s.ws.font.truewidth,
+					float64(s.ws.font.cellX(start-pos[1])),
 					float64((y-pos[0])*s.ws.font.lineHeight),
-					float64(end-start+1)*s.ws.font.truewidth,
+					float64(s.ws.font.cellsWidth(start-pos[1], end-pos[1]+1)),
 					float64(s.ws.font.lineHeight),
 				)
 				p.FillRect4(
@@ -635,9 +1276,9 @@ func (s *Screen) fillHightlight(p *gui.QPainter, y int, col int, cols int, pos [
 	}
 	if lastBg != nil {
 		rectF.SetRect(
-			float64(start-pos[1])*s.ws.font.truewidth,
+			float64(s.ws.font.cellX(start-pos[1])),
 			float64((y-pos[0])*s.ws.font.lineHeight),
-			float64(end-start+1)*s.ws.font.truewidth,
+			float64(s.ws.font.cellsWidth(start-pos[1], end-pos[1]+1)),
 			float64(s.ws.font.lineHeight),
 		)
 		p.FillRect4(
@@ -654,8 +1295,11 @@ func (s *Screen) drawText(p *gui.QPainter, y int, col int, cols int, pos [2]int)
 	}
 	pointF := core.NewQPointF()
 	line := screen.content[y]
-	chars := map[*RGBA][]int{}
+	chars := map[Highlight][]int{}
 	specialChars := []int{}
+	controlChars := []int{}
+	inlayChars := []int{}
+	rightLeftChars := []int{}
 	if col > 0 {
 		char := line[col-1]
 		if char != nil && char.char != "" {
@@ -681,6 +1325,20 @@ func (s *Screen) drawText(p *gui.QPainter, y int, col int, cols int, pos [2]int)
 		if char.char == "" {
 			continue
 		}
+		if isControlChar(char.char) {
+			controlChars = append(controlChars, x)
+			continue
+		}
+		if s.inlay != nil && s.isInlayHint(char) {
+			inlayChars = append(inlayChars, x)
+			continue
+		}
+		if isRTLChar(char.char) {
+			if win := s.windowAt(y, x); win != nil && win.rightleft {
+				rightLeftChars = append(rightLeftChars, x)
+				continue
+			}
+		}
 		if !char.normalWidth {
 			specialChars = append(specialChars, x)
 			continue
@@ -689,15 +1347,21 @@ func (s *Screen) drawText(p *gui.QPainter, y int, col int, cols int, pos [2]int)
 		if fg == nil {
 			fg = s.ws.foreground
 		}
-		colorSlice, ok := chars[fg]
+		bold, italic := s.fontStyle(&char.highlight)
+		highlight := Highlight{
+			foreground: fg,
+			bold:       bold,
+			italic:     italic,
+		}
+		colorSlice, ok := chars[highlight]
 		if !ok {
 			colorSlice = []int{}
 		}
 		colorSlice = append(colorSlice, x)
-		chars[fg] = colorSlice
+		chars[highlight] = colorSlice
 	}
 
-	for fg, colorSlice := range chars {
+	for highlight, colorSlice := range chars {
 		text := ""
 		slice := colorSlice[:]
 		for x := col; x < col+cols; x++ {
@@ -715,13 +1379,15 @@ func (s *Screen) drawText(p *gui.QPainter, y int, col int, cols int, pos [2]int)
 			}
 		}
 		if text != "" {
-			p.SetPen2(gui.NewQColor3(fg.R, fg.G, fg.B, int(fg.A*255)))
-			pointF.SetX(float64(col-pos[1]) * s.ws.font.truewidth)
+			fg := highlight.foreground
+			s.setFontStyle(p, highlight.bold, highlight.italic)
+			x := s.ws.font.textX(col - pos[1])
 			pointF.SetY(float64((y-pos[0])*s.ws.font.lineHeight + s.ws.font.shift))
-			p.DrawText(pointF, text)
+			s.drawRun(p, pointF, x, keepCellOrder(text), highlight.bold, fg)
 		}
 	}
 
+	s.drawRightLeftRuns(p, line, rightLeftChars, y, pos)
 	for _, x := range specialChars {
 		char := line[x]
 		if char == nil || char.char == " " {
@@ -731,10 +1397,289 @@ func (s *Screen) drawText(p *gui.QPainter, y int, col int, cols int, pos [2]int)
 		if fg == nil {
 			fg = s.ws.foreground
 		}
-		p.SetPen2(gui.NewQColor3(fg.R, fg.G, fg.B, int(fg.A*255)))
-		pointF.SetX(float64(x-pos[1]) * s.ws.font.truewidth)
+		bold, italic := s.fontStyle(&char.highlight)
+		s.setFontStyle(p, bold, italic)
+		pointF.SetX(s.ws.font.textX(x - pos[1]))
 		pointF.SetY(float64((y-pos[0])*s.ws.font.lineHeight + s.ws.font.shift))
-		p.DrawText(pointF, char.char)
+		s.drawGlyphs(p, pointF, char.char, fg)
+	}
+	s.setFontStyle(p, false, false)
+	s.drawControlChars(p, line, controlChars, y, pos)
+	s.drawInlayHints(p, line, inlayChars, y, pos)
+
+	s.drawDecorations(p, y, col, cols, pos)
+}
+
+// isControlChar is true for a cell holding a raw control character, which
+// neovim only sends when it doesn't show it in caret notation itself
+func isControlChar(char string) bool {
+	return len(char) == 1 && (char[0] < 0x20 || char[0] == 0x7f)
+}
+
+// drawControlChars draws the control characters in caret notation with the
+// SpecialKey color when GonvimControlChars is on, and leaves them blank
+// otherwise. Neovim gave each of them one cell, so the caret pair is drawn
+// squeezed into that cell.
+func (s *Screen) drawControlChars(p *gui.QPainter, line []*Char, xs []int, y int, pos [2]int) {
+	if !s.controlChars || len(xs) == 0 {
+		return
+	}
+	fg := s.specialKeyColor
+	if fg == nil {
+		fg = s.ws.foreground
+	}
+	if fg == nil {
+		return
+	}
+	pointF := core.NewQPointF3(0, float64(s.ws.font.shift))
+	for _, x := range xs {
+		c := line[x].char[0]
+		text := "^?"
+		if c < 0x20 {
+			text = "^" + string(rune(c+'@'))
+		}
+		p.Save()
+		p.Translate3(s.ws.font.textX(x-pos[1]), float64((y-pos[0])*s.ws.font.lineHeight))
+		p.Scale(0.5, 1)
+		s.drawGlyphs(p, pointF, text, fg)
+		p.Restore()
+	}
+}
+
+// setEditorBg handles GonvimEditorBg, a #rrggbb color for the editor area
+// behind the text in place of the Normal background, or no color to go back
+// to it. neovim's Normal isn't changed.
+func (s *Screen) setEditorBg(args []interface{}) {
+	color := ""
+	if len(args) > 0 {
+		color, _ = args[0].(string)
+	}
+	color = strings.TrimSpace(color)
+	var bg *RGBA
+	if color != "" {
+		bg = hexToRGBA(color)
+		if bg == nil {
+			go s.ws.nvim.Command(fmt.Sprintf("echomsg 'GonvimEditorBg: invalid color %s'", strings.Replace(color, "'", "''", -1)))
+			return
+		}
+	}
+	s.editorBg = bg
+	if s.gridLock[0] > 0 {
+		s.ws.letterbox()
+	}
+	s.queueRedrawAll()
+	s.update()
+}
+
+func (s *Screen) toggleControlChars(args []interface{}) {
+	controlChars := toggleArg(s.controlChars, args)
+	if controlChars == s.controlChars {
+		return
+	}
+	s.controlChars = controlChars
+	s.lineCache.invalidate()
+	s.queueRedrawAll()
+	s.update()
+}
+
+// drawRun draws the text of a run starting at x, squeezing a bold run whose
+// glyphs are wider than the cells
+func (s *Screen) drawRun(p *gui.QPainter, point *core.QPointF, x float64, text string, bold bool, fg *RGBA) {
+	scale := 1.0
+	if bold {
+		scale = s.ws.font.boldScale()
+	}
+	if scale >= 1 {
+		point.SetX(x)
+		s.drawGlyphs(p, point, text, fg)
+		return
+	}
+	// a bold glyph wider than the cell would push the rest of the run
+	// over the cells after it
+	p.Save()
+	p.Translate3(x, 0)
+	p.Scale(scale, 1)
+	point.SetX(0)
+	s.drawGlyphs(p, point, text, fg)
+	p.Restore()
+}
+
+// drawGlyphs draws the text applying g:gonvim_text_contrast, from 0.5 to
+// 1.5 with 1 being neutral. Qt has no gamma setting for glyph antialiasing, so
+// a contrast below 1 fades the glyphs and above 1 draws them a second time to
+// thicken their edges. How much that shows depends on the platform's font
+// rasterizer, and with subpixel antialiasing the color fringes get heavier too.
+func (s *Screen) drawGlyphs(p *gui.QPainter, point *core.QPointF, text string, fg *RGBA) {
+	contrast := s.textContrast
+	alpha := fg.A
+	if contrast < 1 {
+		alpha *= contrast
+	}
+	p.SetPen2(gui.NewQColor3(fg.R, fg.G, fg.B, int(alpha*255)))
+	p.DrawText(point, text)
+	if contrast > 1 {
+		p.SetPen2(gui.NewQColor3(fg.R, fg.G, fg.B, int(fg.A*(contrast-1)*255)))
+		p.DrawText(point, text)
+	}
+}
+
+func (s *Screen) setFontStyle(p *gui.QPainter, bold, italic bool) {
+	p.SetFont(s.ws.font.styleFont(bold, italic))
+}
+
+// drawDecorations draws the undercurls, underlines and double underlines of
+// the row in the special color of each cell, so e.g. SpellBad, SpellCap,
+// SpellRare and SpellLocal each show in their own guisp, and the
+// strikethroughs in the foreground unless the highlight has a guisp of its
+// own. A double width character's second cell has the same
+// highlight, so the lines cover both cells.
+func (s *Screen) drawDecorations(p *gui.QPainter, y int, col int, cols int, pos [2]int) {
+	s.drawDecorationRuns(p, y, col, cols, pos, func(hl *Highlight) bool { return hl.undercurl }, s.drawUndercurl)
+	s.drawDecorationRuns(p, y, col, cols, pos, func(hl *Highlight) bool { return hl.underline && !hl.undercurl && !hl.underdouble }, s.drawUnderline)
+	s.drawDecorationRuns(p, y, col, cols, pos, func(hl *Highlight) bool { return hl.underdouble && !hl.undercurl }, s.drawUnderdouble)
+	s.drawStrikethroughs(p, y, col, cols, pos)
+}
+
+func (s *Screen) drawStrikethroughs(p *gui.QPainter, y int, col int, cols int, pos [2]int) {
+	line := s.content[y]
+	font := s.ws.font
+	for x := col; x < col+cols && x < len(line); x++ {
+		char := line[x]
+		if char == nil || !char.highlight.strike {
+			continue
+		}
+		fg := s.strikeColor(&char.highlight)
+		if fg == nil {
+			continue
+		}
+		start := x
+		for x+1 < col+cols && x+1 < len(line) {
+			next := line[x+1]
+			if next == nil || !next.highlight.strike || !sameColor(s.strikeColor(&next.highlight), fg) {
+				break
+			}
+			x++
+		}
+		middle := (y-pos[0])*font.lineHeight + font.lineHeight/2
+		p.SetPen(gui.NewQPen3(fg.QColor()))
+		p.DrawLine3(
+			font.cellX(start-pos[1]),
+			middle,
+			font.cellX(x-pos[1]+1),
+			middle,
+		)
+	}
+}
+
+// strikeColor is the special color when the highlight sets one of its own,
+// and the foreground otherwise
+func (s *Screen) strikeColor(hl *Highlight) *RGBA {
+	if hl.special != nil && !sameColor(hl.special, s.ws.special) {
+		return hl.special
+	}
+	if hl.foreground != nil {
+		return hl.foreground
+	}
+	return s.ws.foreground
+}
+
+// drawDecorationRuns draws the runs of cells with the decoration, split where
+// the special color changes
+func (s *Screen) drawDecorationRuns(p *gui.QPainter, y int, col int, cols int, pos [2]int, has func(*Highlight) bool, draw func(*gui.QPainter, int, int, int, *RGBA)) {
+	line := s.content[y]
+	for x := col; x < col+cols && x < len(line); x++ {
+		char := line[x]
+		if char == nil || !has(&char.highlight) {
+			continue
+		}
+		start := x
+		sp := char.highlight.special
+		for x+1 < col+cols && x+1 < len(line) {
+			next := line[x+1]
+			if next == nil || !has(&next.highlight) {
+				break
+			}
+			if (next.highlight.special == nil) != (sp == nil) {
+				break
+			}
+			if sp != nil && !sp.equals(next.highlight.special) {
+				break
+			}
+			x++
+		}
+		color := sp
+		if color == nil {
+			color = char.highlight.foreground
+		}
+		if color == nil {
+			color = s.ws.foreground
+		}
+		draw(p, start-pos[1], x-pos[1]+1, y-pos[0], color)
+	}
+}
+
+// drawUnderline draws a straight line where drawUndercurl centers its curl
+func (s *Screen) drawUnderline(p *gui.QPainter, start, end, row int, color *RGBA) {
+	font := s.ws.font
+	left := font.cellX(start)
+	right := font.cellX(end)
+	y := row*font.lineHeight + font.shift + 2
+	p.SetPen(gui.NewQPen3(color.QColor()))
+	p.DrawLine3(left, y, right, y)
+}
+
+// drawUnderdouble draws two lines, the upper one where drawUnderline draws
+func (s *Screen) drawUnderdouble(p *gui.QPainter, start, end, row int, color *RGBA) {
+	font := s.ws.font
+	left := font.cellX(start)
+	right := font.cellX(end)
+	y := row*font.lineHeight + font.shift + 2
+	p.SetPen(gui.NewQPen3(color.QColor()))
+	p.DrawLine3(left, y, right, y)
+	p.DrawLine3(left, y+2, right, y+2)
+}
+
+// hasUnderdouble is true for the double underline of an attribute map, named
+// underlineline before neovim 0.8
+func hasUnderdouble(attrs map[string]interface{}) bool {
+	_, double := attrs["underdouble"]
+	_, lineline := attrs["underlineline"]
+	return double || lineline
+}
+
+// drawUndercurl draws the undercurl between the start and end columns in the
+// configured style. All styles are centered on the same line position.
+func (s *Screen) drawUndercurl(p *gui.QPainter, start, end, row int, color *RGBA) {
+	font := s.ws.font
+	left := font.cellX(start)
+	right := font.cellX(end)
+	y := row*font.lineHeight + font.shift + 2
+
+	pen := gui.NewQPen3(color.QColor())
+	switch s.undercurlStyle {
+	case "dotted":
+		pen.SetStyle(core.Qt__DotLine)
+		p.SetPen(pen)
+		p.DrawLine3(left, y, right, y)
+	case "dashed":
+		pen.SetStyle(core.Qt__DashLine)
+		p.SetPen(pen)
+		p.DrawLine3(left, y, right, y)
+	case "double":
+		p.SetPen(pen)
+		p.DrawLine3(left, y-1, right, y-1)
+		p.DrawLine3(left, y+1, right, y+1)
+	default:
+		p.SetPen(pen)
+		up := true
+		for x := left; x < right; x += 2 {
+			if up {
+				p.DrawLine3(x, y+1, x+2, y-1)
+			} else {
+				p.DrawLine3(x, y-1, x+2, y+1)
+			}
+			up = !up
+		}
 	}
 }
 
@@ -751,31 +1696,31 @@ func (w *Window) drawBorder(p *gui.QPainter, s *Screen) {
 		height++
 	}
 	p.FillRect5(
-		int(float64(w.pos[1]+w.width)*s.ws.font.truewidth),
+		s.ws.font.cellX(w.pos[1]+w.width),
 		w.pos[0]*s.ws.font.lineHeight,
-		int(s.ws.font.truewidth),
+		s.ws.font.cellsWidth(w.pos[1]+w.width, w.pos[1]+w.width+1),
 		height*s.ws.font.lineHeight,
 		gui.NewQColor3(bg.R, bg.G, bg.B, 255),
 	)
-	p.FillRect5(
-		int(float64(w.pos[1]+1+w.width)*s.ws.font.truewidth-1),
+	s.drawBorderLine(
+		p,
+		s.ws.font.cellX(w.pos[1]+1+w.width)-1,
 		w.pos[0]*s.ws.font.lineHeight,
 		1,
 		height*s.ws.font.lineHeight,
-		gui.NewQColor3(0, 0, 0, 255),
 	)
 
 	gradient := gui.NewQLinearGradient3(
-		(float64(w.width+w.pos[1])+1)*float64(s.ws.font.truewidth),
+		float64(s.ws.font.cellX(w.width+w.pos[1]+1)),
 		0,
-		(float64(w.width+w.pos[1])+1)*float64(s.ws.font.truewidth)-6,
+		float64(s.ws.font.cellX(w.width+w.pos[1]+1)-6),
 		0,
 	)
 	gradient.SetColorAt(0, gui.NewQColor3(10, 10, 10, 125))
 	gradient.SetColorAt(1, gui.NewQColor3(10, 10, 10, 0))
 	brush := gui.NewQBrush10(gradient)
 	p.FillRect2(
-		int((float64(w.width+w.pos[1])+1)*s.ws.font.truewidth)-6,
+		s.ws.font.cellX(w.width+w.pos[1]+1)-6,
 		w.pos[0]*s.ws.font.lineHeight,
 		6,
 		height*s.ws.font.lineHeight,
@@ -791,32 +1736,182 @@ func (w *Window) drawBorder(p *gui.QPainter, s *Screen) {
 	// )
 
 	if w.pos[0] > 0 {
-		p.FillRect5(
-			int(float64(w.pos[1])*s.ws.font.truewidth),
+		s.drawBorderLine(
+			p,
+			s.ws.font.cellX(w.pos[1]),
 			w.pos[0]*s.ws.font.lineHeight-1,
-			int(float64(w.width+1)*s.ws.font.truewidth),
+			s.ws.font.cellsWidth(w.pos[1], w.pos[1]+w.width+1),
 			1,
-			gui.NewQColor3(0, 0, 0, 255),
 		)
 	}
 	gradient = gui.NewQLinearGradient3(
-		float64(w.pos[1])*s.ws.font.truewidth,
+		float64(s.ws.font.cellX(w.pos[1])),
 		float64(w.pos[0]*s.ws.font.lineHeight),
-		float64(w.pos[1])*s.ws.font.truewidth,
+		float64(s.ws.font.cellX(w.pos[1])),
 		float64(w.pos[0]*s.ws.font.lineHeight+5),
 	)
 	gradient.SetColorAt(0, gui.NewQColor3(10, 10, 10, 125))
 	gradient.SetColorAt(1, gui.NewQColor3(10, 10, 10, 0))
 	brush = gui.NewQBrush10(gradient)
 	p.FillRect2(
-		int(float64(w.pos[1])*s.ws.font.truewidth),
+		s.ws.font.cellX(w.pos[1]),
 		w.pos[0]*s.ws.font.lineHeight,
-		int(float64(w.width+1)*s.ws.font.truewidth),
+		s.ws.font.cellsWidth(w.pos[1], w.pos[1]+w.width+1),
 		5,
 		brush,
 	)
 }
 
+// checkFocus tracks the focused window. When it or its size changes, the
+// outline and the dimming of the old and new focused windows are outside of
+// what neovim redraws, so the whole screen is repainted.
+func (s *Screen) checkFocus() {
+	if !s.focusOutline && !s.dimInactive {
+		return
+	}
+	win := s.cursorWin()
+	if win == nil {
+		return
+	}
+	rect := [5]int{int(win.win), win.pos[0], win.pos[1], win.width, win.height}
+	if rect != s.focusRect {
+		s.focusRect = rect
+		s.widget.Update()
+	}
+}
+
+// drawInactiveDim fades the windows other than the focused one toward the
+// background, by their 'winblend' or a light dim when it is not set
+func (s *Screen) drawInactiveDim(p *gui.QPainter) {
+	if !s.dimInactive || s.focusRect[3] == 0 {
+		return
+	}
+	bg := s.ws.background
+	if bg == nil {
+		return
+	}
+	font := s.ws.font
+	for _, win := range s.curWins {
+		if int(win.win) == s.focusRect[0] {
+			continue
+		}
+		blend := win.blend
+		if blend <= 0 {
+			blend = 15
+		}
+		if blend > 100 {
+			blend = 100
+		}
+		p.FillRect5(
+			font.cellX(win.pos[1]),
+			win.pos[0]*font.lineHeight,
+			font.cellsWidth(win.pos[1], win.pos[1]+win.width),
+			win.height*font.lineHeight,
+			gui.NewQColor3(bg.R, bg.G, bg.B, blend*255/100),
+		)
+	}
+}
+
+// drawFocusOutline draws the outline just inside the focused window, thin
+// enough to stay clear of the glyphs
+func (s *Screen) drawFocusOutline(p *gui.QPainter) {
+	if !s.focusOutline || s.focusRect[3] == 0 {
+		return
+	}
+	color := s.outlineColor
+	if color == nil {
+		color = newRGBA(81, 154, 186, 1)
+	}
+	qcolor := color.QColor()
+	width := s.outlineWidth
+	font := s.ws.font
+	x := font.cellX(s.focusRect[2])
+	y := s.focusRect[1] * font.lineHeight
+	w := font.cellsWidth(s.focusRect[2], s.focusRect[2]+s.focusRect[3])
+	h := s.focusRect[4] * font.lineHeight
+	p.FillRect5(x, y, w, width, qcolor)
+	p.FillRect5(x, y+h-width, w, width, qcolor)
+	p.FillRect5(x, y, width, h, qcolor)
+	p.FillRect5(x+w-width, y, width, h, qcolor)
+}
+
+// drawBorderLine draws a split border, a vertical line when width is 1 and a
+// horizontal one otherwise. With antialiased borders the line is one device
+// pixel thick, on the last device pixel of the logical pixel the hard-edged
+// line covers, so it lines up the same under scaling but stays crisp.
+func (s *Screen) drawBorderLine(p *gui.QPainter, x, y, width, height int) {
+	if !s.antialiasBorders() {
+		p.FillRect5(x, y, width, height, gui.NewQColor3(0, 0, 0, 255))
+		return
+	}
+	dpr := s.widget.DevicePixelRatioF()
+	offset := 1 - 0.5/dpr
+	pen := gui.NewQPen3(gui.NewQColor3(0, 0, 0, 255))
+	pen.SetWidthF(1 / dpr)
+	pen.SetCapStyle(core.Qt__FlatCap)
+	p.SetRenderHint(gui.QPainter__Antialiasing, true)
+	p.SetPen(pen)
+	if width == 1 {
+		p.DrawLine(core.NewQLineF3(float64(x)+offset, float64(y), float64(x)+offset, float64(y+height)))
+	} else {
+		p.DrawLine(core.NewQLineF3(float64(x), float64(y)+offset, float64(x+width), float64(y)+offset))
+	}
+	p.SetRenderHint(gui.QPainter__Antialiasing, false)
+}
+
+// antialiasBorders is g:gonvim_antialias_borders, which by default is on
+// for scaled displays only
+func (s *Screen) antialiasBorders() bool {
+	switch s.borderAntialias {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	return s.widget.DevicePixelRatioF() > 1
+}
+
+func (w *Window) drawGutterSeparator(p *gui.QPainter, s *Screen) {
+	if !s.gutterSeparator || w.textoff <= 0 || w.textoff >= w.width {
+		return
+	}
+	fg := s.ws.foreground
+	if fg == nil {
+		return
+	}
+	p.FillRect5(
+		s.ws.font.cellX(w.pos[1]+w.textoff)-1,
+		w.pos[0]*s.ws.font.lineHeight,
+		1,
+		w.height*s.ws.font.lineHeight,
+		gui.NewQColor3(fg.R, fg.G, fg.B, 40),
+	)
+}
+
+// drawSignSeparator draws a faint line between the sign column and the
+// number column with g:gonvim_sign_separator set. It's only drawn over the
+// cells, so clicks on the signs and numbers go where they did.
+func (w *Window) drawSignSeparator(p *gui.QPainter, s *Screen) {
+	// the sign column is between the fold and the number columns
+	if !s.signSeparator || w.numcol <= w.foldcolumn || w.numcol >= w.width {
+		return
+	}
+	fg := s.ws.foreground
+	if fg == nil {
+		return
+	}
+	p.FillRect5(
+		s.ws.font.cellX(w.pos[1]+w.numcol),
+		w.pos[0]*s.ws.font.lineHeight,
+		1,
+		w.height*s.ws.font.lineHeight,
+		gui.NewQColor3(fg.R, fg.G, fg.B, 30),
+	)
+}
+
+// isNormalWidth measures only the base character of the cell, neovim sends
+// combining characters in the same cell as the character they compose with
+// and they take no width of their own
 func (s *Screen) isNormalWidth(char string) bool {
 	if len(char) == 0 {
 		return true
@@ -824,5 +1919,12 @@ func (s *Screen) isNormalWidth(char string) bool {
 	if char[0] <= 127 {
 		return true
 	}
-	return s.ws.font.fontMetrics.Width(char) == s.ws.font.truewidth
+	return s.ws.font.fontMetrics.Width(baseChar(char)) == s.ws.font.glyphWidth
+}
+
+// baseChar returns the first character of the cell, without the combining
+// characters after it
+func baseChar(char string) string {
+	_, size := utf8.DecodeRuneInString(char)
+	return char[:size]
 }
@@ -0,0 +1,268 @@
+package editor
+
+import (
+	"math"
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+func TestRowsPastEnd(t *testing.T) {
+	s := &Screen{
+		curWins: map[nvim.Window]*Window{
+			1000: {win: 1000, pos: [2]int{0, 0}, width: 40, height: 20, topline: 95, lines: 100},
+			1001: {win: 1001, pos: [2]int{0, 41}, width: 40, height: 20, topline: 1, lines: 500},
+			1002: {win: 1002, pos: [2]int{21, 0}, width: 81, height: 10},
+		},
+	}
+	cases := []struct {
+		col, row int
+		rows     int
+	}{
+		{5, 5, 14},
+		{50, 5, 0},
+		{5, 25, -1},
+		{40, 5, -1},
+	}
+	for _, c := range cases {
+		rows := s.rowsPastEnd(c.col, c.row)
+		if rows != c.rows {
+			t.Errorf("rowsPastEnd(%d, %d) = %d, want %d", c.col, c.row, rows, c.rows)
+		}
+	}
+}
+
+func TestWidenToGlyphs(t *testing.T) {
+	// "a", "漢" over cells 1 and 2, "b", "字" over cells 4 and 5
+	line := []*Char{
+		{char: "a", normalWidth: true},
+		{char: "漢"},
+		{char: ""},
+		{char: "b", normalWidth: true},
+		{char: "字"},
+		{char: ""},
+	}
+	cases := []struct {
+		col, cols         int
+		wantCol, wantCols int
+	}{
+		{0, 1, 0, 1},
+		{2, 1, 1, 2},
+		{1, 1, 1, 2},
+		{2, 3, 1, 5},
+		{0, 6, 0, 6},
+		{5, 1, 4, 2},
+		{3, 1, 3, 1},
+	}
+	for _, c := range cases {
+		col, cols := widenToGlyphs(line, c.col, c.cols)
+		if col != c.wantCol || cols != c.wantCols {
+			t.Errorf("widenToGlyphs(%d, %d) = %d, %d, want %d, %d", c.col, c.cols, col, cols, c.wantCol, c.wantCols)
+		}
+	}
+}
+
+func TestHighlightSetStyle(t *testing.T) {
+	s := &Screen{ws: &Workspace{}}
+	cases := []struct {
+		attrs        map[string]interface{}
+		bold, italic bool
+	}{
+		{map[string]interface{}{}, false, false},
+		{map[string]interface{}{"bold": true}, true, false},
+		{map[string]interface{}{"italic": true}, false, true},
+		{map[string]interface{}{"bold": true, "italic": true, "foreground": int64(0xff0000)}, true, true},
+	}
+	for _, c := range cases {
+		s.highlightSet([]interface{}{[]interface{}{c.attrs}})
+		if s.highlight.bold != c.bold || s.highlight.italic != c.italic {
+			t.Errorf("highlightSet(%v): bold %v, italic %v", c.attrs, s.highlight.bold, s.highlight.italic)
+		}
+	}
+}
+
+func TestBaseChar(t *testing.T) {
+	cases := []struct {
+		char, base string
+	}{
+		{"", ""},
+		{"a", "a"},
+		{"e\u0301", "e"},
+		{"\u0915\u094d", "\u0915"},
+		{"漢", "漢"},
+		{"a\u0308\u0301", "a"},
+	}
+	for _, c := range cases {
+		base := baseChar(c.char)
+		if base != c.base {
+			t.Errorf("baseChar(%q) = %q, want %q", c.char, base, c.base)
+		}
+	}
+}
+
+func TestWindowClamp(t *testing.T) {
+	win := &Window{pos: [2]int{2, 10}, width: 30, height: 8}
+	cases := []struct {
+		row, col         int
+		wantRow, wantCol int
+	}{
+		{5, 20, 5, 20},
+		{0, 0, 2, 10},
+		{20, 50, 9, 39},
+		{9, 39, 9, 39},
+		{10, 40, 9, 39},
+		{1, 25, 2, 25},
+	}
+	for _, c := range cases {
+		row, col := win.clamp(c.row, c.col)
+		if row != c.wantRow || col != c.wantCol {
+			t.Errorf("clamp(%d, %d) = %d, %d, want %d, %d", c.row, c.col, row, col, c.wantRow, c.wantCol)
+		}
+	}
+}
+
+func TestWindowAt(t *testing.T) {
+	s := &Screen{
+		curWins: map[nvim.Window]*Window{
+			1000: {win: 1000, pos: [2]int{0, 0}, width: 40, height: 20},
+			1001: {win: 1001, pos: [2]int{0, 41}, width: 39, height: 20},
+		},
+	}
+	cases := []struct {
+		row, col int
+		win      nvim.Window
+	}{
+		{0, 0, 1000},
+		{19, 39, 1000},
+		{5, 40, 0},
+		{5, 41, 1001},
+		{20, 5, 0},
+	}
+	for _, c := range cases {
+		var id nvim.Window
+		if win := s.windowAt(c.row, c.col); win != nil {
+			id = win.win
+		}
+		if id != c.win {
+			t.Errorf("windowAt(%d, %d) = %d, want %d", c.row, c.col, id, c.win)
+		}
+	}
+}
+
+// newGridScreen returns a screen of the size with a blank grid and nothing
+// queued to repaint, as it is after the first paint
+func newGridScreen(rows, cols int) *Screen {
+	s := &Screen{
+		ws:           &Workspace{rows: rows, cols: cols},
+		scrollRegion: []int{0, 0, 0, 0},
+	}
+	s.resize(nil)
+	s.dirtyAll = false
+	s.dirtyLines = make([][2]int, rows)
+	return s
+}
+
+// gridPut writes the text at the row and column like neovim's cursor_goto
+// and put do
+func gridPut(s *Screen, row, col int, text string) {
+	s.handleGridEvent("cursor_goto", []interface{}{[]interface{}{int64(row), int64(col)}})
+	chars := []interface{}{}
+	for _, r := range text {
+		chars = append(chars, string(r))
+	}
+	s.handleGridEvent("put", []interface{}{chars})
+}
+
+// gridSetHighlight sets the attributes the next puts are drawn with like
+// neovim's highlight_set does
+func gridSetHighlight(s *Screen, hl map[string]interface{}) {
+	s.handleGridEvent("highlight_set", []interface{}{[]interface{}{hl}})
+}
+
+// neovim draws 'showbreak' as plain cells at the start of the continuation
+// row, after setting the NonText highlight
+func TestShowbreakCells(t *testing.T) {
+	s := newGridScreen(3, 10)
+	nonText := calcColor(0x5c6370)
+	gridSetHighlight(s, map[string]interface{}{})
+	gridPut(s, 0, 0, "a long lin")
+	gridSetHighlight(s, map[string]interface{}{"foreground": int64(0x5c6370)})
+	gridPut(s, 1, 0, ">> ")
+	gridSetHighlight(s, map[string]interface{}{})
+	s.handleGridEvent("put", []interface{}{[]interface{}{"e"}})
+
+	if text := string(s.gridText(false)); text != "a long lin\n>> e      \n          \n" {
+		t.Errorf("grid text %q", text)
+	}
+	for col := 0; col < 3; col++ {
+		if !sameColor(s.content[1][col].highlight.foreground, nonText) {
+			t.Errorf("showbreak cell %d not drawn with NonText", col)
+		}
+	}
+	if s.content[1][3].highlight.foreground != nil {
+		t.Errorf("the text after showbreak kept the NonText color")
+	}
+}
+
+// neovim resolves overlapping syntax, treesitter and semantic token
+// highlights itself and puts the cells again with the winning attributes
+func TestOverlappingHighlightRuns(t *testing.T) {
+	s := newGridScreen(1, 12)
+	keyword := calcColor(0xc678dd)
+	semantic := calcColor(0xe5c07b)
+	gridSetHighlight(s, map[string]interface{}{"foreground": int64(0xc678dd)})
+	gridPut(s, 0, 0, "function")
+	gridSetHighlight(s, map[string]interface{}{"foreground": int64(0xe5c07b), "bold": true})
+	gridPut(s, 0, 2, "nct")
+	gridSetHighlight(s, map[string]interface{}{})
+
+	for col := 0; col < 8; col++ {
+		hl := s.content[0][col].highlight
+		inner := col >= 2 && col < 5
+		want := keyword
+		if inner {
+			want = semantic
+		}
+		if !sameColor(hl.foreground, want) || hl.bold != inner {
+			t.Errorf("cell %d: foreground %v bold %v, want %v bold %v", col, hl.foreground, hl.bold, want, inner)
+		}
+	}
+	if text := string(s.gridText(false)); text != "function    \n" {
+		t.Errorf("grid text %q", text)
+	}
+}
+
+// moving the window between a Retina and a regular screen changes the
+// ratio, and the resize event that follows has to fit the grid to the line
+// height rounded for the new screen
+func TestResizeAcrossScreens(t *testing.T) {
+	s := newScreen()
+	s.ws = &Workspace{screen: s, font: initFontNew("Monospace", 13, 1)}
+	s.height = 600
+	s.widget.Resize2(800, s.height)
+	font := s.ws.font
+	rows := map[float64]int{}
+	for _, dpr := range []float64{1, 2, 1.25, 1.5, 1} {
+		// what screenChanged does with the new screen's ratio
+		font.setDevicePixelRatio(dpr)
+		size := core.NewQSize2(800, s.height)
+		core.QCoreApplication_SendEvent(s.widget, gui.NewQResizeEvent(size, size))
+
+		device := float64(font.lineHeight) * dpr
+		if math.Abs(device-math.Round(device)) > 0.01 {
+			t.Errorf("line height %d at ratio %v is %v device pixels", font.lineHeight, dpr, device)
+		}
+		if want := s.height / font.lineHeight; s.ws.rows != want {
+			t.Errorf("rows at ratio %v = %d, want %d", dpr, s.ws.rows, want)
+		}
+		if want := int(800 / font.truewidth); s.ws.cols != want {
+			t.Errorf("cols at ratio %v = %d, want %d", dpr, s.ws.cols, want)
+		}
+		if prev, ok := rows[dpr]; ok && prev != s.ws.rows {
+			t.Errorf("rows back at ratio %v = %d, were %d", dpr, s.ws.rows, prev)
+		}
+		rows[dpr] = s.ws.rows
+	}
+}
@@ -0,0 +1,238 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/svg"
+	"github.com/therecipe/qt/widgets"
+)
+
+const sidebarChanged = `call rpcnotify(0, "Gui", "gonvim_sidebar_changed", map(filter(getbufinfo({'buflisted': 1}), 'v:val.changed'), 'v:val.bufnr'))`
+
+// Sidebar is the vertical buffer list on the left of the screen. It shows
+// the buffers of the tabline data, or its tabs before neovim 0.5, which
+// doesn't send the buffers, so it needs the tabline, g:gonvim_draw_tabline.
+// Only the modified buffers are sent by an autocmd of its own.
+type Sidebar struct {
+	ws      *Workspace
+	widget  *widgets.QWidget
+	layout  *widgets.QVBoxLayout
+	items   []*SidebarItem
+	width   int
+	shown   bool
+	changed map[int]bool
+}
+
+// SidebarItem is a buffer, or a tab, in the sidebar
+type SidebarItem struct {
+	s        *Sidebar
+	widget   *widgets.QWidget
+	icon     *svg.QSvgWidget
+	label    *widgets.QLabel
+	modified *widgets.QLabel
+	command  string
+	fileType string
+	text     string
+	changed  bool
+	active   bool
+	hidden   bool
+}
+
+func initSidebar() *Sidebar {
+	layout := widgets.NewQVBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(0)
+	layout.AddStretch(1)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetContentsMargins(0, 0, 0, 0)
+	widget.SetLayout(layout)
+	widget.SetObjectName("sidebar")
+	widget.SetStyleSheet(`
+	QWidget#sidebar {
+		border-right: 1px solid rgba(0, 0, 0, 1);
+		background-color: rgba(24, 29, 34, 1);
+	}
+	* {
+		color: rgba(147, 161, 161, 1);
+	}
+	`)
+	widget.Hide()
+	return &Sidebar{
+		widget: widget,
+		layout: layout,
+		width:  200,
+	}
+}
+
+func (s *Sidebar) toggle(args []interface{}) {
+	shown := toggleArg(s.shown, args)
+	if shown == s.shown {
+		return
+	}
+	if shown && !s.ws.drawTabline {
+		go s.ws.nvim.Command(`echomsg "GonvimSidebar shows the tabline data and needs g:gonvim_draw_tabline"`)
+		return
+	}
+	s.shown = shown
+	if shown {
+		s.widget.SetFixedWidth(s.width)
+		s.widget.Show()
+		s.update()
+	} else {
+		s.widget.Hide()
+	}
+	go func() {
+		neovim := s.ws.nvim
+		neovim.Command("augroup GonvimSidebar | autocmd! | augroup END")
+		if !shown {
+			return
+		}
+		neovim.Command("autocmd GonvimSidebar BufEnter,BufWritePost,TextChanged,InsertLeave * " + sidebarChanged)
+		neovim.Command(sidebarChanged)
+	}()
+}
+
+// setChanged marks the buffers modified, from the gonvim_sidebar_changed
+// notification
+func (s *Sidebar) setChanged(args []interface{}) {
+	if len(args) < 1 {
+		return
+	}
+	bufs, _ := args[0].([]interface{})
+	s.changed = map[int]bool{}
+	for _, buf := range bufs {
+		s.changed[reflectToInt(buf)] = true
+	}
+	s.update()
+}
+
+// update lists the buffers, or the tabs, the tabline last got
+func (s *Sidebar) update() {
+	if !s.shown {
+		return
+	}
+	t := s.ws.tabline
+	count := 0
+	if len(t.Buffers) > 0 {
+		for _, buf := range t.Buffers {
+			item := s.item(count)
+			item.command = fmt.Sprintf("buffer %d", buf.ID)
+			item.setFile(buf.Name)
+			item.setChanged(s.changed[buf.ID])
+			item.setActive(buf.ID == t.CurrentBuf)
+			item.show()
+			count++
+		}
+	} else {
+		for _, tab := range t.Tabs[:t.tabCount] {
+			item := s.item(count)
+			item.command = fmt.Sprintf("call nvim_set_current_tabpage(%d)", tab.ID)
+			item.setFile(tab.fileText)
+			item.setChanged(false)
+			item.setActive(tab.ID == t.CurrentID)
+			item.show()
+			count++
+		}
+	}
+	for i := count; i < len(s.items); i++ {
+		s.items[i].hide()
+	}
+}
+
+// item returns the i'th item, adding items up to it
+func (s *Sidebar) item(i int) *SidebarItem {
+	for len(s.items) <= i {
+		s.items = append(s.items, s.newItem())
+	}
+	return s.items[i]
+}
+
+func (s *Sidebar) newItem() *SidebarItem {
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetContentsMargins(10, 8, 10, 8)
+	layout := widgets.NewQHBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(8)
+	icon := svg.NewQSvgWidget(nil)
+	icon.SetFixedSize2(14, 14)
+	label := widgets.NewQLabel(nil, 0)
+	modified := widgets.NewQLabel(nil, 0)
+	modified.SetText("●")
+	modified.Hide()
+	layout.AddWidget(icon, 0, 0)
+	layout.AddWidget(label, 1, 0)
+	layout.AddWidget(modified, 0, 0)
+	widget.SetLayout(layout)
+	s.layout.InsertWidget(len(s.items), widget, 0, 0)
+
+	item := &SidebarItem{
+		s:        s,
+		widget:   widget,
+		icon:     icon,
+		label:    label,
+		modified: modified,
+	}
+	setClickable(widget)
+	widget.ConnectMousePressEvent(func(event *gui.QMouseEvent) {
+		command := item.command
+		go s.ws.nvim.Command(command)
+	})
+	return item
+}
+
+func (i *SidebarItem) setFile(name string) {
+	text := filepath.Base(name)
+	if name == "" {
+		text = "[No Name]"
+	}
+	if text != i.text {
+		i.text = text
+		i.label.SetText(text)
+		i.widget.SetToolTip(name)
+	}
+	fileType := getFileType(name)
+	if fileType != i.fileType {
+		i.fileType = fileType
+		svgContent := i.s.ws.getSvg(fileType, nil)
+		i.icon.Load2(core.NewQByteArray2(svgContent, len(svgContent)))
+	}
+}
+
+func (i *SidebarItem) setChanged(changed bool) {
+	if changed == i.changed {
+		return
+	}
+	i.changed = changed
+	i.modified.SetVisible(changed)
+}
+
+func (i *SidebarItem) setActive(active bool) {
+	if active == i.active {
+		return
+	}
+	i.active = active
+	if active {
+		i.widget.SetStyleSheet(".QWidget {border-left: 3px solid rgba(81, 154, 186, 1); background-color: rgba(0, 0, 0, 1);} QWidget {color: rgba(212, 215, 214, 1);}")
+	} else {
+		i.widget.SetStyleSheet("")
+	}
+}
+
+func (i *SidebarItem) show() {
+	if !i.hidden {
+		return
+	}
+	i.hidden = false
+	i.widget.Show()
+}
+
+func (i *SidebarItem) hide() {
+	if i.hidden {
+		return
+	}
+	i.hidden = true
+	i.widget.Hide()
+}
@@ -0,0 +1,203 @@
+package editor
+
+import (
+	"math"
+	"time"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+const (
+	smoothScrollDuration     = 80 * time.Millisecond
+	smoothScrollJumpDuration = 120 * time.Millisecond
+	smoothScrollJumpDistance = 8
+	smoothScrollInterval     = 16
+)
+
+// smoothScroll animates the scroll events with g:gonvim_smooth_scroll set,
+// in typewriter mode and for the lines moved by a fold command with
+// g:gonvim_fold_animation set.
+// The content is scrolled at once as before and the scroll region is painted
+// with a pixel offset going from the scrolled distance to 0, over a pixmap of
+// the region drawn from the content before the scroll so the rows scrolled
+// out are still seen while they leave. A scroll arriving during the
+// animation draws the frame on the screen into the pixmap and carries on
+// from where it is. The pixmap is drawn like the rows, not grabbed from the
+// widget, so it doesn't paint the whole widget in the middle of a redraw
+// batch or take in the cursor and other child widgets.
+//
+// Jumps like Ctrl-D/U/F/B are set apart from small scrolls so they don't
+// drag: a scroll of at least g:gonvim_smooth_scroll_jump_lines rows (half the
+// region by default) takes g:gonvim_smooth_scroll_jump_duration ms and glides
+// over the last g:gonvim_smooth_scroll_jump_distance rows only (8, 0 glides
+// the whole jump), the rest of it is jumped at once. Small scrolls take
+// g:gonvim_smooth_scroll_duration ms. A duration of 0 turns the animation off.
+type smoothScroll struct {
+	timer  *core.QTimer
+	start  time.Time
+	length time.Duration
+	from   float64
+	offset float64
+	base   float64
+	pixmap *gui.QPixmap
+	region [4]int
+
+	duration     time.Duration
+	jumpDuration time.Duration
+	jumpLines    int
+	jumpDistance int
+}
+
+func newSmoothScroll() smoothScroll {
+	return smoothScroll{
+		duration:     smoothScrollDuration,
+		jumpDuration: smoothScrollJumpDuration,
+		jumpDistance: smoothScrollJumpDistance,
+	}
+}
+
+// animation returns how many of the count rows scrolled in a region of the
+// given rows are animated and for how long
+func (ss *smoothScroll) animation(count, rows int) (int, time.Duration) {
+	lines := ss.jumpLines
+	if lines <= 0 {
+		lines = rows / 2
+	}
+	distance := count
+	if distance < 0 {
+		distance = -distance
+	}
+	if lines <= 0 || distance < lines {
+		return count, ss.duration
+	}
+	if ss.jumpDistance > 0 && distance > ss.jumpDistance {
+		distance = ss.jumpDistance
+	}
+	if count < 0 {
+		distance = -distance
+	}
+	return distance, ss.jumpDuration
+}
+
+// startSmoothScroll is called by scroll before it moves the content
+func (s *Screen) startSmoothScroll(top, bot, left, right, count int) {
+	fold := s.foldShift()
+	if !(s.ws.smoothScroll || s.ws.typewriter || fold) || !s.widget.IsVisible() {
+		return
+	}
+	ss := &s.smooth
+	if ss.timer == nil {
+		ss.timer = core.NewQTimer(nil)
+		ss.timer.ConnectTimeout(s.smoothScrollTick)
+	}
+	region := [4]int{top, bot, left, right}
+	if ss.pixmap != nil && ss.region != region {
+		s.stopSmoothScroll()
+	}
+	distance, length := ss.animation(count, bot-top+1)
+	if fold {
+		distance, length = count, foldAnimationDuration
+		s.foldAnim.shifted = true
+	}
+	if length <= 0 {
+		s.stopSmoothScroll()
+		return
+	}
+	font := s.ws.font
+	x, y, width, height := s.smoothScrollRect(region)
+	if width <= 0 || height <= 0 {
+		return
+	}
+	// the pixmap is painted with the current offset, the frame on the screen
+	dpr := s.widget.DevicePixelRatioF()
+	pixmap := gui.NewQPixmap3(int(math.Ceil(float64(width)*dpr)), int(math.Ceil(float64(height)*dpr)))
+	pixmap.SetDevicePixelRatio(dpr)
+	ss.region = region
+	p := gui.NewQPainter2(pixmap)
+	p.SetFont(font.fontNew)
+	p.Translate3(float64(-x), float64(-y))
+	s.drawScrollFrame(p)
+	p.DestroyQPainter()
+	ss.pixmap = pixmap
+	from := ss.offset + float64(distance*font.lineHeight)
+	from = math.Max(math.Min(from, float64(height)), -float64(height))
+	ss.from = from
+	ss.base = from
+	ss.offset = from
+	ss.start = time.Now()
+	ss.length = length
+	ss.timer.Start(smoothScrollInterval)
+}
+
+func (s *Screen) smoothScrollRect(region [4]int) (int, int, int, int) {
+	font := s.ws.font
+	x := font.cellX(region[2])
+	y := region[0] * font.lineHeight
+	width := font.cellX(region[3]+1) - x
+	height := (region[1]+1)*font.lineHeight - y
+	return x, y, width, height
+}
+
+func (s *Screen) smoothScrollTick() {
+	ss := &s.smooth
+	progress := float64(time.Since(ss.start)) / float64(ss.length)
+	if progress >= 1 {
+		s.stopSmoothScroll()
+		return
+	}
+	eased := 1 - (1-progress)*(1-progress)
+	ss.offset = ss.from * (1 - eased)
+	s.widget.Update2(s.smoothScrollRect(ss.region))
+}
+
+func (s *Screen) stopSmoothScroll() {
+	ss := &s.smooth
+	if ss.timer != nil {
+		ss.timer.Stop()
+	}
+	if ss.pixmap == nil {
+		return
+	}
+	ss.pixmap = nil
+	ss.offset = 0
+	s.widget.Update2(s.smoothScrollRect(ss.region))
+}
+
+// drawSmoothScroll paints the scroll region over the rows painted in place
+func (s *Screen) drawSmoothScroll(p *gui.QPainter) {
+	if s.smooth.pixmap == nil {
+		return
+	}
+	s.drawScrollFrame(p)
+}
+
+// drawScrollFrame paints the scroll region as the animation shows it, the
+// pixmap shifted by how far the animation got and the rows at the offset.
+// Without an animation running it is just the rows.
+func (s *Screen) drawScrollFrame(p *gui.QPainter) {
+	ss := &s.smooth
+	top, bot, left, right := ss.region[0], ss.region[1], ss.region[2], ss.region[3]
+	x, y, width, height := s.smoothScrollRect(ss.region)
+	p.Save()
+	p.SetClipRect2(core.NewQRect4(x, y, width, height), core.Qt__IntersectClip)
+	if ss.pixmap != nil {
+		p.DrawPixmap(
+			core.NewQRectF4(float64(x), float64(y)+ss.offset-ss.base, float64(width), float64(height)),
+			ss.pixmap,
+			core.NewQRectF4(0, 0, float64(ss.pixmap.Width()), float64(ss.pixmap.Height())),
+		)
+	}
+	p.Translate3(0, ss.offset)
+	font := s.ws.font
+	background := s.ws.background
+	if s.editorBg != nil {
+		background = s.editorBg
+	}
+	for row := top; row <= bot && row < s.ws.rows; row++ {
+		s.fillBackground(p, x, row*font.lineHeight, width, font.lineHeight, background)
+		s.fillHightlight(p, row, left, right-left+1, [2]int{0, 0})
+		s.drawLine(p, row, left, right-left+1)
+	}
+	p.Restore()
+}
@@ -0,0 +1,41 @@
+package editor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSmoothScrollJumps(t *testing.T) {
+	ss := newSmoothScroll()
+	tests := []struct {
+		count, rows int
+		distance    int
+		duration    time.Duration
+	}{
+		{1, 40, 1, smoothScrollDuration},
+		{-3, 40, -3, smoothScrollDuration},
+		{19, 40, 19, smoothScrollDuration},
+		// Ctrl-D/U scroll half the window
+		{20, 40, smoothScrollJumpDistance, smoothScrollJumpDuration},
+		{-20, 40, -smoothScrollJumpDistance, smoothScrollJumpDuration},
+		// Ctrl-F/B scroll all of it
+		{38, 40, smoothScrollJumpDistance, smoothScrollJumpDuration},
+		{2, 4, 2, smoothScrollJumpDuration},
+	}
+	for _, test := range tests {
+		distance, duration := ss.animation(test.count, test.rows)
+		if distance != test.distance || duration != test.duration {
+			t.Errorf("count %d in %d rows: got %d rows over %v, want %d rows over %v",
+				test.count, test.rows, distance, duration, test.distance, test.duration)
+		}
+	}
+
+	ss.jumpLines = 10
+	ss.jumpDistance = 0
+	if distance, duration := ss.animation(12, 40); distance != 12 || duration != smoothScrollJumpDuration {
+		t.Errorf("uncapped jump: got %d rows over %v", distance, duration)
+	}
+	if _, duration := ss.animation(9, 40); duration != smoothScrollDuration {
+		t.Errorf("count below g:gonvim_smooth_scroll_jump_lines animated as a jump")
+	}
+}
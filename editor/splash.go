@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"os"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/widgets"
+)
+
+const (
+	splashTimeout  = 5000
+	splashInterval = 16
+	splashStep     = 0.1
+)
+
+// Splash covers the editor from the window showing up until a screen paints
+// real content, then fades out, so the blank screen while neovim starts isn't
+// seen. It is only a cover and doesn't hold anything back. Setting
+// GONVIM_NO_SPLASH turns it off. If nothing gets painted it goes away after
+// splashTimeout ms anyway.
+type Splash struct {
+	widget  *widgets.QWidget
+	effect  *widgets.QGraphicsOpacityEffect
+	timer   *core.QTimer
+	opacity float64
+	shown   bool
+	fading  bool
+}
+
+func initSplash(version string) *Splash {
+	if os.Getenv("GONVIM_NO_SPLASH") != "" {
+		return nil
+	}
+	logo := widgets.NewQLabel(nil, 0)
+	logo.SetText("Gonvim")
+	logo.SetStyleSheet("font-size: 36px; font-weight: bold;")
+	logo.SetAlignment(core.Qt__AlignCenter)
+	versionLabel := widgets.NewQLabel(nil, 0)
+	versionLabel.SetText(version)
+	versionLabel.SetStyleSheet("color: rgba(131, 131, 131, 1);")
+	versionLabel.SetAlignment(core.Qt__AlignCenter)
+	layout := widgets.NewQVBoxLayout()
+	layout.AddStretch(1)
+	layout.AddWidget(logo, 0, 0)
+	layout.AddWidget(versionLabel, 0, 0)
+	layout.AddStretch(1)
+	layout.SetSpacing(6)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetLayout(layout)
+	widget.SetStyleSheet(".QWidget {background-color: rgba(40, 44, 52, 1);} * {color: rgba(205, 211, 222, 1);}")
+	effect := widgets.NewQGraphicsOpacityEffect(nil)
+	effect.SetOpacity(1)
+	widget.SetGraphicsEffect(effect)
+	widget.Hide()
+	s := &Splash{
+		widget:  widget,
+		effect:  effect,
+		timer:   core.NewQTimer(nil),
+		opacity: 1,
+	}
+	s.timer.ConnectTimeout(s.tick)
+	return s
+}
+
+func (s *Splash) show() {
+	if s == nil {
+		return
+	}
+	s.shown = true
+	s.widget.Show()
+	s.widget.Raise()
+	s.timer.Start(splashTimeout)
+}
+
+func (s *Splash) resize(width, height int) {
+	if s == nil {
+		return
+	}
+	s.widget.Resize2(width, height)
+}
+
+// waiting is true until the splash starts fading out
+func (s *Splash) waiting() bool {
+	return s != nil && s.shown && !s.fading
+}
+
+func (s *Splash) fadeOut() {
+	if !s.waiting() {
+		return
+	}
+	s.fading = true
+	s.timer.Start(splashInterval)
+}
+
+func (s *Splash) tick() {
+	if !s.fading {
+		s.fadeOut()
+		return
+	}
+	s.opacity -= splashStep
+	if s.opacity > 0 {
+		s.effect.SetOpacity(s.opacity)
+		return
+	}
+	s.timer.Stop()
+	s.shown = false
+	s.widget.Hide()
+}
@@ -0,0 +1,228 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/svg"
+	"github.com/therecipe/qt/widgets"
+)
+
+const startScreenFiles = 10
+
+const startScreenCheck = `call rpcnotify(0, 'Gui', 'gonvim_start_screen', bufname('%') == '' && &buftype == '' && !&modified && line('$') == 1 && getline(1) == '', filter(v:oldfiles[:50], 'filereadable(expand(v:val))')[:9])`
+
+// StartScreen is shown instead of an empty editor when the current buffer is
+// an unnamed empty one and g:gonvim_start_screen is set. It lists recent
+// files from v:oldfiles and a few actions. Up/Down or j/k move, Enter or a
+// click opens, and any other key hides it and goes to neovim as usual.
+type StartScreen struct {
+	ws       *Workspace
+	widget   *widgets.QWidget
+	panel    *widgets.QWidget
+	layout   *widgets.QVBoxLayout
+	items    []*StartScreenItem
+	entries  []*startScreenEntry
+	selected int
+	enabled  bool
+	shown    bool
+}
+
+// StartScreenItem is a row of the start screen
+type StartScreenItem struct {
+	widget *widgets.QWidget
+	icon   *svg.QSvgWidget
+	label  *widgets.QLabel
+	hint   *widgets.QLabel
+}
+
+type startScreenEntry struct {
+	text    string
+	hint    string
+	icon    string
+	command string
+}
+
+func initStartScreen() *StartScreen {
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetContentsMargins(0, 0, 0, 0)
+	layout := widgets.NewQVBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(0)
+	panel := widgets.NewQWidget(widget, 0)
+	panel.SetLayout(layout)
+	panel.SetContentsMargins(0, 0, 0, 0)
+	panel.SetStyleSheet("* {color: rgba(205, 211, 222, 1);}")
+	widget.Hide()
+	return &StartScreen{
+		widget: widget,
+		panel:  panel,
+		layout: layout,
+	}
+}
+
+func (s *StartScreen) enable() {
+	s.enabled = true
+	go func() {
+		s.ws.nvim.Command("augroup GonvimStartScreen | autocmd! | augroup END")
+		s.ws.nvim.Command("autocmd GonvimStartScreen BufEnter * " + startScreenCheck)
+		s.ws.nvim.Command(startScreenCheck)
+	}()
+}
+
+func (s *StartScreen) update(args []interface{}) {
+	if !s.enabled || len(args) < 2 {
+		return
+	}
+	if !isTrue(args[0]) {
+		s.hide()
+		return
+	}
+	files, _ := args[1].([]interface{})
+	entries := []*startScreenEntry{}
+	for _, f := range files {
+		file, ok := f.(string)
+		if !ok || len(entries) >= startScreenFiles {
+			continue
+		}
+		entries = append(entries, &startScreenEntry{
+			text:    filepath.Base(file),
+			hint:    filepath.Dir(file),
+			icon:    getFileType(file),
+			command: fmt.Sprintf("execute 'edit ' . fnameescape('%s')", strings.Replace(file, "'", "''", -1)),
+		})
+	}
+	entries = append(entries,
+		&startScreenEntry{text: "New File", hint: ":enew", icon: "default", command: "enew"},
+		&startScreenEntry{text: "Open File", hint: ":edit", icon: "folder", command: `call feedkeys(":edit ", "n")`},
+		&startScreenEntry{text: "Quit", hint: ":qa", icon: "cross", command: "qa"},
+	)
+	s.show(entries)
+}
+
+func (s *StartScreen) newItem() *StartScreenItem {
+	icon := svg.NewQSvgWidget(nil)
+	icon.SetFixedSize2(14, 14)
+	label := widgets.NewQLabel(nil, 0)
+	hint := widgets.NewQLabel(nil, 0)
+	hint.SetStyleSheet("color: rgba(131, 131, 131, 1);")
+	layout := widgets.NewQHBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(10)
+	layout.AddWidget(icon, 0, 0)
+	layout.AddWidget(label, 0, 0)
+	layout.AddWidget(hint, 1, 0)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetContentsMargins(12, 6, 12, 6)
+	widget.SetFixedWidth(500)
+	widget.SetLayout(layout)
+	s.layout.AddWidget(widget, 0, 0)
+	index := len(s.items)
+	setClickable(widget)
+	widget.ConnectMousePressEvent(func(event *gui.QMouseEvent) {
+		s.selected = index
+		s.open()
+	})
+	return &StartScreenItem{
+		widget: widget,
+		icon:   icon,
+		label:  label,
+		hint:   hint,
+	}
+}
+
+func (s *StartScreen) show(entries []*startScreenEntry) {
+	s.entries = entries
+	for i, entry := range entries {
+		if i >= len(s.items) {
+			s.items = append(s.items, s.newItem())
+		}
+		item := s.items[i]
+		item.label.SetText(entry.text)
+		item.hint.SetText(entry.hint)
+		svgContent := s.ws.getSvg(entry.icon, nil)
+		item.icon.Load2(core.NewQByteArray2(svgContent, len(svgContent)))
+		item.widget.Show()
+	}
+	for i := len(entries); i < len(s.items); i++ {
+		s.items[i].widget.Hide()
+	}
+	s.selected = 0
+	s.updateSelected()
+	s.shown = true
+	s.resize()
+	s.widget.Show()
+	s.widget.Raise()
+}
+
+func (s *StartScreen) resize() {
+	if !s.shown {
+		return
+	}
+	width := s.ws.screen.widget.Width()
+	height := s.ws.screen.widget.Height()
+	bg := s.ws.background
+	if bg != nil {
+		s.widget.SetStyleSheet(fmt.Sprintf(".QWidget {background-color: %s;}", bg.String()))
+	}
+	s.widget.Resize2(width, height)
+	s.panel.AdjustSize()
+	s.panel.Move2((width-s.panel.Width())/2, (height-s.panel.Height())/2)
+}
+
+func (s *StartScreen) hide() {
+	if !s.shown {
+		return
+	}
+	s.shown = false
+	s.widget.Hide()
+}
+
+func (s *StartScreen) updateSelected() {
+	for i, item := range s.items {
+		if i == s.selected {
+			item.widget.SetStyleSheet(fmt.Sprintf(".QWidget {background-color: %s;}", editor.selectedBg))
+		} else {
+			item.widget.SetStyleSheet("")
+		}
+	}
+}
+
+func (s *StartScreen) open() {
+	if s.selected < 0 || s.selected >= len(s.entries) {
+		return
+	}
+	command := s.entries[s.selected].command
+	s.hide()
+	go s.ws.nvim.Command(command)
+}
+
+// keyPress returns true if the start screen handled the key
+func (s *StartScreen) keyPress(event *gui.QKeyEvent) bool {
+	if !s.shown {
+		return false
+	}
+	switch {
+	case event.Key() == int(core.Qt__Key_Down) || event.Text() == "j":
+		if s.selected < len(s.entries)-1 {
+			s.selected++
+			s.updateSelected()
+		}
+	case event.Key() == int(core.Qt__Key_Up) || event.Text() == "k":
+		if s.selected > 0 {
+			s.selected--
+			s.updateSelected()
+		}
+	case event.Key() == int(core.Qt__Key_Return) || event.Key() == int(core.Qt__Key_Enter):
+		s.open()
+	default:
+		if event.Text() != "" {
+			s.hide()
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package editor
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// Stats is the live rendering stats overlay
+type Stats struct {
+	ws         *Workspace
+	enabled    bool
+	timer      *core.QTimer
+	font       *gui.QFont
+	lineHeight int
+	width      int
+	padding    int
+
+	frames    int
+	events    int
+	paintTime time.Duration
+	paintArea int
+
+	fps       int
+	eventRate int
+	lastPaint time.Duration
+	lastArea  int
+}
+
+func initStats() *Stats {
+	font := gui.NewQFont2("Monospace", 9, int(gui.QFont__Normal), false)
+	fontMetrics := gui.NewQFontMetricsF(font)
+	stats := &Stats{
+		timer:      core.NewQTimer(nil),
+		font:       font,
+		lineHeight: int(math.Ceil(fontMetrics.Height())),
+		width:      int(math.Ceil(fontMetrics.Width("redraw 000000/s"))),
+		padding:    6,
+	}
+	stats.timer.ConnectTimeout(stats.tick)
+	return stats
+}
+
+func (s *Stats) toggle(args []interface{}) {
+	enabled := toggleArg(s.enabled, args)
+	if enabled == s.enabled {
+		return
+	}
+	s.enabled = enabled
+	s.frames = 0
+	s.events = 0
+	s.paintTime = 0
+	s.paintArea = 0
+	if enabled {
+		s.timer.Start(1000)
+	} else {
+		s.timer.Stop()
+	}
+	s.update()
+}
+
+func (s *Stats) paintDone(start time.Time, area int) {
+	if !s.enabled {
+		return
+	}
+	s.frames++
+	s.paintTime = time.Since(start)
+	s.paintArea += area
+}
+
+func (s *Stats) redrawEvents(n int) {
+	if !s.enabled {
+		return
+	}
+	s.events += n
+}
+
+func (s *Stats) tick() {
+	s.fps = s.frames
+	s.eventRate = s.events
+	s.lastPaint = s.paintTime
+	s.lastArea = s.paintArea
+	s.frames = 0
+	s.events = 0
+	s.paintArea = 0
+	s.update()
+}
+
+func (s *Stats) lines() []string {
+	return []string{
+		fmt.Sprintf("fps    %d", s.fps),
+		fmt.Sprintf("paint  %.2fms", float64(s.lastPaint)/float64(time.Millisecond)),
+		fmt.Sprintf("area   %dpx", s.lastArea),
+		fmt.Sprintf("redraw %d/s", s.eventRate),
+	}
+}
+
+func (s *Stats) rect() (int, int, int, int) {
+	width := s.width + s.padding*2
+	height := s.lineHeight*len(s.lines()) + s.padding*2
+	x := s.ws.screen.widget.Width() - width - s.padding
+	y := s.padding
+	return x, y, width, height
+}
+
+func (s *Stats) update() {
+	x, y, width, height := s.rect()
+	s.ws.screen.widget.Update2(x, y, width, height)
+}
+
+func (s *Stats) draw(p *gui.QPainter) {
+	if !s.enabled {
+		return
+	}
+	bg := s.ws.background
+	if bg == nil {
+		bg = newRGBA(24, 29, 34, 1)
+	}
+	fg := s.ws.foreground
+	if fg == nil {
+		fg = newRGBA(205, 211, 222, 1)
+	}
+	x, y, width, height := s.rect()
+	p.FillRect5(x, y, width, height, gui.NewQColor3(bg.R, bg.G, bg.B, 230))
+	p.FillRect5(x, y, width, 1, gui.NewQColor3(fg.R, fg.G, fg.B, 60))
+	p.SetFont(s.font)
+	p.SetPen2(fg.QColor())
+	pointF := core.NewQPointF()
+	for i, line := range s.lines() {
+		pointF.SetX(float64(x + s.padding))
+		pointF.SetY(float64(y + s.padding + (i+1)*s.lineHeight - s.lineHeight/4))
+		p.DrawText(pointF, line)
+	}
+}
@@ -0,0 +1,198 @@
+package editor
+
+import (
+	"path/filepath"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+// WorkspaceSwitcher is the overlay listing the workspaces. Ctrl+Tab opens it
+// and cycles through the workspaces, releasing Ctrl switches to the selected
+// one. Opened with GonvimWorkspaceSwitcher it waits for Enter instead.
+type WorkspaceSwitcher struct {
+	widget   *widgets.QWidget
+	layout   *widgets.QVBoxLayout
+	items    []*WorkspaceSwitcherItem
+	selected int
+	shown    bool
+	holding  bool
+}
+
+// WorkspaceSwitcherItem is a workspace in the switcher
+type WorkspaceSwitcherItem struct {
+	widget *widgets.QWidget
+	cwd    *widgets.QLabel
+	file   *widgets.QLabel
+}
+
+func initWorkspaceSwitcher() *WorkspaceSwitcher {
+	layout := widgets.NewQVBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(0)
+	layout.SetSizeConstraint(widgets.QLayout__SetMinAndMaxSize)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetLayout(layout)
+	widget.SetContentsMargins(1, 1, 1, 1)
+	widget.SetObjectName("switcher")
+	widget.SetStyleSheet("QWidget#switcher {border: 1px solid #000;} .QWidget {background-color: rgba(24, 29, 34, 1); } * { color: rgba(205, 211, 222, 1); }")
+	shadow := widgets.NewQGraphicsDropShadowEffect(nil)
+	shadow.SetBlurRadius(20)
+	shadow.SetColor(gui.NewQColor3(0, 0, 0, 255))
+	shadow.SetOffset3(0, 2)
+	widget.SetGraphicsEffect(shadow)
+	widget.Hide()
+	return &WorkspaceSwitcher{
+		widget: widget,
+		layout: layout,
+	}
+}
+
+func (s *WorkspaceSwitcher) newItem() *WorkspaceSwitcherItem {
+	cwd := widgets.NewQLabel(nil, 0)
+	cwd.SetStyleSheet("font-weight: bold;")
+	file := widgets.NewQLabel(nil, 0)
+	file.SetStyleSheet("color: rgba(131, 131, 131, 1);")
+	layout := widgets.NewQVBoxLayout()
+	layout.SetContentsMargins(0, 0, 0, 0)
+	layout.SetSpacing(2)
+	layout.AddWidget(cwd, 0, 0)
+	layout.AddWidget(file, 0, 0)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetContentsMargins(12, 8, 12, 8)
+	widget.SetFixedWidth(400)
+	widget.SetLayout(layout)
+	s.layout.AddWidget(widget, 0, 0)
+	return &WorkspaceSwitcherItem{
+		widget: widget,
+		cwd:    cwd,
+		file:   file,
+	}
+}
+
+func (s *WorkspaceSwitcher) show(holding bool) {
+	s.holding = holding
+	if s.shown {
+		return
+	}
+	s.shown = true
+	s.selected = editor.active
+	for i, ws := range editor.workspaces {
+		if i >= len(s.items) {
+			s.items = append(s.items, s.newItem())
+		}
+		item := s.items[i]
+		cwd := ws.cwd
+		if cwd == "" {
+			cwd = "[No Directory]"
+		}
+		item.cwd.SetText(cwd)
+		file := ws.statusline.file.file
+		if file == "" {
+			file = "[No Name]"
+		} else if ws.cwd != "" {
+			rel, err := filepath.Rel(ws.cwd, file)
+			if err == nil {
+				file = rel
+			}
+		}
+		item.file.SetText(file)
+		item.widget.Show()
+	}
+	for i := len(editor.workspaces); i < len(s.items); i++ {
+		s.items[i].widget.Hide()
+	}
+	s.updateSelected()
+	s.widget.AdjustSize()
+	s.resize()
+	s.widget.Show()
+	s.widget.Raise()
+}
+
+func (s *WorkspaceSwitcher) resize() {
+	if !s.shown {
+		return
+	}
+	width := editor.wsWidget.Width()
+	height := editor.wsWidget.Height()
+	s.widget.Move2((width-s.widget.Width())/2, (height-s.widget.Height())/2)
+}
+
+func (s *WorkspaceSwitcher) hide() {
+	if !s.shown {
+		return
+	}
+	s.shown = false
+	s.holding = false
+	s.widget.Hide()
+}
+
+func (s *WorkspaceSwitcher) move(delta int) {
+	n := len(editor.workspaces)
+	if n == 0 {
+		return
+	}
+	s.selected = ((s.selected+delta)%n + n) % n
+	s.updateSelected()
+}
+
+func (s *WorkspaceSwitcher) updateSelected() {
+	for i := 0; i < len(editor.workspaces) && i < len(s.items); i++ {
+		if i == s.selected {
+			s.items[i].widget.SetStyleSheet("background-color: rgba(81, 154, 186, 0.5);")
+		} else {
+			s.items[i].widget.SetStyleSheet("")
+		}
+	}
+}
+
+func (s *WorkspaceSwitcher) confirm() {
+	selected := s.selected
+	s.hide()
+	editor.workspaceSwitch(selected + 1)
+}
+
+// keyPress returns true if the switcher handled the key
+func (s *WorkspaceSwitcher) keyPress(event *gui.QKeyEvent) bool {
+	key := core.Qt__Key(event.Key())
+	ctrl := event.Modifiers()&core.Qt__ControlModifier > 0
+	if !s.shown {
+		if ctrl && len(editor.workspaces) > 1 && (key == core.Qt__Key_Tab || key == core.Qt__Key_Backtab) {
+			s.show(true)
+			s.step(key)
+			return true
+		}
+		return false
+	}
+	switch key {
+	case core.Qt__Key_Escape:
+		s.hide()
+	case core.Qt__Key_Return, core.Qt__Key_Enter:
+		s.confirm()
+	case core.Qt__Key_Tab, core.Qt__Key_Backtab:
+		s.step(key)
+	case core.Qt__Key_Down:
+		s.move(1)
+	case core.Qt__Key_Up:
+		s.move(-1)
+	}
+	return true
+}
+
+func (s *WorkspaceSwitcher) step(key core.Qt__Key) {
+	if key == core.Qt__Key_Backtab {
+		s.move(-1)
+	} else {
+		s.move(1)
+	}
+}
+
+func (s *WorkspaceSwitcher) keyRelease(event *gui.QKeyEvent) {
+	if !s.shown || !s.holding {
+		return
+	}
+	if core.Qt__Key(event.Key()) == core.Qt__Key_Control {
+		s.confirm()
+	}
+}
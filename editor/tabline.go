@@ -17,6 +17,9 @@ type Tabline struct {
 	layout        *widgets.QLayout
 	CurrentID     int
 	Tabs          []*Tab
+	tabCount      int
+	CurrentBuf    int
+	Buffers       []tablineBuffer
 	marginDefault int
 	marginTop     int
 	marginBottom  int
@@ -41,6 +44,13 @@ type Tab struct {
 	hidden    bool
 }
 
+// tablineBuffer is a listed buffer, which tabline_update sends from neovim
+// 0.5 on
+type tablineBuffer struct {
+	ID   int
+	Name string
+}
+
 func (t *Tabline) subscribe() {
 	if !t.ws.drawTabline {
 		t.widget.Hide()
@@ -376,7 +386,7 @@ func (t *Tabline) update(args []interface{}) {
 			continue
 		}
 		if i > len(t.Tabs)-1 {
-			return
+			break
 		}
 		tab := t.Tabs[i]
 		tab.ID = int(tabMap["tab"].(nvim.Tabpage))
@@ -401,6 +411,35 @@ func (t *Tabline) update(args []interface{}) {
 		tab.setActive(false)
 		tab.hide()
 	}
+	t.tabCount = minInt(len(tabs), len(t.Tabs))
+	t.updateBuffers(arg)
+	t.ws.sidebar.update()
+}
+
+// updateBuffers keeps the current buffer and the listed buffers when
+// tabline_update has them
+func (t *Tabline) updateBuffers(arg []interface{}) {
+	t.CurrentBuf = 0
+	t.Buffers = t.Buffers[:0]
+	if len(arg) < 4 {
+		return
+	}
+	if buf, ok := arg[2].(nvim.Buffer); ok {
+		t.CurrentBuf = int(buf)
+	}
+	bufs, _ := arg[3].([]interface{})
+	for _, b := range bufs {
+		bufMap, ok := b.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		buf, ok := bufMap["buffer"].(nvim.Buffer)
+		if !ok {
+			continue
+		}
+		name, _ := bufMap["name"].(string)
+		t.Buffers = append(t.Buffers, tablineBuffer{ID: int(buf), Name: name})
+	}
 }
 
 func getFileType(text string) string {
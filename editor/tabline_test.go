@@ -0,0 +1,39 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+func TestTablineUpdateBuffers(t *testing.T) {
+	tabline := &Tabline{}
+	tabline.updateBuffers([]interface{}{
+		nvim.Tabpage(1),
+		[]interface{}{},
+		nvim.Buffer(3),
+		[]interface{}{
+			map[string]interface{}{"buffer": nvim.Buffer(1), "name": "main.go"},
+			map[string]interface{}{"buffer": nvim.Buffer(3), "name": ""},
+			"not a buffer",
+		},
+	})
+	if tabline.CurrentBuf != 3 {
+		t.Errorf("CurrentBuf = %d, want 3", tabline.CurrentBuf)
+	}
+	want := []tablineBuffer{{1, "main.go"}, {3, ""}}
+	if len(tabline.Buffers) != len(want) {
+		t.Fatalf("Buffers = %v, want %v", tabline.Buffers, want)
+	}
+	for i, buf := range want {
+		if tabline.Buffers[i] != buf {
+			t.Errorf("Buffers[%d] = %v, want %v", i, tabline.Buffers[i], buf)
+		}
+	}
+
+	// before neovim 0.5 there are only the tabs
+	tabline.updateBuffers([]interface{}{nvim.Tabpage(1), []interface{}{}})
+	if tabline.CurrentBuf != 0 || len(tabline.Buffers) != 0 {
+		t.Errorf("without buffers: CurrentBuf %d, Buffers %v", tabline.CurrentBuf, tabline.Buffers)
+	}
+}
@@ -0,0 +1,247 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+const (
+	tabOverviewWidth   = 240
+	tabOverviewSpacing = 16
+)
+
+// TabOverview is the grid of tabpages opened with GonvimTabOverview. Only
+// the current tabpage is on the screen, so each tabpage's thumbnail is a
+// grab of the screen taken when it was last left, or now for the current
+// one. Tabpages not seen since gonvim started show their name only. Arrow
+// keys or hjkl move, Enter or a click goes to the tabpage and Escape closes.
+type TabOverview struct {
+	ws       *Workspace
+	widget   *widgets.QWidget
+	scroll   *widgets.QScrollArea
+	panel    *widgets.QWidget
+	layout   *widgets.QGridLayout
+	items    []*TabOverviewItem
+	tabs     []nvim.Tabpage
+	thumbs   map[nvim.Tabpage]*gui.QPixmap
+	columns  int
+	selected int
+	shown    bool
+}
+
+// TabOverviewItem is a tabpage in the overview
+type TabOverviewItem struct {
+	widget *widgets.QWidget
+	thumb  *widgets.QLabel
+	name   *widgets.QLabel
+}
+
+func initTabOverview() *TabOverview {
+	layout := widgets.NewQGridLayout2()
+	layout.SetContentsMargins(tabOverviewSpacing, tabOverviewSpacing, tabOverviewSpacing, tabOverviewSpacing)
+	layout.SetSpacing(tabOverviewSpacing)
+	panel := widgets.NewQWidget(nil, 0)
+	panel.SetLayout(layout)
+	scroll := widgets.NewQScrollArea(nil)
+	scroll.SetWidget(panel)
+	scroll.SetWidgetResizable(true)
+	scroll.SetFrameShape(widgets.QFrame__NoFrame)
+	scroll.SetFocusPolicy(core.Qt__NoFocus)
+	widgetLayout := widgets.NewQVBoxLayout()
+	widgetLayout.SetContentsMargins(0, 0, 0, 0)
+	widgetLayout.AddWidget(scroll, 1, 0)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetLayout(widgetLayout)
+	widget.SetStyleSheet(".QWidget {background-color: rgba(24, 29, 34, 0.95);} QScrollArea {background: transparent;} * {color: rgba(205, 211, 222, 1);}")
+	scroll.Viewport().SetStyleSheet("background: transparent;")
+	widget.Hide()
+	return &TabOverview{
+		widget: widget,
+		scroll: scroll,
+		panel:  panel,
+		layout: layout,
+		thumbs: map[nvim.Tabpage]*gui.QPixmap{},
+	}
+}
+
+// capture keeps a thumbnail of what is on the screen for the tabpage
+func (t *TabOverview) capture(args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	screen := t.ws.screen.widget
+	if screen.Width() <= 0 || screen.Height() <= 0 {
+		return
+	}
+	pixmap := screen.Grab(core.NewQRect4(0, 0, -1, -1))
+	t.thumbs[nvim.Tabpage(reflectToInt(args[0]))] = pixmap.Scaled2(
+		tabOverviewWidth,
+		tabOverviewWidth*screen.Height()/screen.Width(),
+		core.Qt__KeepAspectRatio,
+		core.Qt__SmoothTransformation,
+	)
+}
+
+func (t *TabOverview) newItem() *TabOverviewItem {
+	thumb := widgets.NewQLabel(nil, 0)
+	thumb.SetAlignment(core.Qt__AlignCenter)
+	thumb.SetFixedWidth(tabOverviewWidth)
+	name := widgets.NewQLabel(nil, 0)
+	name.SetAlignment(core.Qt__AlignCenter)
+	name.SetFixedWidth(tabOverviewWidth)
+	layout := widgets.NewQVBoxLayout()
+	layout.SetContentsMargins(4, 4, 4, 4)
+	layout.SetSpacing(4)
+	layout.AddWidget(thumb, 0, 0)
+	layout.AddWidget(name, 0, 0)
+	widget := widgets.NewQWidget(nil, 0)
+	widget.SetLayout(layout)
+	index := len(t.items)
+	setClickable(widget)
+	widget.ConnectMousePressEvent(func(event *gui.QMouseEvent) {
+		t.selected = index
+		t.open()
+	})
+	return &TabOverviewItem{
+		widget: widget,
+		thumb:  thumb,
+		name:   name,
+	}
+}
+
+// show takes the tabpages, the current one and the tabpages' names
+func (t *TabOverview) show(args []interface{}) {
+	if len(args) < 3 {
+		return
+	}
+	tabs, _ := args[0].([]interface{})
+	current := nvim.Tabpage(reflectToInt(args[1]))
+	names, _ := args[2].([]interface{})
+	t.capture(args[1:2])
+
+	t.tabs = []nvim.Tabpage{}
+	for _, tab := range tabs {
+		t.tabs = append(t.tabs, nvim.Tabpage(reflectToInt(tab)))
+	}
+	thumbs := map[nvim.Tabpage]*gui.QPixmap{}
+	for _, tab := range t.tabs {
+		if thumb, ok := t.thumbs[tab]; ok {
+			thumbs[tab] = thumb
+		}
+	}
+	t.thumbs = thumbs
+
+	t.columns = (t.ws.screen.widget.Width() - tabOverviewSpacing) / (tabOverviewWidth + 8 + tabOverviewSpacing)
+	if t.columns < 1 {
+		t.columns = 1
+	}
+	t.selected = 0
+	for i, tab := range t.tabs {
+		if i >= len(t.items) {
+			t.items = append(t.items, t.newItem())
+		}
+		item := t.items[i]
+		t.layout.RemoveWidget(item.widget)
+		t.layout.AddWidget(item.widget, i/t.columns, i%t.columns, 0)
+		name := ""
+		if i < len(names) {
+			name, _ = names[i].(string)
+		}
+		if name == "" {
+			name = "[No Name]"
+		}
+		item.name.SetText(fmt.Sprintf("%d  %s", i+1, filepath.Base(name)))
+		thumb := t.thumbs[tab]
+		if thumb != nil {
+			item.thumb.SetPixmap(thumb)
+			item.thumb.SetFixedHeight(thumb.Height())
+		} else {
+			item.thumb.Clear()
+			item.thumb.SetFixedHeight(tabOverviewWidth * 9 / 16)
+		}
+		if tab == current {
+			t.selected = i
+		}
+		item.widget.Show()
+	}
+	for i := len(t.tabs); i < len(t.items); i++ {
+		t.items[i].widget.Hide()
+	}
+	t.updateSelected()
+	t.shown = true
+	t.resize()
+	t.widget.Show()
+	t.widget.Raise()
+}
+
+func (t *TabOverview) resize() {
+	if !t.shown {
+		return
+	}
+	t.widget.Resize2(t.ws.screen.widget.Width(), t.ws.screen.widget.Height())
+}
+
+func (t *TabOverview) hide() {
+	if !t.shown {
+		return
+	}
+	t.shown = false
+	t.widget.Hide()
+}
+
+func (t *TabOverview) updateSelected() {
+	for i, item := range t.items {
+		if i == t.selected {
+			item.widget.SetStyleSheet(fmt.Sprintf(".QWidget {background-color: %s;}", editor.selectedBg))
+			t.scroll.EnsureWidgetVisible(item.widget, tabOverviewSpacing, tabOverviewSpacing)
+		} else {
+			item.widget.SetStyleSheet("")
+		}
+	}
+}
+
+func (t *TabOverview) open() {
+	if t.selected < 0 || t.selected >= len(t.tabs) {
+		return
+	}
+	tab := t.tabs[t.selected]
+	t.hide()
+	go t.ws.nvim.SetCurrentTabpage(tab)
+}
+
+func (t *TabOverview) move(n int) {
+	selected := t.selected + n
+	if selected < 0 || selected >= len(t.tabs) {
+		return
+	}
+	t.selected = selected
+	t.updateSelected()
+}
+
+// keyPress returns true if the overview handled the key, it takes all keys
+// while it is shown
+func (t *TabOverview) keyPress(event *gui.QKeyEvent) bool {
+	if !t.shown {
+		return false
+	}
+	switch {
+	case event.Key() == int(core.Qt__Key_Escape):
+		t.hide()
+	case event.Key() == int(core.Qt__Key_Return) || event.Key() == int(core.Qt__Key_Enter):
+		t.open()
+	case event.Key() == int(core.Qt__Key_Right) || event.Text() == "l":
+		t.move(1)
+	case event.Key() == int(core.Qt__Key_Left) || event.Text() == "h":
+		t.move(-1)
+	case event.Key() == int(core.Qt__Key_Down) || event.Text() == "j":
+		t.move(t.columns)
+	case event.Key() == int(core.Qt__Key_Up) || event.Text() == "k":
+		t.move(-t.columns)
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"time"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+	"github.com/therecipe/qt/widgets"
+)
+
+const (
+	tabSwitchDuration = 120 * time.Millisecond
+	tabSwitchInterval = 16
+)
+
+// TabSwitch animates going to another tabpage with g:gonvim_tab_animation
+// set to "fade" or "slide". The screen is grabbed when the tabpage is left
+// and the grab is laid over the new tabpage, then fades out or slides away
+// to the side of the tabpage gone to. The new tabpage is drawn and takes
+// input underneath from the start, the grab ignores the mouse and never has
+// the focus. Anything else, the default, switches at once.
+type TabSwitch struct {
+	ws     *Workspace
+	label  *widgets.QLabel
+	effect *widgets.QGraphicsOpacityEffect
+	timer  *core.QTimer
+	style  string
+	start  time.Time
+	from   int
+	left   bool
+}
+
+func initTabSwitch() *TabSwitch {
+	label := widgets.NewQLabel(nil, 0)
+	label.SetAttribute(core.Qt__WA_TransparentForMouseEvents, true)
+	label.SetFocusPolicy(core.Qt__NoFocus)
+	effect := widgets.NewQGraphicsOpacityEffect(nil)
+	effect.SetOpacity(1)
+	label.SetGraphicsEffect(effect)
+	label.Hide()
+	t := &TabSwitch{
+		label:  label,
+		effect: effect,
+		timer:  core.NewQTimer(nil),
+	}
+	t.timer.ConnectTimeout(t.tick)
+	return t
+}
+
+func (t *TabSwitch) enabled() bool {
+	return t.style == "fade" || t.style == "slide"
+}
+
+// leave grabs the screen of the tabpage being left, args are its handle
+// and number
+func (t *TabSwitch) leave(args []interface{}) {
+	t.stop()
+	if !t.enabled() || len(args) < 2 {
+		return
+	}
+	screen := t.ws.screen.widget
+	if screen.Width() <= 0 || screen.Height() <= 0 {
+		return
+	}
+	t.label.SetPixmap(screen.Grab(core.NewQRect4(0, 0, -1, -1)))
+	t.from = reflectToInt(args[1])
+}
+
+// enter starts the animation over the tabpage gone to, args are its number
+func (t *TabSwitch) enter(args []interface{}) {
+	if !t.enabled() || len(args) < 1 || t.from == 0 {
+		return
+	}
+	to := reflectToInt(args[0])
+	if to == t.from {
+		t.from = 0
+		return
+	}
+	t.left = to > t.from
+	t.from = 0
+	screen := t.ws.screen.widget
+	t.label.Resize2(screen.Width(), screen.Height())
+	t.label.Move2(0, 0)
+	t.effect.SetOpacity(1)
+	t.label.Show()
+	t.label.Raise()
+	t.start = time.Now()
+	t.timer.Start(tabSwitchInterval)
+}
+
+func (t *TabSwitch) tick() {
+	progress := float64(time.Since(t.start)) / float64(tabSwitchDuration)
+	if progress >= 1 {
+		t.stop()
+		return
+	}
+	// ease out, most of the way is covered at the start
+	eased := 1 - (1-progress)*(1-progress)
+	if t.style == "slide" {
+		x := int(eased * float64(t.label.Width()))
+		if t.left {
+			x = -x
+		}
+		t.label.Move2(x, 0)
+		t.effect.SetOpacity(1 - eased/2)
+		return
+	}
+	t.effect.SetOpacity(1 - eased)
+}
+
+func (t *TabSwitch) stop() {
+	t.timer.Stop()
+	t.label.Hide()
+	t.label.SetPixmap(gui.NewQPixmap())
+}
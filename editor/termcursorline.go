@@ -0,0 +1,88 @@
+package editor
+
+import (
+	"fmt"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// terminalCursorline applies g:gonvim_terminal_cursorline to the window while
+// the focus is in a terminal buffer, and gives the window its own
+// 'cursorline' and 'winhighlight' back once it leaves. 0, the default, turns
+// the cursorline off, 1 leaves it as it is, and a highlight group name shows
+// it in that group. The changes are applied in order by one goroutine, so a
+// window's options are given back before the next window's are saved.
+type terminalCursorline struct {
+	config    interface{}
+	focused   nvim.Window
+	changes   chan terminalCursorlineChange
+	win       nvim.Window
+	active    bool
+	saved     bool
+	savedHl   string
+	savedLine bool
+}
+
+type terminalCursorlineChange struct {
+	terminal bool
+	win      nvim.Window
+	group    string
+}
+
+// update is called after the cursor moved and acts when the focus went to
+// another terminal window or between terminal and other buffers
+func (t *terminalCursorline) update(w *Workspace, terminal bool) {
+	group, _ := t.config.(string)
+	if group == "" && isTrue(t.config) {
+		return
+	}
+	var win nvim.Window
+	if terminal {
+		current := w.screen.cursorWin()
+		if current == nil {
+			return
+		}
+		win = current.win
+	}
+	if win == t.focused {
+		return
+	}
+	t.focused = win
+	if t.changes == nil {
+		t.changes = make(chan terminalCursorlineChange, 100)
+		go func() {
+			for change := range t.changes {
+				t.apply(w.nvim, change.terminal, change.win, change.group)
+			}
+		}()
+	}
+	t.changes <- terminalCursorlineChange{terminal: terminal, win: win, group: group}
+}
+
+func (t *terminalCursorline) apply(neovim *nvim.Nvim, terminal bool, win nvim.Window, group string) {
+	if t.active {
+		if t.saved {
+			neovim.SetWindowOption(t.win, "cursorline", t.savedLine)
+			neovim.SetWindowOption(t.win, "winhl", t.savedHl)
+		}
+		t.active = false
+		t.saved = false
+	}
+	if !terminal {
+		return
+	}
+	t.win = win
+	t.active = true
+	t.saved = neovim.WindowOption(win, "cursorline", &t.savedLine) == nil &&
+		neovim.WindowOption(win, "winhl", &t.savedHl) == nil
+	if group == "" {
+		neovim.SetWindowOption(win, "cursorline", false)
+		return
+	}
+	hl := fmt.Sprintf("CursorLine:%s", group)
+	if t.savedHl != "" {
+		hl = t.savedHl + "," + hl
+	}
+	neovim.SetWindowOption(win, "winhl", hl)
+	neovim.SetWindowOption(win, "cursorline", true)
+}
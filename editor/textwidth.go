@@ -0,0 +1,44 @@
+package editor
+
+import (
+	"github.com/therecipe/qt/gui"
+)
+
+// With g:gonvim_textwidth_dim set everything right of 'textwidth' is faded
+// into the background, by g:gonvim_textwidth_dim_amount from 0 to 1, 0.35 by
+// default. Windows with 'textwidth' 0 are left alone. The column moves with
+// the text when a window is scrolled sideways.
+type textwidthDim struct {
+	enabled bool
+	amount  float64
+}
+
+func (s *Screen) drawTextwidthDim(p *gui.QPainter) {
+	if !s.twDim.enabled || s.twDim.amount <= 0 {
+		return
+	}
+	font := s.ws.font
+	for _, win := range s.curWins {
+		if win.textwidth <= 0 {
+			continue
+		}
+		start := win.textstart + maxInt(win.textwidth-win.leftcol, 0)
+		if start >= win.width {
+			continue
+		}
+		bg := win.bg
+		if bg == nil {
+			bg = s.ws.background
+		}
+		if bg == nil {
+			continue
+		}
+		p.FillRect5(
+			font.cellX(win.pos[1]+start),
+			win.pos[0]*font.lineHeight,
+			font.cellsWidth(win.pos[1]+start, win.pos[1]+win.width),
+			win.height*font.lineHeight,
+			gui.NewQColor3(bg.R, bg.G, bg.B, int(s.twDim.amount*255)),
+		)
+	}
+}
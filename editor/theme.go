@@ -0,0 +1,209 @@
+package editor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+)
+
+// themeGroups are the highlight groups a theme file keeps the colors of
+var themeGroups = []string{
+	"Normal", "NormalNC", "Cursor", "CursorLine", "CursorLineNr", "LineNr",
+	"VertSplit", "StatusLine", "StatusLineNC", "TabLine", "TabLineSel",
+	"TabLineFill", "Pmenu", "PmenuSel", "PmenuSbar", "PmenuThumb", "Visual",
+	"Search", "Folded", "FoldColumn", "SignColumn", "NonText", "SpecialKey",
+	"DiffAdd", "DiffChange", "DiffDelete", "DiffText", "ErrorMsg",
+	"WarningMsg", "MatchParen",
+}
+
+// Theme is a gonvim theme file as written by GonvimExportTheme, JSON like
+//
+//	{
+//	  "name": "gruvbox",
+//	  "background": "dark",
+//	  "foreground": "#ebdbb2",
+//	  "backgroundColor": "#282828",
+//	  "border": "#504945",
+//	  "groups": {
+//	    "Normal": {"fg": "#ebdbb2", "bg": "#282828"},
+//	    "Pmenu": {"fg": "#ebdbb2", "bg": "#504945"},
+//	    ...
+//	  }
+//	}
+//
+// name is g:colors_name and background 'background'. foreground and
+// backgroundColor are the Normal colors gonvim draws with and border is the
+// color of the window borders, the VertSplit foreground. groups has the fg,
+// bg and sp of the highlight groups in themeGroups after following links, a
+// color is left out when the group doesn't set it.
+//
+// GonvimLoadTheme <path>, or a path in g:gonvim_theme at startup, loads a
+// theme file for the GUI chrome. The popupmenu and the ext_messages panel
+// take the colors of the groups they use from it, and the palette, the
+// notifications, the signature help, the location popup and the input
+// method tooltip take the Pmenu colors, or foreground and backgroundColor,
+// and the border color. The grid keeps drawing with the colorscheme, and
+// the theme stays on when the colorscheme changes.
+type Theme struct {
+	Name            string                       `json:"name"`
+	Background      string                       `json:"background"`
+	Foreground      string                       `json:"foreground"`
+	BackgroundColor string                       `json:"backgroundColor"`
+	Border          string                       `json:"border"`
+	Groups          map[string]map[string]string `json:"groups"`
+}
+
+func (w *Workspace) exportTheme(args []interface{}) {
+	path := ""
+	if len(args) > 0 {
+		path, _ = args[0].(string)
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		go w.nvim.Command(`echomsg "GonvimExportTheme needs a file path"`)
+		return
+	}
+	go func() {
+		err := w.writeTheme(path)
+		if err != nil {
+			w.nvim.Command(fmt.Sprintf("echomsg '%s'", strings.Replace(err.Error(), "'", "''", -1)))
+			return
+		}
+		w.nvim.Command(fmt.Sprintf("echomsg 'theme written to %s'", strings.Replace(path, "'", "''", -1)))
+	}()
+}
+
+func (w *Workspace) writeTheme(path string) error {
+	theme := &Theme{
+		Groups: map[string]map[string]string{},
+	}
+	w.nvim.Eval("get(g:, 'colors_name', '')", &theme.Name)
+	w.nvim.Option("background", &theme.Background)
+	for _, group := range themeGroups {
+		colors := map[string]string{}
+		for _, attr := range []string{"fg", "bg", "sp"} {
+			color := w.hlColor(group, attr)
+			if color != nil {
+				colors[attr] = color.Hex()
+			}
+		}
+		if len(colors) > 0 {
+			theme.Groups[group] = colors
+		}
+	}
+	theme.Foreground = theme.Groups["Normal"]["fg"]
+	theme.BackgroundColor = theme.Groups["Normal"]["bg"]
+	theme.Border = theme.Groups["VertSplit"]["fg"]
+
+	data, err := json.MarshalIndent(theme, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err = homedir.Expand(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func (w *Workspace) loadTheme(args []interface{}) {
+	path := ""
+	if len(args) > 0 {
+		path, _ = args[0].(string)
+	}
+	path = strings.TrimSpace(path)
+	if path == "" {
+		go w.nvim.Command(`echomsg "GonvimLoadTheme needs a file path"`)
+		return
+	}
+	go func() {
+		theme, err := readTheme(path)
+		if err != nil {
+			w.nvim.Command(fmt.Sprintf("echomsg '%s'", strings.Replace(err.Error(), "'", "''", -1)))
+			return
+		}
+		w.guiUpdates <- []interface{}{"gonvim_theme", theme}
+		w.signal.GuiSignal()
+	}()
+}
+
+func readTheme(path string) (*Theme, error) {
+	path, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	theme := &Theme{}
+	err = json.Unmarshal(data, theme)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return theme, nil
+}
+
+// attrs returns the group colors of the theme keyed like getHlAttrs
+func (t *Theme) attrs() hlAttrs {
+	attrs := hlAttrs{}
+	for group, colors := range t.Groups {
+		for attr, color := range colors {
+			attrs[[2]string{group, attr}] = color
+		}
+	}
+	return attrs
+}
+
+// setTheme applies the loaded theme on the GUI thread. The popupmenu and
+// the message panel get it through setColors, as the colorscheme's colors
+// are fetched again.
+func (w *Workspace) setTheme(theme *Theme) {
+	w.theme = theme
+	w.setChromeColors()
+	w.screen.updateColors()
+}
+
+// themeAttrs returns the attrs with the theme's colors over them
+func (w *Workspace) themeAttrs(attrs hlAttrs) hlAttrs {
+	if w.theme == nil {
+		return attrs
+	}
+	merged := hlAttrs{}
+	for key, value := range attrs {
+		merged[key] = value
+	}
+	for key, value := range w.theme.attrs() {
+		merged[key] = value
+	}
+	return merged
+}
+
+// setChromeColors styles the chrome drawn in fixed colors with the theme
+func (w *Workspace) setChromeColors() {
+	theme := w.theme
+	attrs := theme.attrs()
+	fg := attrs.color("Pmenu", "fg")
+	if fg == nil {
+		fg = hexToRGBA(theme.Foreground)
+	}
+	bg := attrs.color("Pmenu", "bg")
+	if bg == nil {
+		bg = hexToRGBA(theme.BackgroundColor)
+	}
+	border := hexToRGBA(theme.Border)
+	if fg == nil || bg == nil {
+		return
+	}
+	if border == nil {
+		border = newRGBA(0, 0, 0, 1)
+	}
+	w.palette.widget.SetStyleSheet(fmt.Sprintf("QWidget#palette {border: 1px solid %s;} .QWidget {background-color: %s; } * { color: %s; }", border.String(), bg.String(), fg.String()))
+	w.message.widget.SetStyleSheet(fmt.Sprintf("* {background-color: %s; color: %s;}", bg.String(), fg.String()))
+	w.signature.widget.SetStyleSheet(fmt.Sprintf(".QWidget {border: 1px solid %s;} QWidget {background-color: %s;} * {color: %s;}", border.String(), bg.String(), fg.String()))
+	w.loc.widget.SetStyleSheet(fmt.Sprintf(".QWidget { border: 1px solid %s; } * {color: %s; background-color: %s;}", border.String(), fg.String(), bg.String()))
+	w.screen.tooltip.SetStyleSheet(fmt.Sprintf("* {color: %s; background-color: %s; text-decoration: underline;}", fg.String(), bg.String()))
+}
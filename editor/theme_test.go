@@ -0,0 +1,61 @@
+package editor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadTheme(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gonvim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	written := &Theme{
+		Name:            "gruvbox",
+		Foreground:      "#ebdbb2",
+		BackgroundColor: "#282828",
+		Border:          "#504945",
+		Groups: map[string]map[string]string{
+			"Pmenu":      {"fg": "#ebdbb2", "bg": "#504945"},
+			"ErrorMsg":   {"fg": "#fb4934"},
+			"WarningMsg": {"fg": "#fabd2f"},
+		},
+	}
+	data, _ := json.Marshal(written)
+	path := filepath.Join(dir, "theme.json")
+	ioutil.WriteFile(path, data, 0644)
+
+	theme, err := readTheme(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &Workspace{theme: theme}
+	attrs := w.themeAttrs(hlAttrs{
+		{"Pmenu", "fg"}:    "#ffffff",
+		{"PmenuSel", "bg"}: "#3c3836",
+	})
+	want := hlAttrs{
+		{"Pmenu", "fg"}:      "#ebdbb2",
+		{"Pmenu", "bg"}:      "#504945",
+		{"PmenuSel", "bg"}:   "#3c3836",
+		{"ErrorMsg", "fg"}:   "#fb4934",
+		{"WarningMsg", "fg"}: "#fabd2f",
+	}
+	if len(attrs) != len(want) {
+		t.Errorf("themeAttrs() = %v, want %v", attrs, want)
+	}
+	for key, color := range want {
+		if attrs[key] != color {
+			t.Errorf("themeAttrs()[%v] = %q, want %q", key, attrs[key], color)
+		}
+	}
+
+	ioutil.WriteFile(path, []byte("{"), 0644)
+	if _, err := readTheme(path); err == nil {
+		t.Error("readTheme of a broken file returned no error")
+	}
+}
@@ -0,0 +1,71 @@
+package editor
+
+import (
+	"math"
+
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+// setTransparent applies g:gonvim_transparent, the opacity of the editor
+// background from 0 to 1. Below 1 the window is made translucent and the
+// editor background, Normal or GonvimEditorBg, is filled with that opacity
+// so the desktop shows through it. Text and the other backgrounds stay opaque.
+// The tabline and statusline keep their own background.
+func (w *Workspace) setTransparent(args []interface{}) {
+	if len(args) == 0 {
+		return
+	}
+	transparent := math.Min(math.Max(reflectToFloat(args[0]), 0), 1)
+	if transparent == w.transparent {
+		return
+	}
+	w.transparent = transparent
+	if transparent < 1 {
+		editor.setTranslucent()
+	}
+	w.screen.lineCache.invalidate()
+	w.screen.queueRedrawAll()
+	w.screen.update()
+}
+
+// setTranslucent gives the main window an alpha channel. Qt only picks it up
+// when the native window is created, so a window already shown is created
+// again.
+func (e *Editor) setTranslucent() {
+	if e.translucent {
+		return
+	}
+	e.translucent = true
+	e.window.SetAttribute(core.Qt__WA_TranslucentBackground, true)
+	if e.window.IsVisible() {
+		e.window.Destroy(true, true)
+		e.window.Show()
+		e.watchScreen()
+	}
+}
+
+// transparentBg is true for cells in the background g:gonvim_transparent
+// makes see-through
+func (s *Screen) transparentBg(bg *RGBA) bool {
+	return s.ws.transparent < 1 && bg != nil && s.ws.background != nil && bg.equals(s.ws.background)
+}
+
+// fillBackground fills the rect with the editor background. With
+// g:gonvim_transparent set the fill replaces what is under it instead of
+// blending with it, so painting a cell again doesn't make it more opaque.
+func (s *Screen) fillBackground(p *gui.QPainter, x, y, width, height int, bg *RGBA) {
+	if bg == nil {
+		return
+	}
+	transparent := s.ws.transparent
+	if transparent >= 1 {
+		p.FillRect5(x, y, width, height, bg.QColor())
+		return
+	}
+	color := gui.NewQColor3(bg.R, bg.G, bg.B, int(bg.A*transparent*255))
+	p.Save()
+	p.SetCompositionMode(gui.QPainter__CompositionMode_Source)
+	p.FillRect5(x, y, width, height, color)
+	p.Restore()
+}
@@ -12,6 +12,40 @@ func reflectToInt(iface interface{}) int {
 	return 0
 }
 
+func reflectToFloat(iface interface{}) float64 {
+	switch a := iface.(type) {
+	case float64:
+		return a
+	case float32:
+		return float64(a)
+	}
+	return float64(reflectToInt(iface))
+}
+
+func clampInt(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 func isZero(d interface{}) bool {
 	if d == nil {
 		return false
@@ -45,3 +79,20 @@ func isTrue(d interface{}) bool {
 	}
 	return false
 }
+
+func toggleArg(current bool, args []interface{}) bool {
+	if len(args) == 0 {
+		return !current
+	}
+	arg, ok := args[0].(string)
+	if !ok {
+		return isTrue(args[0])
+	}
+	switch arg {
+	case "on", "1", "true":
+		return true
+	case "off", "0", "false":
+		return false
+	}
+	return !current
+}
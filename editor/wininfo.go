@@ -0,0 +1,104 @@
+package editor
+
+import (
+	"strings"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// winInfoFunc returns what the gutters, the quickfix shading, the margin
+// fade, the textwidth dim and the wheel limit need from getwininfo(), for
+// the windows of the current tabpage, so getWindows gets it all in one call.
+// With folds set it adds the rows of the closed folds, which
+// GonvimClosedFolds finds from inside each window, and with view set the
+// first column shown when the window is scrolled sideways. Those need
+// win_execute(), and the folds screenpos(), so they are left out before
+// neovim 0.5.
+const winInfoFunc = `function! GonvimClosedFolds() abort
+  let rows = []
+  let top = win_screenpos(0)[0]
+  let lnum = line('w0')
+  while lnum <= line('w$')
+    if foldclosed(lnum) != -1
+      call add(rows, screenpos(win_getid(), lnum, 1).row - top)
+      let lnum = foldclosedend(lnum)
+    endif
+    let lnum += 1
+  endwhile
+  return rows
+endfunction
+function! GonvimWinInfo(folds, view) abort
+  let wins = []
+  for info in getwininfo()
+    if info.tabnr != tabpagenr()
+      continue
+    endif
+    let id = info.winid
+    let lines = nvim_buf_line_count(info.bufnr)
+    let win = {'winid': id, 'textoff': get(info, 'textoff', 0), 'topline': info.topline}
+    let win.lines = lines
+    let win.top = info.topline <= 1
+    let win.bottom = info.botline >= lines
+    let win.number = getwinvar(id, '&number') || getwinvar(id, '&relativenumber')
+    let win.numberwidth = max([getwinvar(id, '&numberwidth'), len(string(lines)) + 1])
+    let win.textwidth = getbufvar(info.bufnr, '&textwidth')
+    let win.winbar = get(info, 'winbar', 0)
+    let win.folds = []
+    if a:folds && exists('*win_execute') && exists('*screenpos')
+      let win.folds = eval(win_execute(id, 'echon string(GonvimClosedFolds())'))
+    endif
+    let win.leftcol = 0
+    if a:view && exists('*win_execute')
+      let win.leftcol = str2nr(win_execute(id, 'echon winsaveview().leftcol'))
+    endif
+    let win.qfidx = 0
+    if info.quickfix
+      let win.qfidx = info.loclist ? getloclist(id, {'idx': 0}).idx : getqflist({'idx': 0}).idx
+    endif
+    call add(wins, win)
+  endfor
+  return wins
+endfunction`
+
+// vimFunction returns the command that defines the function in source, as
+// nvim_command takes a single line
+func vimFunction(source string) string {
+	source = strings.Replace(source, `\`, `\\`, -1)
+	source = strings.Replace(source, `"`, `\"`, -1)
+	source = strings.Replace(source, "\n", `\n`, -1)
+	return `execute "` + source + `"`
+}
+
+// setWinInfo sets the fields GonvimWinInfo returned on the windows
+func (s *Screen) setWinInfo(wins map[nvim.Window]*Window, infos []map[string]interface{}) {
+	for _, info := range infos {
+		win, ok := wins[nvim.Window(reflectToInt(info["winid"]))]
+		if !ok {
+			continue
+		}
+		textoff := reflectToInt(info["textoff"])
+		win.textstart = textoff
+		win.topline = reflectToInt(info["topline"])
+		win.lines = reflectToInt(info["lines"])
+		win.qfIdx = reflectToInt(info["qfidx"])
+		win.atTop = reflectToInt(info["top"]) != 0
+		win.atBottom = reflectToInt(info["bottom"]) != 0
+		win.textwidth = reflectToInt(info["textwidth"])
+		win.leftcol = reflectToInt(info["leftcol"])
+		// the position is of the winbar, the text starts below it, and
+		// the fold rows are counted from the winbar too
+		win.winbar = reflectToInt(info["winbar"])
+		win.pos[0] += win.winbar
+		folds, _ := info["folds"].([]interface{})
+		for _, row := range folds {
+			win.foldRows = append(win.foldRows, reflectToInt(row)-win.winbar)
+		}
+		if reflectToInt(info["number"]) != 1 {
+			continue
+		}
+		if s.gutterSeparator {
+			win.textoff = textoff
+		}
+		win.numcol = textoff - reflectToInt(info["numberwidth"])
+	}
+}
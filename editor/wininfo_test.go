@@ -0,0 +1,48 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+func TestVimFunction(t *testing.T) {
+	command := vimFunction("function! F() abort\n  return \"a\\b\"\nendfunction")
+	want := `execute "function! F() abort\n  return \"a\\b\"\nendfunction"`
+	if command != want {
+		t.Errorf("vimFunction = %s, want %s", command, want)
+	}
+}
+
+func TestSetWinInfo(t *testing.T) {
+	s := &Screen{gutterSeparator: true}
+	wins := map[nvim.Window]*Window{
+		1000: {win: 1000, numcol: -1},
+		1001: {win: 1001, numcol: -1},
+	}
+	s.setWinInfo(wins, []map[string]interface{}{
+		{"winid": int64(1000), "textoff": int64(6), "topline": int64(1), "top": int64(1), "bottom": int64(0), "number": int64(1), "numberwidth": int64(4), "textwidth": int64(80), "qfidx": int64(0), "leftcol": int64(7), "folds": []interface{}{int64(2), int64(5)}},
+		{"winid": int64(1001), "textoff": int64(2), "topline": int64(9), "top": int64(0), "bottom": int64(1), "number": int64(0), "numberwidth": int64(4), "textwidth": int64(0), "qfidx": int64(3)},
+		{"winid": int64(1002), "textoff": int64(4)},
+	})
+
+	numbered := wins[1000]
+	if numbered.textoff != 6 || numbered.textstart != 6 || numbered.numcol != 2 {
+		t.Errorf("numbered window: textoff %d, textstart %d, numcol %d", numbered.textoff, numbered.textstart, numbered.numcol)
+	}
+	if !numbered.atTop || numbered.atBottom || numbered.textwidth != 80 || numbered.leftcol != 7 {
+		t.Errorf("numbered window: atTop %v, atBottom %v, textwidth %d, leftcol %d", numbered.atTop, numbered.atBottom, numbered.textwidth, numbered.leftcol)
+	}
+
+	if len(numbered.foldRows) != 2 || numbered.foldRows[0] != 2 || numbered.foldRows[1] != 5 {
+		t.Errorf("numbered window: foldRows %v", numbered.foldRows)
+	}
+
+	plain := wins[1001]
+	if plain.textoff != 0 || plain.textstart != 2 || plain.numcol != -1 {
+		t.Errorf("plain window: textoff %d, textstart %d, numcol %d", plain.textoff, plain.textstart, plain.numcol)
+	}
+	if plain.topline != 9 || plain.qfIdx != 3 || plain.atTop || !plain.atBottom {
+		t.Errorf("plain window: topline %d, qfIdx %d, atTop %v, atBottom %v", plain.topline, plain.qfIdx, plain.atTop, plain.atBottom)
+	}
+}
@@ -2,12 +2,14 @@ package editor
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/dzhou121/gonvim/fuzzy"
 	"github.com/neovim/go-client/nvim"
@@ -45,6 +47,16 @@ type Workspace struct {
 	cmdline    *Cmdline
 	signature  *Signature
 	message    *Message
+	msgPanel   *MessagePanel
+	stats      *Stats
+	latency    *Latency
+	console    *Console
+	crumbs     *Breadcrumbs
+	start      *StartScreen
+	overview   *TabOverview
+	tabSwitch  *TabSwitch
+	sidebar    *Sidebar
+	modal      *Modal
 	svgs       map[string]*SvgXML
 	svgsOnce   sync.Once
 	width      int
@@ -58,19 +70,36 @@ type Workspace struct {
 	foreground *RGBA
 	background *RGBA
 	special    *RGBA
+	pumblend   int
 	mode       string
+	modeInfo   map[string]map[string]interface{}
 	cwd        string
 	cwdBase    string
 
 	signal        *workspaceSignal
 	redrawUpdates chan [][]interface{}
 	guiUpdates    chan []interface{}
+	inputs        chan func()
 	stopOnce      sync.Once
 	stop          chan struct{}
+	redrawChunk   int
+	redrawPending [][]interface{}
+	redrawTimer   *core.QTimer
 
 	drawStatusline bool
 	drawTabline    bool
 	drawLint       bool
+
+	smoothScroll   bool
+	transparent    float64
+	typewriter     bool
+	clipboardPaste bool
+	pasteShortcut  bool
+	present        *presentSettings
+	theme          *Theme
+	ligature       ligatureConfig
+	termCursorline terminalCursorline
+	screenLayout   *widgets.QHBoxLayout
 }
 
 func newWorkspace(path string) (*Workspace, error) {
@@ -79,10 +108,15 @@ func newWorkspace(path string) (*Workspace, error) {
 		signal:        NewWorkspaceSignal(nil),
 		redrawUpdates: make(chan [][]interface{}, 1000),
 		guiUpdates:    make(chan []interface{}, 1000),
+		inputs:        make(chan func(), 1000),
+		transparent:   1,
 	}
+	w.redrawTimer = core.NewQTimer(nil)
+	w.redrawTimer.SetSingleShot(true)
+	w.redrawTimer.ConnectTimeout(w.flushRedraw)
 	w.signal.ConnectRedrawSignal(func() {
 		updates := <-w.redrawUpdates
-		w.handleRedraw(updates)
+		w.queueRedraw(updates)
 	})
 	w.signal.ConnectGuiSignal(func() {
 		updates := <-w.guiUpdates
@@ -104,12 +138,11 @@ func newWorkspace(path string) (*Workspace, error) {
 		}
 		editor.workspaces = workspaces
 		w.hide()
-		if editor.active == index {
-			if index > 0 {
-				editor.active--
-			}
-			editor.workspaceUpdate()
+		editor.switcher.hide()
+		if editor.active > index || (editor.active == index && index > 0) {
+			editor.active--
 		}
+		editor.workspaceUpdate()
 	})
 	fontFamily := ""
 	switch runtime.GOOS {
@@ -121,6 +154,7 @@ func newWorkspace(path string) (*Workspace, error) {
 		fontFamily = "Monospace"
 	}
 	w.font = initFontNew(fontFamily, 14, 6)
+	w.ligature.current = true
 	w.tabline = newTabline()
 	w.tabline.ws = w
 	w.statusline = initStatuslineNew()
@@ -150,8 +184,36 @@ func newWorkspace(path string) (*Workspace, error) {
 	w.message = initMessage()
 	w.message.widget.SetParent(w.screen.widget)
 	w.message.ws = w
+	w.msgPanel = initMessagePanel()
+	w.msgPanel.widget.SetParent(w.screen.widget)
+	w.msgPanel.mode.SetParent(w.screen.widget)
+	w.msgPanel.cmd.SetParent(w.screen.widget)
+	w.msgPanel.ws = w
 	w.cmdline = initCmdline()
 	w.cmdline.ws = w
+	w.stats = initStats()
+	w.stats.ws = w
+	w.latency = &Latency{ws: w}
+	w.console = initConsole()
+	w.console.widget.SetParent(w.screen.widget)
+	w.console.ws = w
+	w.crumbs = initBreadcrumbs()
+	w.crumbs.widget.SetParent(w.screen.widget)
+	w.crumbs.ws = w
+	w.start = initStartScreen()
+	w.start.widget.SetParent(w.screen.widget)
+	w.start.ws = w
+	w.overview = initTabOverview()
+	w.overview.widget.SetParent(w.screen.widget)
+	w.overview.ws = w
+	w.tabSwitch = initTabSwitch()
+	w.tabSwitch.label.SetParent(w.screen.widget)
+	w.tabSwitch.ws = w
+	w.modal = initModal()
+	w.modal.widget.SetParent(w.screen.widget)
+	w.modal.ws = w
+	w.sidebar = initSidebar()
+	w.sidebar.ws = w
 
 	// screenLayout := widgets.NewQHBoxLayout()
 	// screenLayout.SetContentsMargins(0, 0, 0, 0)
@@ -162,6 +224,12 @@ func newWorkspace(path string) (*Workspace, error) {
 	// screenLayout.AddWidget(w.screen.widget, 1, 0)
 	// screenLayout.AddWidget(w.markdown.webview, 0, 0)
 
+	screenLayout := widgets.NewQHBoxLayout()
+	screenLayout.SetContentsMargins(0, 0, 0, 0)
+	screenLayout.SetSpacing(0)
+	screenLayout.AddWidget(w.sidebar.widget, 0, 0)
+	screenLayout.AddWidget(w.screen.widget, 1, 0)
+
 	layout := widgets.NewQVBoxLayout()
 	w.widget = widgets.NewQWidget(nil, 0)
 	w.widget.SetContentsMargins(0, 0, 0, 0)
@@ -171,7 +239,8 @@ func newWorkspace(path string) (*Workspace, error) {
 	w.widget.ConnectInputMethodEvent(w.InputMethodEvent)
 	w.widget.ConnectInputMethodQuery(w.InputMethodQuery)
 	layout.AddWidget(w.tabline.widget, 0, 0)
-	layout.AddWidget(w.screen.widget, 1, 0)
+	layout.AddLayout(screenLayout, 1)
+	w.screenLayout = screenLayout
 	layout.AddWidget(w.statusline.widget, 0, 0)
 	layout.SetContentsMargins(0, 0, 0, 0)
 	layout.SetSpacing(0)
@@ -183,6 +252,7 @@ func newWorkspace(path string) (*Workspace, error) {
 
 	w.widget.SetParent(editor.wsWidget)
 	w.widget.Move2(0, 0)
+	w.font.setDevicePixelRatio(editor.window.DevicePixelRatioF())
 	w.updateSize()
 
 	// err := w.startNvim()
@@ -224,7 +294,9 @@ func (w *Workspace) startNvim(path string) error {
 		w.guiUpdates <- updates
 		w.signal.GuiSignal()
 	})
+	workspace := recorder.workspace()
 	w.nvim.RegisterHandler("redraw", func(updates ...[]interface{}) {
+		recorder.record(workspace, updates)
 		w.redrawUpdates <- updates
 		w.signal.RedrawSignal()
 	})
@@ -238,6 +310,7 @@ func (w *Workspace) startNvim(path string) error {
 		})
 		w.signal.StopSignal()
 	}()
+	go w.sendInputs()
 
 	w.configure()
 	w.attachUI(path)
@@ -246,6 +319,27 @@ func (w *Workspace) startNvim(path string) error {
 	return nil
 }
 
+// input queues keys to send to neovim. Keys, mouse input and pastes all go
+// through the one queue, so they reach neovim in the order they were given
+// without the GUI thread waiting on neovim.
+func (w *Workspace) input(keys string) {
+	w.inputs <- func() {
+		w.nvim.Input(keys)
+	}
+}
+
+// sendInputs sends the queued input until neovim exits
+func (w *Workspace) sendInputs() {
+	for {
+		select {
+		case send := <-w.inputs:
+			send()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
 func (w *Workspace) configure() {
 	var drawSplit interface{}
 	w.nvim.Var("gonvim_draw_split", &drawSplit)
@@ -255,6 +349,265 @@ func (w *Workspace) configure() {
 		w.screen.drawSplit = true
 	}
 
+	w.nvim.Var("gonvim_undercurl_style", &w.screen.undercurlStyle)
+	w.nvim.Var("gonvim_terminal_cursor", &w.cursor.terminalShape)
+	w.nvim.Var("gonvim_terminal_cursor_blink", &w.cursor.terminalBlink)
+	w.nvim.Var("gonvim_busy_cursor", &w.cursor.busyStyle)
+	var cursorBlink interface{}
+	w.nvim.Var("gonvim_cursor_blink", &cursorBlink)
+	w.cursor.blinkDisabled = isZero(cursorBlink)
+	w.nvim.Var("gonvim_terminal_cursorline", &w.termCursorline.config)
+	w.nvim.Var("gonvim_tab_animation", &w.tabSwitch.style)
+	steadyModes := []string{}
+	w.nvim.Var("gonvim_cursor_steady", &steadyModes)
+	w.cursor.steadyModes = map[string]bool{}
+	for _, mode := range steadyModes {
+		w.cursor.steadyModes[mode] = true
+	}
+
+	// the font is changed on the Qt thread, like GuiLinespace does
+	var lineSpace interface{}
+	w.nvim.Var("gonvim_linespace", &lineSpace)
+	if lineSpace != nil {
+		w.nvim.Command(fmt.Sprintf("call rpcnotify(0, 'Gui', 'gonvim_linespace', '%d')", reflectToInt(lineSpace)))
+	}
+	var letterSpace interface{}
+	w.nvim.Var("gonvim_letterspace", &letterSpace)
+	if letterSpace != nil {
+		w.nvim.Command(fmt.Sprintf("call rpcnotify(0, 'Gui', 'gonvim_letterspace', '%d')", reflectToInt(letterSpace)))
+	}
+
+	var transparent interface{}
+	w.nvim.Var("gonvim_transparent", &transparent)
+	if transparent != nil {
+		w.nvim.Command(fmt.Sprintf("call rpcnotify(0, 'Gui', 'gonvim_transparent', %f)", reflectToFloat(transparent)))
+	}
+
+	// only read at startup, it decides how gonvim attaches
+	var multigrid interface{}
+	w.nvim.Var("gonvim_multigrid", &multigrid)
+	w.screen.grids.enabled = isTrue(multigrid)
+
+	w.screen.scrollPastEnd = -1
+	var scrollPastEnd interface{}
+	w.nvim.Var("gonvim_scroll_past_end", &scrollPastEnd)
+	if scrollPastEnd != nil {
+		w.screen.scrollPastEnd = maxInt(reflectToInt(scrollPastEnd), 0)
+	}
+
+	w.nvim.Option("pumblend", &w.pumblend)
+	w.nvim.Option("pumheight", &w.popup.pumheight)
+	w.nvim.Option("mousemodel", &w.screen.context.mousemodel)
+	w.nvim.Var("gonvim_popupmenu_max_rows", &w.popup.maxRows)
+
+	var textContrast interface{}
+	w.nvim.Var("gonvim_text_contrast", &textContrast)
+	if textContrast != nil {
+		w.screen.textContrast = math.Min(math.Max(reflectToFloat(textContrast), 0.5), 1.5)
+	}
+
+	var focusOutline interface{}
+	w.nvim.Var("gonvim_focus_outline", &focusOutline)
+	w.screen.focusOutline = isTrue(focusOutline)
+	outlineColor := ""
+	w.nvim.Var("gonvim_focus_outline_color", &outlineColor)
+	w.screen.outlineColor = hexToRGBA(outlineColor)
+	outlineWidth := 0
+	w.nvim.Var("gonvim_focus_outline_width", &outlineWidth)
+	if outlineWidth < 1 || outlineWidth > 2 {
+		outlineWidth = 1
+	}
+	w.screen.outlineWidth = outlineWidth
+
+	inlayHintStyle := ""
+	inlayHintGroup := ""
+	w.nvim.Var("gonvim_inlay_hint_style", &inlayHintStyle)
+	w.nvim.Var("gonvim_inlay_hint_group", &inlayHintGroup)
+	w.screen.setInlayHintStyle(inlayHintStyle, inlayHintGroup)
+
+	var clipboardPaste interface{}
+	w.nvim.Var("gonvim_clipboard_paste", &clipboardPaste)
+	w.clipboardPaste = isTrue(clipboardPaste)
+	w.pasteShortcut = clipboardPaste == nil || w.clipboardPaste
+
+	var ligatures interface{}
+	w.nvim.Var("gonvim_ligatures", &ligatures)
+	w.ligature.enabled = !isZero(ligatures)
+	w.ligature.filetypes = nil
+	w.nvim.Var("gonvim_ligature_filetypes", &w.ligature.filetypes)
+	w.nvim.Command(`call rpcnotify(0, 'Gui', 'gonvim_ligature_filetype', &filetype)`)
+
+	w.nvim.Var("gonvim_redraw_chunk", &w.redrawChunk)
+
+	var textwidthDim, textwidthDimAmount interface{}
+	w.nvim.Var("gonvim_textwidth_dim", &textwidthDim)
+	w.nvim.Var("gonvim_textwidth_dim_amount", &textwidthDimAmount)
+	w.screen.twDim.enabled = isTrue(textwidthDim)
+	w.screen.twDim.amount = 0.35
+	if textwidthDimAmount != nil {
+		w.screen.twDim.amount = math.Min(math.Max(reflectToFloat(textwidthDimAmount), 0), 1)
+	}
+
+	var marginFade interface{}
+	w.nvim.Var("gonvim_margin_fade", &marginFade)
+	w.screen.fade.enabled = isTrue(marginFade)
+	var fadeHeight, fadeStrength interface{}
+	w.nvim.Var("gonvim_margin_fade_height", &fadeHeight)
+	w.nvim.Var("gonvim_margin_fade_strength", &fadeStrength)
+	w.screen.fade.height = 2
+	if fadeHeight != nil {
+		w.screen.fade.height = math.Max(reflectToFloat(fadeHeight), 0)
+	}
+	w.screen.fade.strength = 0.8
+	if fadeStrength != nil {
+		w.screen.fade.strength = math.Min(math.Max(reflectToFloat(fadeStrength), 0), 1)
+	}
+
+	var snapSize interface{}
+	w.nvim.Var("gonvim_snap_window_size", &snapSize)
+	editor.snapSize = isTrue(snapSize)
+
+	var foldColumn interface{}
+	w.nvim.Var("gonvim_fold_column", &foldColumn)
+	w.screen.foldColumn = isTrue(foldColumn)
+	foldColumnWidth := 0
+	w.nvim.Var("gonvim_fold_column_width", &foldColumnWidth)
+	if w.screen.foldColumn && foldColumnWidth > 0 {
+		w.nvim.Command(fmt.Sprintf("set foldcolumn=%d", clampInt(foldColumnWidth, 1, 12)))
+	}
+
+	var foldAnimation interface{}
+	w.nvim.Var("gonvim_fold_animation", &foldAnimation)
+	w.screen.foldAnim.enabled = isTrue(foldAnimation)
+
+	var foldSummary interface{}
+	w.nvim.Var("gonvim_fold_summary", &foldSummary)
+	w.screen.foldSummary = isTrue(foldSummary)
+	hasStatuscol := 0
+	w.nvim.Eval("exists('+statuscolumn')", &hasStatuscol)
+	w.screen.hasStatuscol = hasStatuscol == 1
+
+	var cursorWord interface{}
+	w.nvim.Var("gonvim_cursor_word", &cursorWord)
+	w.screen.cursorWord.enabled = isTrue(cursorWord)
+	cursorWordGroup := ""
+	w.nvim.Var("gonvim_cursor_word_group", &cursorWordGroup)
+	if cursorWordGroup != "" {
+		w.screen.cursorWord.group = cursorWordGroup
+	}
+	var cursorNr interface{}
+	w.nvim.Var("gonvim_cursor_line_nr", &cursorNr)
+	w.screen.cursorNr.enabled = isTrue(cursorNr)
+	var smoothScroll interface{}
+	w.nvim.Var("gonvim_smooth_scroll", &smoothScroll)
+	w.smoothScroll = isTrue(smoothScroll)
+	var smoothDuration, jumpDuration, jumpLines, jumpDistance interface{}
+	w.nvim.Var("gonvim_smooth_scroll_duration", &smoothDuration)
+	w.nvim.Var("gonvim_smooth_scroll_jump_duration", &jumpDuration)
+	w.nvim.Var("gonvim_smooth_scroll_jump_lines", &jumpLines)
+	w.nvim.Var("gonvim_smooth_scroll_jump_distance", &jumpDistance)
+	w.screen.smooth.duration = smoothScrollDuration
+	if smoothDuration != nil {
+		w.screen.smooth.duration = time.Duration(reflectToInt(smoothDuration)) * time.Millisecond
+	}
+	w.screen.smooth.jumpDuration = smoothScrollJumpDuration
+	if jumpDuration != nil {
+		w.screen.smooth.jumpDuration = time.Duration(reflectToInt(jumpDuration)) * time.Millisecond
+	}
+	w.screen.smooth.jumpLines = reflectToInt(jumpLines)
+	w.screen.smooth.jumpDistance = smoothScrollJumpDistance
+	if jumpDistance != nil {
+		w.screen.smooth.jumpDistance = reflectToInt(jumpDistance)
+	}
+	var lineCache interface{}
+	w.nvim.Var("gonvim_line_cache", &lineCache)
+	w.screen.lineCache.enabled = isTrue(lineCache)
+	w.screen.lineCache.invalidate()
+	var mouseIBeam interface{}
+	w.nvim.Var("gonvim_mouse_ibeam", &mouseIBeam)
+	w.screen.mouseIBeam = isTrue(mouseIBeam)
+	var lineMarker interface{}
+	w.nvim.Var("gonvim_line_marker", &lineMarker)
+	w.screen.lineMarker.enabled = isTrue(lineMarker)
+	w.nvim.Var("gonvim_line_marker_width", &w.screen.lineMarker.width)
+	lineMarkerGroup := ""
+	w.nvim.Var("gonvim_line_marker_group", &lineMarkerGroup)
+	if lineMarkerGroup != "" {
+		w.screen.lineMarker.group = lineMarkerGroup
+	}
+
+	var highlightStyle interface{}
+	w.nvim.Var("gonvim_highlight_style", &highlightStyle)
+	w.screen.setHighlightStyles(highlightStyle)
+
+	var startScreen interface{}
+	w.nvim.Var("gonvim_start_screen", &startScreen)
+	if isTrue(startScreen) {
+		w.start.enable()
+	}
+
+	var breadcrumbs interface{}
+	w.nvim.Var("gonvim_breadcrumbs", &breadcrumbs)
+	w.crumbs.enabled = isTrue(breadcrumbs)
+	if w.crumbs.enabled {
+		// a blank winbar keeps a row above the text for the breadcrumbs
+		w.nvim.Command(`if has('nvim-0.8') && &winbar ==# '' | set winbar=\  | endif`)
+	}
+
+	var cmdlineGhost interface{}
+	w.nvim.Var("gonvim_cmdline_ghost", &cmdlineGhost)
+	w.cmdline.ghostEnabled = isTrue(cmdlineGhost)
+	var wildmenuIcons interface{}
+	w.nvim.Var("gonvim_wildmenu_icons", &wildmenuIcons)
+	w.cmdline.wildmenuIcons = isTrue(wildmenuIcons)
+
+	var theme string
+	w.nvim.Var("gonvim_theme", &theme)
+	if theme != "" {
+		w.loadTheme([]interface{}{theme})
+	}
+
+	var dimInactive interface{}
+	w.nvim.Var("gonvim_dim_inactive", &dimInactive)
+	w.screen.dimInactive = isTrue(dimInactive)
+
+	var quickfixShading interface{}
+	w.nvim.Var("gonvim_quickfix_shading", &quickfixShading)
+	w.screen.quickfixShading = isTrue(quickfixShading)
+
+	var antialiasBorders interface{}
+	w.nvim.Var("gonvim_antialias_borders", &antialiasBorders)
+	if antialiasBorders == nil {
+		w.screen.borderAntialias = ""
+	} else if isZero(antialiasBorders) {
+		w.screen.borderAntialias = "off"
+	} else {
+		w.screen.borderAntialias = "on"
+	}
+
+	var gutterSeparator interface{}
+	w.nvim.Var("gonvim_gutter_separator", &gutterSeparator)
+	w.screen.gutterSeparator = isTrue(gutterSeparator)
+	var signSeparator interface{}
+	w.nvim.Var("gonvim_sign_separator", &signSeparator)
+	w.screen.signSeparator = isTrue(signSeparator)
+
+	var diffBackground interface{}
+	w.nvim.Var("gonvim_diff_background", &diffBackground)
+	w.screen.diffBackground = !isZero(diffBackground)
+	var diffConnectors interface{}
+	w.nvim.Var("gonvim_diff_connectors", &diffConnectors)
+	w.screen.diffConnectors = isTrue(diffConnectors)
+	// the colors are read by paint, so they are set on the GUI thread
+	w.guiUpdates <- []interface{}{"gonvim_colorscheme"}
+	w.signal.GuiSignal()
+
+	sidebarWidth := 0
+	w.nvim.Var("gonvim_sidebar_width", &sidebarWidth)
+	if sidebarWidth > 0 {
+		w.sidebar.width = sidebarWidth
+	}
+
 	var drawStatusline interface{}
 	w.nvim.Var("gonvim_draw_statusline", &drawStatusline)
 	if isZero(drawStatusline) {
@@ -299,11 +652,17 @@ func (w *Workspace) attachUI(path string) error {
 	w.statusline.subscribe()
 	w.loc.subscribe()
 	w.message.subscribe()
+	w.modal.subscribe()
+	w.screen.subscribeCells()
 	w.uiAttached = true
 	err := w.nvim.AttachUI(w.cols, w.rows, w.attachUIOption())
 	if err != nil {
 		return err
 	}
+	if w.msgPanel.enabled {
+		// the messages are in the panel, the windows get their rows
+		w.nvim.Command("if has('nvim-0.8') | set cmdheight=0 | endif")
+	}
 	return nil
 }
 
@@ -313,6 +672,40 @@ func (w *Workspace) workspaceCommands(path string) {
 	w.nvim.Command(`command! GonvimWorkspaceNext call rpcnotify(0, 'Gui', 'gonvim_workspace_next')`)
 	w.nvim.Command(`command! GonvimWorkspacePrevious call rpcnotify(0, 'Gui', 'gonvim_workspace_previous')`)
 	w.nvim.Command(`command! -nargs=1 GonvimWorkspaceSwitch call rpcnotify(0, 'Gui', 'gonvim_workspace_switch', <args>)`)
+	w.nvim.Command(`command! GonvimWorkspaceClose call rpcnotify(0, 'Gui', 'gonvim_workspace_close')`)
+	w.nvim.Command(`command! GonvimWorkspaceSwitcher call rpcnotify(0, 'Gui', 'gonvim_workspace_switcher')`)
+	w.nvim.Command(`autocmd ColorScheme * call rpcnotify(0, "Gui", "gonvim_colorscheme")`)
+	w.nvim.Command(`autocmd OptionSet pumblend call rpcnotify(0, "Gui", "gonvim_pumblend", &pumblend)`)
+	w.nvim.Command(`autocmd OptionSet pumheight call rpcnotify(0, "Gui", "gonvim_pumheight", &pumheight)`)
+	w.nvim.Command(`autocmd OptionSet mousemodel call rpcnotify(0, "Gui", "gonvim_mousemodel", &mousemodel)`)
+	w.nvim.Command(`command! -range=% GonvimCopyHighlighted call rpcnotify(0, 'Gui', 'gonvim_copy_highlighted', <line1>, line('w0'), get(get(getwininfo(win_getid()), 0, {}), 'textoff', 0), &tabstop, getline(<line1>, <line2>))`)
+	w.nvim.Command(`command! -nargs=+ GonvimBufferBg call rpcnotify(0, 'Gui', 'gonvim_buffer_bg', <f-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimSidebar call rpcnotify(0, 'Gui', 'gonvim_sidebar', <q-args>)`)
+	w.nvim.Command(`autocmd CursorMoved,CursorMovedI,BufEnter * if get(g:, 'gonvim_breadcrumbs', 0) && exists('*GonvimBreadcrumbs') | call rpcnotify(0, 'Gui', 'gonvim_breadcrumbs', GonvimBreadcrumbs()) | endif`)
+	w.nvim.Command(`command! GonvimTabOverview call rpcnotify(0, 'Gui', 'gonvim_tab_overview', nvim_list_tabpages(), nvim_get_current_tabpage(), map(range(1, tabpagenr('$')), 'bufname(tabpagebuflist(v:val)[tabpagewinnr(v:val) - 1])'))`)
+	w.nvim.Command(`autocmd TabLeave * call rpcnotify(0, 'Gui', 'gonvim_tab_leave', nvim_get_current_tabpage(), tabpagenr())`)
+	w.nvim.Command(`autocmd TabEnter * call rpcnotify(0, 'Gui', 'gonvim_tab_enter', tabpagenr())`)
+	w.nvim.Command(`command! -nargs=? GonvimCursorWord call rpcnotify(0, 'Gui', 'gonvim_cursor_word', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimResize call rpcnotify(0, 'Gui', 'gonvim_resize', <q-args>)`)
+	w.nvim.Command(`command! -nargs=1 GonvimFontSize call rpcnotify(0, 'Gui', 'gonvim_font_size', <q-args>)`)
+	w.nvim.Command(`command! -nargs=1 GonvimLinespace call rpcnotify(0, 'Gui', 'gonvim_linespace', <q-args>)`)
+	w.nvim.Command(`command! -nargs=1 GonvimLetterspace call rpcnotify(0, 'Gui', 'gonvim_letterspace', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimCursorBlink call rpcnotify(0, 'Gui', 'gonvim_cursor_blink', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimLineMarker call rpcnotify(0, 'Gui', 'gonvim_line_marker', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimZoomWindow call rpcnotify(0, 'Gui', 'gonvim_zoom_window', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimEditorBg call rpcnotify(0, 'Gui', 'gonvim_editor_bg', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimControlChars call rpcnotify(0, 'Gui', 'gonvim_control_chars', <q-args>)`)
+	w.nvim.Command(`command! -nargs=* GonvimConsole call rpcnotify(0, 'Gui', 'gonvim_console', <f-args>)`)
+	w.nvim.Command(`command! -nargs=? -complete=file GonvimLatency call rpcnotify(0, 'Gui', 'gonvim_latency', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimStats call rpcnotify(0, 'Gui', 'gonvim_stats', <q-args>)`)
+	w.nvim.Command(`autocmd BufEnter,FileType * call rpcnotify(0, 'Gui', 'gonvim_ligature_filetype', &filetype)`)
+	w.nvim.Command(`command! -nargs=1 -complete=file GonvimExportTheme call rpcnotify(0, 'Gui', 'gonvim_export_theme', <q-args>)`)
+	w.nvim.Command(`command! -nargs=1 -complete=file GonvimLoadTheme call rpcnotify(0, 'Gui', 'gonvim_load_theme', <q-args>)`)
+	w.nvim.Command(`command! -nargs=1 -bang -complete=file GonvimDumpGrid call rpcnotify(0, 'Gui', 'gonvim_dump_grid', <q-args>, <bang>0)`)
+	w.nvim.Command(`command! -nargs=? GonvimPresentMode call rpcnotify(0, 'Gui', 'gonvim_present_mode', <q-args>)`)
+	w.nvim.Command(`command! -nargs=? GonvimTypewriter call rpcnotify(0, 'Gui', 'gonvim_typewriter', <q-args>)`)
+	w.nvim.Command(vimFunction(winInfoFunc))
+	w.nvim.Command(vimFunction(foldFunc))
 	if path != "" {
 		w.nvim.Command("so " + path)
 	}
@@ -345,6 +738,10 @@ func (w *Workspace) setCwd(cwd string) {
 func (w *Workspace) attachUIOption() map[string]interface{} {
 	o := make(map[string]interface{})
 	o["rgb"] = true
+	if w.screen.grids.enabled {
+		o["ext_linegrid"] = true
+		o["ext_multigrid"] = true
+	}
 
 	apiInfo, err := w.nvim.APIInfo()
 	if err == nil {
@@ -376,6 +773,11 @@ func (w *Workspace) attachUIOption() map[string]interface{} {
 						o["ext_cmdline"] = true
 					} else if name == "msg_chunk" {
 						o["ext_messages"] = true
+					} else if name == "msg_show" && w.screen.grids.enabled {
+						// ext_messages needs ext_linegrid, which gonvim
+						// only draws with multigrid
+						o["ext_messages"] = true
+						w.msgPanel.enabled = true
 					} else if name == "popupmenu_show" {
 						o["ext_popupmenu"] = true
 					} else if name == "tabline_update" {
@@ -425,10 +827,52 @@ func (w *Workspace) updateSize() {
 	w.screen.updateSize()
 	w.palette.resize()
 	w.message.resize()
+	w.msgPanel.resize()
+	w.modal.resize()
+	w.console.resize()
+	w.start.resize()
+	w.overview.resize()
+}
+
+// queueRedraw handles the redraw events, at most g:gonvim_redraw_chunk of
+// them before the screen gets a chance to paint, so a flood of events shows
+// on the screen as it comes in. 0, the default, handles them all at once.
+func (w *Workspace) queueRedraw(updates [][]interface{}) {
+	if w.redrawChunk <= 0 && len(w.redrawPending) == 0 {
+		w.handleRedraw(updates)
+		return
+	}
+	w.redrawPending = append(w.redrawPending, updates...)
+	w.flushRedraw()
+}
+
+func (w *Workspace) flushRedraw() {
+	n := len(w.redrawPending)
+	if w.redrawChunk > 0 && n > w.redrawChunk {
+		n = w.redrawChunk
+	}
+	if n == 0 {
+		return
+	}
+	updates := w.redrawPending[:n]
+	w.redrawPending = w.redrawPending[n:]
+	if len(w.redrawPending) == 0 {
+		w.redrawPending = nil
+	}
+	w.handleRedraw(updates)
+	if len(w.redrawPending) > 0 {
+		// the paint queued by handleRedraw runs before the timer
+		w.redrawTimer.Start(0)
+	}
 }
 
 func (w *Workspace) handleRedraw(updates [][]interface{}) {
 	s := w.screen
+	s.redrawMutex.Lock()
+	defer s.redrawMutex.Unlock()
+	w.stats.redrawEvents(len(updates))
+	w.latency.redraw()
+	w.console.redraw(updates)
 	for _, update := range updates {
 		event := update[0].(string)
 		args := update[1:]
@@ -452,22 +896,35 @@ func (w *Workspace) handleRedraw(updates [][]interface{}) {
 			} else {
 				w.special = calcColor(reflectToInt(args[0]))
 			}
-		case "cursor_goto":
-			s.cursorGoto(args)
-		case "put":
-			s.put(args)
-		case "eol_clear":
-			s.eolClear(args)
-		case "clear":
-			s.clear(args)
-		case "resize":
-			s.resize(args)
-		case "highlight_set":
-			s.highlightSet(args)
-		case "set_scroll_region":
-			s.setScrollRegion(args)
-		case "scroll":
-			s.scroll(args)
+		case "default_colors_set":
+			s.defaultColorsSet(args)
+		case "hl_attr_define":
+			s.hlAttrDefine(args)
+		case "grid_resize":
+			s.gridResize(args)
+		case "grid_clear":
+			s.gridClear(args)
+		case "grid_destroy":
+			s.gridDestroy(args)
+		case "grid_cursor_goto":
+			s.gridCursorGoto(args)
+		case "grid_line":
+			s.gridLine(args)
+		case "grid_scroll":
+			s.gridScroll(args)
+		case "win_pos":
+			s.winPos(args)
+		case "win_float_pos":
+			s.winFloatPos(args)
+		case "win_hide", "win_close":
+			s.winHide(args)
+		case "msg_set_pos":
+			s.msgSetPos(args)
+		case "flush":
+			s.foldFlush()
+		case "hl_group_set", "win_viewport", "win_external_pos":
+		case "mode_info_set":
+			w.modeInfoSet(args)
 		case "mode_change":
 			arg := update[len(update)-1].([]interface{})
 			w.mode = arg[0].(string)
@@ -483,14 +940,18 @@ func (w *Workspace) handleRedraw(updates [][]interface{}) {
 			w.cmdline.show(args)
 		case "cmdline_pos":
 			w.cmdline.changePos(args)
-		case "cmdline_char":
+		case "cmdline_char", "cmdline_special_char":
 			w.cmdline.putChar(args)
 		case "cmdline_hide":
 			w.cmdline.hide(args)
 		case "cmdline_function_show":
 			w.cmdline.functionShow()
-		case "cmdline_function_hide":
+		case "cmdline_function_hide", "cmdline_block_hide":
 			w.cmdline.functionHide()
+		case "cmdline_block_show":
+			w.cmdline.blockShow(args)
+		case "cmdline_block_append":
+			w.cmdline.blockAppend(args)
 		case "wildmenu_show":
 			w.cmdline.wildmenuShow(args)
 		case "wildmenu_select":
@@ -512,26 +973,82 @@ func (w *Workspace) handleRedraw(updates [][]interface{}) {
 		case "msg_chunk":
 			w.message.chunk(args)
 		case "msg_end":
+		case "msg_show":
+			w.msgPanel.show(args)
+		case "msg_clear":
+			w.msgPanel.clear()
+		case "msg_history_show":
+			w.msgPanel.historyShow(args)
+		case "msg_showmode":
+			w.msgPanel.showMode(args)
 		case "msg_showcmd":
+			w.msgPanel.showCmd(args)
+		case "msg_ruler":
+			w.msgPanel.showRuler(args)
 		case "messages":
 		case "busy_start":
+			w.cursor.setBusy(true)
 		case "busy_stop":
+			w.cursor.setBusy(false)
 		default:
-			fmt.Println("Unhandle event", event)
+			if !s.handleGridEvent(event, args) {
+				fmt.Println("Unhandle event", event)
+			}
 		}
 	}
+	s.composeGrids()
+	s.fetchWindows()
+	s.lineMarker.moved()
+	s.moveCursorNr()
+	s.checkZoom()
 	s.update()
+	s.cursorWord.changed()
 	w.cursor.update()
+	w.crumbs.move()
 	w.statusline.mode.redraw()
 }
 
+func (w *Workspace) modeInfoSet(args []interface{}) {
+	if len(args) < 2 {
+		return
+	}
+	infos, ok := args[1].([]interface{})
+	if !ok {
+		return
+	}
+	w.modeInfo = map[string]map[string]interface{}{}
+	for _, i := range infos {
+		info, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := info["name"].(string)
+		if !ok {
+			continue
+		}
+		w.modeInfo[name] = info
+	}
+	w.cursor.mode = ""
+}
+
 func (w *Workspace) handleRPCGui(updates []interface{}) {
+	w.console.gui(updates)
 	event := updates[0].(string)
 	switch event {
 	case "Font":
 		w.guiFont(updates[1:])
 	case "Linespace":
 		w.guiLinespace(updates[1:])
+	case "gonvim_resize":
+		w.lockGrid(updates[1:])
+	case "gonvim_font_size":
+		w.setFontSize(updates[1:])
+	case "gonvim_linespace":
+		w.setLineSpace(updates[1:])
+	case "gonvim_letterspace":
+		w.setLetterSpace(updates[1:])
+	case "gonvim_transparent":
+		w.setTransparent(updates[1:])
 	case "finder_pattern":
 		w.finder.showPattern(updates[1:])
 	case "finder_pattern_pos":
@@ -556,8 +1073,84 @@ func (w *Workspace) handleRPCGui(updates []interface{}) {
 		editor.workspacePrevious()
 	case "gonvim_workspace_switch":
 		editor.workspaceSwitch(reflectToInt(updates[1]))
+	case "gonvim_workspace_close":
+		editor.workspaceClose()
+	case "gonvim_workspace_switcher":
+		editor.switcher.show(false)
 	case "gonvim_workspace_cwd":
 		w.setCwd(updates[1].(string))
+	case "gonvim_colorscheme":
+		w.screen.updateColors()
+	case "gonvim_colors":
+		w.screen.setColors(updates[1].(hlAttrs))
+	case "gonvim_pumblend":
+		w.pumblend = reflectToInt(updates[1])
+	case "gonvim_pumheight":
+		w.popup.setPumheight(updates[1:])
+	case "gonvim_mousemodel":
+		w.screen.context.setMousemodel(updates[1:])
+	case "gonvim_context_menu":
+		w.screen.showContextMenu(updates[1:])
+	case "gonvim_copy_highlighted":
+		w.screen.copyHighlighted(updates[1:])
+	case "gonvim_buffer_bg":
+		w.screen.setBufferBg(updates[1:])
+	case "gonvim_sidebar":
+		w.sidebar.toggle(updates[1:])
+	case "gonvim_sidebar_changed":
+		w.sidebar.setChanged(updates[1:])
+	case "gonvim_cmdline_ghost":
+		w.cmdline.ghostResult(updates[1:])
+	case "gonvim_wildmenu_types":
+		w.cmdline.wildmenuTypesResult(updates[1:])
+	case "gonvim_start_screen":
+		w.start.update(updates[1:])
+	case "gonvim_breadcrumbs":
+		w.crumbs.update(updates[1:])
+	case "gonvim_tab_overview":
+		w.overview.show(updates[1:])
+	case "gonvim_tab_leave":
+		w.tabSwitch.leave(updates[1:])
+		w.overview.capture(updates[1:])
+	case "gonvim_tab_enter":
+		w.tabSwitch.enter(updates[1:])
+	case "gonvim_cursor_word":
+		w.screen.cursorWord.toggle(updates[1:])
+	case "gonvim_cursor_blink":
+		w.cursor.toggleBlink(updates[1:])
+	case "gonvim_line_marker":
+		w.screen.lineMarker.toggle(updates[1:])
+	case "gonvim_zoom_window":
+		w.screen.zoomWindow(updates[1:])
+	case "gonvim_editor_bg":
+		w.screen.setEditorBg(updates[1:])
+	case "gonvim_control_chars":
+		w.screen.toggleControlChars(updates[1:])
+	case "gonvim_console":
+		w.console.command(updates[1:])
+	case "gonvim_latency":
+		w.latency.start(updates[1:])
+	case "gonvim_stats":
+		w.stats.toggle(updates[1:])
+	case "gonvim_ligature_filetype":
+		w.setLigatureFiletype(updates[1:])
+	case "gonvim_export_theme":
+		w.exportTheme(updates[1:])
+	case "gonvim_load_theme":
+		w.loadTheme(updates[1:])
+	case "gonvim_theme":
+		w.setTheme(updates[1].(*Theme))
+	case "gonvim_dump_grid":
+		w.screen.dumpGrid(updates[1:])
+	case "gonvim_present_mode":
+		w.setPresentMode(updates[1:])
+	case "gonvim_windows":
+		layout, _ := updates[1].(*windowLayout)
+		w.screen.setWindows(layout)
+	case "gonvim_typewriter":
+		w.setTypewriter(updates[1:])
+	case "gonvim_modal":
+		w.modal.open(updates[1:])
 	case GonvimMarkdownNewBufferEvent:
 		go w.markdown.newBuffer()
 	case GonvimMarkdownUpdateEvent:
@@ -592,9 +1185,66 @@ func (w *Workspace) guiFont(args ...interface{}) {
 	}
 
 	w.font.change(parts[0], height)
+	w.fontChanged()
+}
+
+const (
+	minFontSize = 4
+	maxFontSize = 72
+)
+
+// setFontSize takes GonvimFontSize's argument, a point size or a step like
+// +1 or -2 from the current one
+func (w *Workspace) setFontSize(args []interface{}) {
+	arg := ""
+	if len(args) > 0 {
+		arg, _ = args[0].(string)
+	}
+	arg = strings.TrimSpace(arg)
+	size, err := strconv.Atoi(arg)
+	if err != nil {
+		go w.nvim.Command(`echomsg "GonvimFontSize takes a size like 14, +1 or -1"`)
+		return
+	}
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		size += w.font.fontNew.PointSize()
+	}
+	w.changeFontSize(size)
+}
+
+// changeFontSize sets the point size of the font, within minFontSize and
+// maxFontSize
+func (w *Workspace) changeFontSize(size int) {
+	size = clampInt(size, minFontSize, maxFontSize)
+	font := w.font.fontNew
+	if size == font.PointSize() {
+		return
+	}
+	w.font.change(font.Family(), size)
+	w.fontChanged()
+}
+
+// screenChanged fits the font to the device pixel ratio of the screen the
+// window is on, when it moved to a screen with another one
+func (w *Workspace) screenChanged() {
+	dpr := w.screen.widget.DevicePixelRatioF()
+	if dpr <= 0 || dpr == w.font.dpr {
+		return
+	}
+	w.font.setDevicePixelRatio(dpr)
+	w.fontChanged()
+}
+
+// fontChanged resizes the grid to the new font metrics and repaints
+// everything drawn with the font
+func (w *Workspace) fontChanged() {
 	w.updateSize()
 	w.popup.updateFont(w.font)
 	w.screen.toolTipFont(w.font)
+	w.cursor.updateShape()
+	w.cursor.move()
+	w.screen.queueRedrawAll()
+	w.screen.update()
 }
 
 func (w *Workspace) guiLinespace(args ...interface{}) {
@@ -614,23 +1264,108 @@ func (w *Workspace) guiLinespace(args ...interface{}) {
 	default:
 		return
 	}
+	w.changeLineSpace(lineSpace)
+}
+
+// setLineSpace takes GonvimLinespace's argument, the pixels added between
+// lines or a step like +1 or -2 from the current spacing
+func (w *Workspace) setLineSpace(args []interface{}) {
+	arg := ""
+	if len(args) > 0 {
+		arg, _ = args[0].(string)
+	}
+	arg = strings.TrimSpace(arg)
+	lineSpace, err := strconv.Atoi(arg)
+	if err != nil {
+		go w.nvim.Command(`echomsg "GonvimLinespace takes pixels like 4, +1 or -1"`)
+		return
+	}
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		lineSpace += w.font.lineSpace
+	}
+	w.changeLineSpace(lineSpace)
+}
+
+// changeLineSpace sets the pixels added between lines, half above and half
+// below the text
+func (w *Workspace) changeLineSpace(lineSpace int) {
+	lineSpace = maxInt(lineSpace, 0)
+	if lineSpace == w.font.lineSpace {
+		return
+	}
 	w.font.changeLineSpace(lineSpace)
-	w.updateSize()
+	w.fontChanged()
 }
 
-// InputMethodEvent is
+// setLetterSpace takes GonvimLetterspace's argument, the pixels added
+// between glyphs, or a step like +1 or -1 from the current ones
+func (w *Workspace) setLetterSpace(args []interface{}) {
+	arg := ""
+	if len(args) > 0 {
+		arg, _ = args[0].(string)
+	}
+	arg = strings.TrimSpace(arg)
+	letterSpace, err := strconv.Atoi(arg)
+	if err != nil {
+		go w.nvim.Command(`echomsg "GonvimLetterspace takes pixels like 2, +1 or -1"`)
+		return
+	}
+	if strings.HasPrefix(arg, "+") || strings.HasPrefix(arg, "-") {
+		letterSpace += w.font.letterSpace
+	}
+	w.changeLetterSpace(letterSpace)
+}
+
+// changeLetterSpace widens the cells by the pixels, the glyphs are centered
+// in them
+func (w *Workspace) changeLetterSpace(letterSpace int) {
+	letterSpace = maxInt(letterSpace, 0)
+	if letterSpace == w.font.letterSpace {
+		return
+	}
+	w.font.changeLetterSpace(letterSpace)
+	w.fontChanged()
+}
+
+// setTypewriter keeps the cursor line vertically centered by forcing a
+// large scrolloff, restoring the user's value when turned off. The scrolls
+// that keep it centered glide with the smooth scroll animation, whether
+// g:gonvim_smooth_scroll is set or not. The value is saved and restored in
+// neovim, each in a single command sent on the input queue, so the
+// commands go in toggle order without the GUI thread waiting on them and
+// toggling quickly can't save the forced value.
+func (w *Workspace) setTypewriter(args []interface{}) {
+	enable := toggleArg(w.typewriter, args)
+	if enable == w.typewriter {
+		return
+	}
+	w.typewriter = enable
+	command := "if exists('g:gonvim_typewriter_scrolloff') | let &scrolloff = g:gonvim_typewriter_scrolloff | unlet g:gonvim_typewriter_scrolloff | endif"
+	if enable {
+		command = "if !exists('g:gonvim_typewriter_scrolloff') | let g:gonvim_typewriter_scrolloff = &scrolloff | endif | set scrolloff=999"
+	}
+	w.inputs <- func() {
+		w.nvim.Command(command)
+	}
+}
+
+// InputMethodEvent sends the committed text to neovim and shows the preedit
+// string inline at the cursor. An input method can commit text and start
+// composing the next in the same event, and an empty preedit string means
+// the composition is done or cancelled.
 func (w *Workspace) InputMethodEvent(event *gui.QInputMethodEvent) {
+	if w.modal.shown {
+		return
+	}
 	if event.CommitString() != "" {
-		w.nvim.Input(event.CommitString())
+		w.input(strings.Replace(event.CommitString(), "<", "<lt>", -1))
+	}
+	preeditString := event.PreeditString()
+	if preeditString == "" {
 		w.screen.tooltip.Hide()
+		w.cursor.update()
 	} else {
-		preeditString := event.PreeditString()
-		if preeditString == "" {
-			w.screen.tooltip.Hide()
-			w.cursor.update()
-		} else {
-			w.screen.toolTip(preeditString)
-		}
+		w.screen.toolTip(preeditString)
 	}
 }
 
@@ -641,7 +1376,7 @@ func (w *Workspace) InputMethodQuery(query core.Qt__InputMethodQuery) *core.QVar
 		imrect := core.NewQRect()
 		row := w.screen.cursor[0]
 		col := w.screen.cursor[1]
-		x := int(float64(col)*w.font.truewidth) - 1
+		x := int(float64(col)*w.font.truewidth) - 1 + w.screen.widget.X()
 		y := row*w.font.lineHeight + w.tabline.height + w.tabline.marginTop + w.tabline.marginBottom
 		imrect.SetRect(x, y, 1, w.font.lineHeight)
 		return core.NewQVariant33(imrect)
@@ -0,0 +1,200 @@
+package editor
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/neovim/go-client/nvim"
+	"github.com/therecipe/qt/core"
+	"github.com/therecipe/qt/gui"
+)
+
+const defaultZoomFactor = 1.5
+
+// windowZoom magnifies the focused window alone, with GonvimZoomWindow
+// [factor]. The grid and the font are shared by all windows, so the window
+// keeps its rows and columns and its cells are painted larger around the
+// cursor, clipped to the window. Only the part of the window near the cursor
+// is seen, the rest is still there and comes into view as the cursor moves.
+// The borders, gutters and other decorations over the window aren't scaled.
+// Without a factor the command turns the zoom on at 1.5 or off again, and
+// the zoom goes away when the focus leaves the window. Mouse input in the
+// window goes to the cell painted under the mouse.
+type windowZoom struct {
+	win    nvim.Window
+	factor float64
+	x      float64
+	y      float64
+}
+
+func (s *Screen) zoomWindow(args []interface{}) {
+	factor := defaultZoomFactor
+	if s.zoom.factor > 1 {
+		factor = 1
+	}
+	if len(args) > 0 {
+		arg, _ := args[0].(string)
+		switch arg {
+		case "":
+		case "off":
+			factor = 1
+		default:
+			f, err := strconv.ParseFloat(arg, 64)
+			if err != nil || f <= 0 {
+				go s.ws.nvim.Command(`echomsg "GonvimZoomWindow takes a factor like 1.5 or off"`)
+				return
+			}
+			factor = f
+		}
+	}
+	win := s.cursorWin()
+	if win == nil || factor <= 1 {
+		s.resetZoom()
+		return
+	}
+	s.zoom.win = win.win
+	s.zoom.factor = factor
+	s.queueRedrawAll()
+	s.update()
+	s.ws.cursor.move()
+}
+
+func (s *Screen) resetZoom() {
+	if s.zoom.factor <= 1 {
+		return
+	}
+	s.zoom.factor = 1
+	s.queueRedrawAll()
+	s.update()
+	s.ws.cursor.move()
+}
+
+// zoomed returns the zoomed window, nil when there is none
+func (s *Screen) zoomed() *Window {
+	if s.zoom.factor <= 1 {
+		return nil
+	}
+	win, ok := s.curWins[s.zoom.win]
+	if !ok {
+		return nil
+	}
+	return win
+}
+
+// checkZoom is called after every redraw. The zoomed window is painted again
+// as a whole when it follows the cursor to another origin, or when any of
+// its cells changed, as a cell is painted scaled away from where it is.
+func (s *Screen) checkZoom() {
+	if s.zoom.factor <= 1 {
+		return
+	}
+	win := s.cursorWin()
+	if win == nil || win.win != s.zoom.win {
+		s.resetZoom()
+		return
+	}
+	x, y := s.zoomOrigin(win)
+	if x == s.zoom.x && y == s.zoom.y && !s.windowDirty(win) {
+		return
+	}
+	s.zoom.x = x
+	s.zoom.y = y
+	s.queueRedraw(win.pos[1], win.pos[0], win.width, win.height)
+}
+
+// windowDirty returns whether any cell of the window is queued to repaint
+func (s *Screen) windowDirty(win *Window) bool {
+	if s.dirtyAll {
+		return true
+	}
+	for row := win.pos[0]; row < win.pos[0]+win.height && row < len(s.dirtyLines); row++ {
+		span := s.dirtyLines[row]
+		if span[1] > span[0] && span[0] < win.pos[1]+win.width && span[1] > win.pos[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// zoomOrigin returns where the top left of the zoomed window's cells goes,
+// so that the cursor stays where it is unless the cells would leave a gap
+// at an edge of the window
+func (s *Screen) zoomOrigin(win *Window) (float64, float64) {
+	font := s.ws.font
+	factor := s.zoom.factor
+	left := float64(win.pos[1]) * font.truewidth
+	top := float64(win.pos[0] * font.lineHeight)
+	width := float64(win.width) * font.truewidth
+	height := float64(win.height * font.lineHeight)
+	cx := (float64(s.cursor[1]) + 0.5) * font.truewidth
+	cy := (float64(s.cursor[0]) + 0.5) * float64(font.lineHeight)
+	x := cx - (cx-left)*factor
+	y := cy - (cy-top)*factor
+	x = math.Max(math.Min(x, left), left+width-width*factor)
+	y = math.Max(math.Min(y, top), top+height-height*factor)
+	return x, y
+}
+
+// zoomPoint maps a point in the zoomed window to where it is painted, and
+// returns the factor things there are scaled by
+func (s *Screen) zoomPoint(x, y int) (int, int, float64) {
+	win := s.zoomed()
+	if win == nil {
+		return x, y, 1
+	}
+	font := s.ws.font
+	left := float64(win.pos[1]) * font.truewidth
+	top := float64(win.pos[0] * font.lineHeight)
+	ox, oy := s.zoomOrigin(win)
+	factor := s.zoom.factor
+	return int(ox + (float64(x)-left)*factor), int(oy + (float64(y)-top)*factor), factor
+}
+
+// pointCell returns the column and row of the cell at the point on the
+// screen. In the zoomed window it is the cell painted there, the zoom
+// undone, so mouse input goes to the text under the mouse.
+func (s *Screen) pointCell(x, y int) (int, int) {
+	font := s.ws.font
+	fx, fy := float64(x), float64(y)
+	if win := s.zoomed(); win != nil {
+		left := float64(win.pos[1]) * font.truewidth
+		top := float64(win.pos[0] * font.lineHeight)
+		width := float64(win.width) * font.truewidth
+		height := float64(win.height * font.lineHeight)
+		if fx >= left && fx < left+width && fy >= top && fy < top+height {
+			ox, oy := s.zoomOrigin(win)
+			fx = left + (fx-ox)/s.zoom.factor
+			fy = top + (fy-oy)/s.zoom.factor
+		}
+	}
+	return int(fx / font.truewidth), int(fy / float64(font.lineHeight))
+}
+
+func (s *Screen) drawZoom(p *gui.QPainter) {
+	win := s.zoomed()
+	if win == nil {
+		return
+	}
+	font := s.ws.font
+	left := int(float64(win.pos[1]) * font.truewidth)
+	top := win.pos[0] * font.lineHeight
+	width := int(float64(win.width) * font.truewidth)
+	height := win.height * font.lineHeight
+	p.Save()
+	p.SetClipRect2(core.NewQRect4(left, top, width, height), core.Qt__IntersectClip)
+	bg := win.bg
+	if bg == nil {
+		bg = s.ws.background
+	}
+	if bg != nil {
+		p.FillRect5(left, top, width, height, bg.QColor())
+	}
+	x, y := s.zoomOrigin(win)
+	p.Translate3(x, y)
+	p.Scale(s.zoom.factor, s.zoom.factor)
+	for row := win.pos[0]; row < win.pos[0]+win.height && row < s.ws.rows; row++ {
+		s.fillHightlight(p, row, win.pos[1], win.width, win.pos)
+		s.drawText(p, row, win.pos[1], win.width, win.pos)
+	}
+	p.Restore()
+}
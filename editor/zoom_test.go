@@ -0,0 +1,39 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+func TestPointCellZoomed(t *testing.T) {
+	win := &Window{win: 1000, pos: [2]int{2, 4}, width: 20, height: 10}
+	s := &Screen{
+		ws:      &Workspace{font: &Font{truewidth: 10, lineHeight: 20}},
+		curWins: map[nvim.Window]*Window{1000: win},
+		cursor:  [2]int{2, 4},
+		zoom:    windowZoom{win: 1000, factor: 2},
+	}
+	cases := []struct {
+		x, y     int
+		col, row int
+	}{
+		// the cells are twice the size around the cursor's, which stays put
+		{45, 45, 4, 2},
+		{65, 45, 5, 2},
+		{85, 85, 6, 3},
+		// outside of the window nothing is scaled
+		{15, 15, 1, 0},
+		{255, 45, 25, 2},
+	}
+	for _, c := range cases {
+		col, row := s.pointCell(c.x, c.y)
+		if col != c.col || row != c.row {
+			t.Errorf("pointCell(%d, %d) = %d, %d, want %d, %d", c.x, c.y, col, row, c.col, c.row)
+		}
+	}
+	s.zoom.factor = 1
+	if col, row := s.pointCell(85, 85); col != 8 || row != 4 {
+		t.Errorf("pointCell without the zoom = %d, %d, want 8, 4", col, row)
+	}
+}